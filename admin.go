@@ -0,0 +1,109 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/api/adminservice/v1"
+	"go.temporal.io/server/common"
+)
+
+// ClusterInfo describes the cluster run by a server.
+type ClusterInfo struct {
+	ClusterName       string
+	ClusterID         string
+	ServerVersion     string
+	HistoryShardCount int32
+	PersistenceStore  string
+	VisibilityStore   string
+	// SupportedClients maps SDK and CLI names to the versions they must satisfy.
+	SupportedClients map[string]string
+}
+
+// NamespaceInfo describes a registered namespace.
+type NamespaceInfo struct {
+	Name        string
+	ID          string
+	Description string
+	OwnerEmail  string
+	Data        map[string]string
+	// State is REGISTERED, DEPRECATED, or DELETED.
+	State     string
+	Retention time.Duration
+	// HistoryArchivalURI and VisibilityArchivalURI are empty unless archival
+	// is enabled for the namespace.
+	HistoryArchivalURI    string
+	VisibilityArchivalURI string
+}
+
+// DescribeCluster returns the name, version, and stores of the cluster.
+func (s *Server) DescribeCluster(ctx context.Context) (*ClusterInfo, error) {
+	admin, err := s.adminService()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := admin.DescribeCluster(ctx, &adminservice.DescribeClusterRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return &ClusterInfo{
+		ClusterName:       resp.GetClusterName(),
+		ClusterID:         resp.GetClusterId(),
+		ServerVersion:     resp.GetServerVersion(),
+		HistoryShardCount: resp.GetHistoryShardCount(),
+		PersistenceStore:  resp.GetPersistenceStore(),
+		VisibilityStore:   resp.GetVisibilityStore(),
+		SupportedClients:  resp.GetSupportedClients(),
+	}, nil
+}
+
+// ListNamespaces returns every namespace except Temporal's system namespace.
+func (s *Server) ListNamespaces(ctx context.Context) ([]NamespaceInfo, error) {
+	svc, err := s.workflowService()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		namespaces []NamespaceInfo
+		token      []byte
+	)
+	for {
+		resp, err := svc.ListNamespaces(ctx, &workflowservice.ListNamespacesRequest{PageSize: 100, NextPageToken: token})
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range resp.GetNamespaces() {
+			info, cfg := ns.GetNamespaceInfo(), ns.GetConfig()
+			if info.GetName() == common.SystemLocalNamespace {
+				continue
+			}
+			namespace := NamespaceInfo{
+				Name:        info.GetName(),
+				ID:          info.GetId(),
+				Description: info.GetDescription(),
+				OwnerEmail:  info.GetOwnerEmail(),
+				Data:        info.GetData(),
+				State:       info.GetState().String(),
+			}
+			if ttl := cfg.GetWorkflowExecutionRetentionTtl(); ttl != nil {
+				namespace.Retention = *ttl
+			}
+			if cfg.GetHistoryArchivalState() == enumspb.ARCHIVAL_STATE_ENABLED {
+				namespace.HistoryArchivalURI = cfg.GetHistoryArchivalUri()
+			}
+			if cfg.GetVisibilityArchivalState() == enumspb.ARCHIVAL_STATE_ENABLED {
+				namespace.VisibilityArchivalURI = cfg.GetVisibilityArchivalUri()
+			}
+			namespaces = append(namespaces, namespace)
+		}
+		if token = resp.GetNextPageToken(); len(token) == 0 {
+			return namespaces, nil
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.temporal.io/server/common"
+	"go.temporal.io/server/common/authorization"
+)
+
+// loadAPIKeys reads an API key file, returning a map of key to key name.
+//
+// Each non-empty line not starting with # holds either a key, or a name and key
+// separated by a colon. Keys without a name are named after their line number.
+func loadAPIKeys(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	keys := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		name, key := fmt.Sprintf("key-%d", line), text
+		if i := strings.Index(text, ":"); i >= 0 {
+			name, key = strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:])
+		}
+		if key == "" {
+			return nil, fmt.Errorf("%s:%d: empty API key", path, line)
+		}
+		keys[key] = name
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s: no API keys found", path)
+	}
+	return keys, nil
+}
+
+// internalAPIKeyName is the subject of temporalite's own clients, which present
+// a key generated for each server when API keys are required.
+const internalAPIKeyName = "temporalite"
+
+// newInternalAPIKey returns a random key for temporalite's own clients.
+func newInternalAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// apiKeyClaimMapper grants admin claims to callers whose authorization header
+// holds a known API key, optionally prefixed with "Bearer ".
+type apiKeyClaimMapper struct {
+	keys map[string]string
+}
+
+func (m *apiKeyClaimMapper) GetClaims(authInfo *authorization.AuthInfo) (*authorization.Claims, error) {
+	if authInfo.AuthToken == "" {
+		return nil, nil
+	}
+	name, ok := m.keys[strings.TrimPrefix(authInfo.AuthToken, "Bearer ")]
+	if !ok {
+		return nil, errors.New("unknown API key")
+	}
+	return &authorization.Claims{Subject: name, System: authorization.RoleAdmin}, nil
+}
+
+// apiKeyAuthorizer allows requests carrying a valid API key.
+//
+// Upstream's system worker connects to the frontend without credentials, so its
+// requests, recognized by the connection they are forwarded from, are allowed
+// in any namespace. Like upstream's default authorizer, anonymous requests to
+// the system namespace are allowed, as are read-only requests that target no
+// namespace, such as health checks.
+type apiKeyAuthorizer struct {
+	peers *forwardedPeers
+}
+
+func (a *apiKeyAuthorizer) Authorize(ctx context.Context, caller *authorization.Claims, target *authorization.CallTarget) (authorization.Result, error) {
+	if caller != nil && caller.Subject != "" {
+		return authorization.Result{Decision: authorization.DecisionAllow}, nil
+	}
+	if _, ok := a.peers.peerAddr(ctx).(systemWorkerAddr); ok {
+		return authorization.Result{Decision: authorization.DecisionAllow}, nil
+	}
+	if target.Namespace == common.SystemLocalNamespace {
+		return authorization.Result{Decision: authorization.DecisionAllow}, nil
+	}
+	if target.Namespace == "" && (authorization.IsReadOnlyGlobalAPI(authorization.ApiName(target.APIName)) ||
+		strings.HasPrefix(target.APIName, "/grpc.health.v1.Health/")) {
+		return authorization.Result{Decision: authorization.DecisionAllow}, nil
+	}
+	return authorization.Result{Decision: authorization.DecisionDeny, Reason: "a valid API key is required"}, nil
+}
+
+// internalCredentials authenticates temporalite's own clients to the frontend
+// with the server's internal API key, unless the caller supplies its own
+// authorization header through next.
+type internalCredentials struct {
+	key  string
+	next headersProvider
+}
+
+// headersProvider matches client.Options.HeadersProvider, whose type the SDK
+// does not export.
+type headersProvider interface {
+	GetHeaders(ctx context.Context) (map[string]string, error)
+}
+
+func (c internalCredentials) GetHeaders(ctx context.Context) (map[string]string, error) {
+	headers := make(map[string]string)
+	if c.next != nil {
+		next, err := c.next.GetHeaders(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range next {
+			headers[k] = v
+		}
+	}
+	if _, ok := headers["authorization"]; !ok {
+		headers["authorization"] = "Bearer " + c.key
+	}
+	return headers, nil
+}
+
+func (c internalCredentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	return c.GetHeaders(ctx)
+}
+
+func (internalCredentials) RequireTransportSecurity() bool {
+	return false
+}
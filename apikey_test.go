@@ -0,0 +1,248 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/server/common"
+	"go.temporal.io/server/common/authorization"
+	"go.temporal.io/server/service/worker/batcher"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func writeAPIKeyFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keys.txt")
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadAPIKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     map[string]string
+		wantErr  bool
+	}{
+		{
+			name:     "named and unnamed keys",
+			contents: "# team keys\nalice: k1\n\nk2\n",
+			want:     map[string]string{"k1": "alice", "k2": "key-4"},
+		},
+		{
+			name:     "empty key",
+			contents: "alice:\n",
+			wantErr:  true,
+		},
+		{
+			name:     "no keys",
+			contents: "# nothing here\n",
+			wantErr:  true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := loadAPIKeys(writeAPIKeyFile(t, tc.contents))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("loadAPIKeys() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("loadAPIKeys() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuthorizer(t *testing.T) {
+	const listOpen = "/temporal.api.workflowservice.v1.WorkflowService/ListOpenWorkflowExecutions"
+
+	// Requests are forwarded to the frontend from forwarded, on behalf of
+	// the system worker at systemWorker.
+	forwarded := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 50001}
+	systemWorker := systemWorkerAddr{&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 50002}}
+
+	tests := []struct {
+		name   string
+		caller *authorization.Claims
+		// client is the address the request is forwarded for, if any.
+		client net.Addr
+		target authorization.CallTarget
+		want   authorization.Decision
+	}{
+		{
+			name:   "key holder",
+			caller: &authorization.Claims{Subject: "alice", System: authorization.RoleAdmin},
+			target: authorization.CallTarget{Namespace: "default", APIName: listOpen},
+			want:   authorization.DecisionAllow,
+		},
+		{
+			name:   "anonymous",
+			target: authorization.CallTarget{Namespace: "default", APIName: listOpen},
+			want:   authorization.DecisionDeny,
+		},
+		{
+			name:   "system worker",
+			client: systemWorker,
+			target: authorization.CallTarget{Namespace: "default", APIName: listOpen},
+			want:   authorization.DecisionAllow,
+		},
+		{
+			name:   "anonymous forwarded",
+			client: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 50002},
+			target: authorization.CallTarget{Namespace: "default", APIName: listOpen},
+			want:   authorization.DecisionDeny,
+		},
+		{
+			name:   "anonymous system namespace",
+			target: authorization.CallTarget{Namespace: "temporal-system", APIName: listOpen},
+			want:   authorization.DecisionAllow,
+		},
+		{
+			name:   "anonymous health check",
+			target: authorization.CallTarget{APIName: "/grpc.health.v1.Health/Check"},
+			want:   authorization.DecisionAllow,
+		},
+		{
+			name:   "anonymous global read",
+			target: authorization.CallTarget{APIName: "/temporal.api.workflowservice.v1.WorkflowService/ListNamespaces"},
+			want:   authorization.DecisionAllow,
+		},
+		{
+			name:   "anonymous global write",
+			target: authorization.CallTarget{APIName: "/temporal.server.api.adminservice.v1.AdminService/AddSearchAttributes"},
+			want:   authorization.DecisionDeny,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := &apiKeyAuthorizer{peers: &forwardedPeers{}}
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: forwarded})
+			if tc.client != nil {
+				a.peers.add(forwarded, tc.client)
+			}
+			target := tc.target
+			got, err := a.Authorize(ctx, tc.caller, &target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Decision != tc.want {
+				t.Errorf("Authorize() = %v, want %v", got.Decision, tc.want)
+			}
+		})
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	s := newTestServer(t, WithAPIKeyFile(writeAPIKeyFile(t, "alice:secret\n")), WithNamespaces("default"))
+	defer s.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	// Temporalite's own clients present the internal key.
+	if err := s.AwaitNamespace(ctx, "default"); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := grpc.DialContext(ctx, s.frontendHostPort, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	svc := workflowservice.NewWorkflowServiceClient(conn)
+	req := &workflowservice.ListOpenWorkflowExecutionsRequest{Namespace: "default"}
+
+	_, err = svc.ListOpenWorkflowExecutions(ctx, req)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("request without key: got error %v, want PermissionDenied", err)
+	}
+	keyed := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+	if _, err := svc.ListOpenWorkflowExecutions(keyed, req); err != nil {
+		t.Errorf("request with key: %v", err)
+	}
+}
+
+func TestAPIKeysBatchOperation(t *testing.T) {
+	s := newTestServer(t, WithAPIKeyFile(writeAPIKeyFile(t, "alice:secret\n")), WithNamespaces("default"))
+	defer s.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	c, err := s.NewClient(ctx, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	run, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{ID: "batch-target", TaskQueue: "unpolled"}, "batch-target")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Wait for the visibility record the batch operation lists.
+	const query = "WorkflowType='batch-target'"
+	for {
+		resp, err := c.CountWorkflow(ctx, &workflowservice.CountWorkflowExecutionsRequest{Namespace: "default", Query: query})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.GetCount() == 1 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// Start the batch operation as tctl batch start does. The system worker
+	// running it presents no API key.
+	system, err := s.NewClient(ctx, common.SystemLocalNamespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer system.Close()
+	batch, err := system.ExecuteWorkflow(ctx, client.StartWorkflowOptions{TaskQueue: batcher.BatcherTaskQueueName}, batcher.BatchWFTypeName, batcher.BatchParams{
+		Namespace: "default",
+		Query:     query,
+		Reason:    "test",
+		BatchType: batcher.BatchTypeTerminate,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Get(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	desc, err := c.DescribeWorkflowExecution(ctx, run.GetID(), run.GetRunID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := desc.GetWorkflowExecutionInfo().GetStatus(); got != enumspb.WORKFLOW_EXECUTION_STATUS_TERMINATED {
+		t.Errorf("batch target status = %v, want terminated", got)
+	}
+}
+
+func TestAPIKeysRejectCustomAuthorizer(t *testing.T) {
+	_, err := NewServer(
+		WithPersistenceDisabled(),
+		WithDynamicPorts(),
+		WithAPIKeyFile(writeAPIKeyFile(t, "secret\n")),
+		WithAuthorizer(authorization.NewDefaultAuthorizer(), nil),
+	)
+	if err == nil {
+		t.Fatal("NewServer() succeeded, want error")
+	}
+}
@@ -0,0 +1,109 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/common"
+	"go.temporal.io/server/common/authorization"
+	"google.golang.org/grpc"
+)
+
+const adminServicePrefix = "/temporal.server.api.adminservice.v1.AdminService/"
+
+// auditEntry is a single line of the audit log.
+type auditEntry struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	Namespace string    `json:"namespace,omitempty"`
+	Identity  string    `json:"identity,omitempty"`
+	Subject   string    `json:"subject,omitempty"`
+	Peer      string    `json:"peer,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// auditLog appends a JSON line for every administrative frontend request:
+// namespace changes, admin service mutations such as search attribute changes,
+// and workflow operations in the system namespace, where batch operations run.
+type auditLog struct {
+	mu    sync.Mutex
+	f     *os.File
+	peers *forwardedPeers
+}
+
+func newAuditLog(path string, peers *forwardedPeers) (*auditLog, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &auditLog{f: f, peers: peers}, nil
+}
+
+func (a *auditLog) Close() error {
+	return a.f.Close()
+}
+
+// audited reports whether a request to method should be written to the audit log.
+func audited(method string, req interface{}) bool {
+	if strings.HasPrefix(method, adminServicePrefix) {
+		name := strings.TrimPrefix(method, adminServicePrefix)
+		return !strings.HasPrefix(name, "Describe") && !strings.HasPrefix(name, "Get") && !strings.HasPrefix(name, "List")
+	}
+	switch r := req.(type) {
+	case *workflowservice.RegisterNamespaceRequest, *workflowservice.UpdateNamespaceRequest, *workflowservice.DeprecateNamespaceRequest:
+		return true
+	case *workflowservice.StartWorkflowExecutionRequest:
+		return r.GetNamespace() == common.SystemLocalNamespace
+	case *workflowservice.TerminateWorkflowExecutionRequest:
+		return r.GetNamespace() == common.SystemLocalNamespace
+	case *workflowservice.RequestCancelWorkflowExecutionRequest:
+		return r.GetNamespace() == common.SystemLocalNamespace
+	case *workflowservice.SignalWorkflowExecutionRequest:
+		return r.GetNamespace() == common.SystemLocalNamespace
+	}
+	return false
+}
+
+func (a *auditLog) Intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if !audited(info.FullMethod, req) {
+		return resp, err
+	}
+
+	entry := auditEntry{
+		Time:   time.Now(),
+		Method: info.FullMethod,
+	}
+	if r, ok := req.(interface{ GetNamespace() string }); ok {
+		entry.Namespace = r.GetNamespace()
+	}
+	if r, ok := req.(interface{ GetIdentity() string }); ok {
+		entry.Identity = r.GetIdentity()
+	}
+	if claims, ok := ctx.Value(authorization.MappedClaims).(*authorization.Claims); ok && claims != nil {
+		entry.Subject = claims.Subject
+	}
+	if addr := a.peers.peerAddr(ctx); addr != nil {
+		entry.Peer = addr.String()
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	b, marshalErr := json.Marshal(entry)
+	if marshalErr == nil {
+		a.mu.Lock()
+		_, _ = a.f.Write(append(b, '\n'))
+		a.mu.Unlock()
+	}
+	return resp, err
+}
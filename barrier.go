@@ -0,0 +1,152 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/server/common"
+	"google.golang.org/grpc"
+)
+
+// A Barrier holds the next frontend call of a method for a workflow ID before
+// it is handled, until it is released. Barriers make races between calls, such
+// as SignalWithStartWorkflowExecution and TerminateWorkflowExecution,
+// reproducible in tests: wait for one call to reach its barrier, make the
+// other, and then release the first.
+type Barrier struct {
+	method     string
+	workflowID string
+	reached    chan struct{}
+	released   chan struct{}
+	release    sync.Once
+	// taken is set once a call reaches the barrier, under barrierSet.mu.
+	taken bool
+}
+
+// Wait blocks until a call reaches the barrier or ctx is done.
+func (b *Barrier) Wait(ctx context.Context) error {
+	select {
+	case <-b.reached:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release lets the held call proceed, or lets the next matching call through
+// without holding it if none has reached the barrier yet.
+func (b *Barrier) Release() {
+	b.release.Do(func() { close(b.released) })
+}
+
+// AddBarrier holds the next call of the WorkflowService method, such as
+// "SignalWithStartWorkflowExecution", for workflowID until the returned
+// barrier is released. Calls identifying their workflow by task token, such as
+// RespondWorkflowTaskCompleted, are matched too. Barriers for the same method
+// and workflow ID hold successive calls in the order they were added.
+//
+// Held calls are released when the server stops.
+func (s *Server) AddBarrier(method, workflowID string) (*Barrier, error) {
+	if workflowServiceMessage(method, "Request") == nil {
+		return nil, fmt.Errorf("unknown WorkflowService method %q", method)
+	}
+	b := &Barrier{
+		method:     workflowServicePrefix + method,
+		workflowID: workflowID,
+		reached:    make(chan struct{}),
+		released:   make(chan struct{}),
+	}
+	s.barriers.add(b)
+	return b, nil
+}
+
+// barrierSet holds frontend calls at barriers added with AddBarrier.
+type barrierSet struct {
+	serializer common.TaskTokenSerializer
+
+	mu       sync.Mutex
+	barriers []*Barrier
+	stopped  bool
+}
+
+func newBarrierSet() *barrierSet {
+	return &barrierSet{serializer: common.NewProtoTaskTokenSerializer()}
+}
+
+func (bs *barrierSet) add(b *Barrier) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if bs.stopped {
+		b.Release()
+	}
+	bs.barriers = append(bs.barriers, b)
+}
+
+// take marks and returns the first barrier for the call no other call has
+// reached, or nil. Barriers are kept so that stop can release held calls.
+func (bs *barrierSet) take(method, workflowID string) *Barrier {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	for _, b := range bs.barriers {
+		if !b.taken && b.method == method && b.workflowID == workflowID {
+			b.taken = true
+			return b
+		}
+	}
+	return nil
+}
+
+// stop releases all barriers, so that no call is held past server shutdown.
+func (bs *barrierSet) stop() {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.stopped = true
+	for _, b := range bs.barriers {
+		b.Release()
+	}
+}
+
+// workflowID returns the workflow ID a request is for, or an empty string.
+func (bs *barrierSet) workflowID(req interface{}) string {
+	switch r := req.(type) {
+	case interface{ GetWorkflowId() string }:
+		return r.GetWorkflowId()
+	case interface {
+		GetWorkflowExecution() *commonpb.WorkflowExecution
+	}:
+		return r.GetWorkflowExecution().GetWorkflowId()
+	case interface{ GetTaskToken() []byte }:
+		if task, err := bs.serializer.Deserialize(r.GetTaskToken()); err == nil {
+			return task.GetWorkflowId()
+		}
+	}
+	return ""
+}
+
+func (bs *barrierSet) Intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !strings.HasPrefix(info.FullMethod, workflowServicePrefix) {
+		return handler(ctx, req)
+	}
+	bs.mu.Lock()
+	empty := len(bs.barriers) == 0
+	bs.mu.Unlock()
+	if empty {
+		return handler(ctx, req)
+	}
+	if b := bs.take(info.FullMethod, bs.workflowID(req)); b != nil {
+		close(b.reached)
+		select {
+		case <-b.released:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return handler(ctx, req)
+}
@@ -0,0 +1,150 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	tokenspb "go.temporal.io/server/api/token/v1"
+	"go.temporal.io/server/common"
+	"google.golang.org/grpc"
+)
+
+func TestBarrierSetWorkflowID(t *testing.T) {
+	token, err := common.NewProtoTaskTokenSerializer().Serialize(&tokenspb.Task{WorkflowId: "order-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		req  interface{}
+		want string
+	}{
+		{name: "workflow ID", req: &workflowservice.SignalWithStartWorkflowExecutionRequest{WorkflowId: "order-1"}, want: "order-1"},
+		{
+			name: "workflow execution",
+			req:  &workflowservice.TerminateWorkflowExecutionRequest{WorkflowExecution: &commonpb.WorkflowExecution{WorkflowId: "order-1"}},
+			want: "order-1",
+		},
+		{name: "task token", req: &workflowservice.RespondWorkflowTaskCompletedRequest{TaskToken: token}, want: "order-1"},
+		{name: "invalid task token", req: &workflowservice.RespondWorkflowTaskCompletedRequest{TaskToken: []byte("invalid")}},
+		{name: "no workflow", req: &workflowservice.DescribeNamespaceRequest{Namespace: "default"}},
+	}
+	bs := newBarrierSet()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := bs.workflowID(tc.req); got != tc.want {
+				t.Errorf("workflowID() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBarriers(t *testing.T) {
+	const signalWithStart = workflowServicePrefix + "SignalWithStartWorkflowExecution"
+	tests := []struct {
+		name       string
+		method     string
+		workflowID string
+		// releaseFirst releases the barrier before the call is made.
+		releaseFirst bool
+		wantHeld     bool
+	}{
+		{name: "held", method: signalWithStart, workflowID: "order-1", wantHeld: true},
+		{name: "released before the call", method: signalWithStart, workflowID: "order-1", releaseFirst: true},
+		{name: "other workflow", method: signalWithStart, workflowID: "order-2"},
+		{name: "other method", method: workflowServicePrefix + "SignalWorkflowExecution", workflowID: "order-1"},
+		{name: "other service", method: "/grpc.health.v1.Health/Check", workflowID: "order-1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &Server{barriers: newBarrierSet()}
+			b, err := s.AddBarrier("SignalWithStartWorkflowExecution", "order-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.releaseFirst {
+				b.Release()
+			}
+
+			handled := make(chan struct{})
+			go func() {
+				req := &workflowservice.SignalWithStartWorkflowExecutionRequest{WorkflowId: tc.workflowID}
+				handler := func(context.Context, interface{}) (interface{}, error) { return nil, nil }
+				_, _ = s.barriers.Intercept(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: tc.method}, handler)
+				close(handled)
+			}()
+
+			if !tc.wantHeld {
+				select {
+				case <-handled:
+				case <-time.After(5 * time.Second):
+					t.Fatal("call was held")
+				}
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := b.Wait(ctx); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case <-handled:
+				t.Fatal("call was handled before the barrier was released")
+			case <-time.After(50 * time.Millisecond):
+			}
+			b.Release()
+			<-handled
+		})
+	}
+}
+
+func TestAddBarrierUnknownMethod(t *testing.T) {
+	s := &Server{barriers: newBarrierSet()}
+	if _, err := s.AddBarrier("SignalWithStart", "order-1"); err == nil {
+		t.Error("AddBarrier() with an unknown method succeeded, want error")
+	}
+}
+
+func TestBarrierSetStop(t *testing.T) {
+	s := &Server{barriers: newBarrierSet()}
+	held, err := s.AddBarrier("SignalWithStartWorkflowExecution", "order-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handled := make(chan struct{})
+	go func() {
+		req := &workflowservice.SignalWithStartWorkflowExecutionRequest{WorkflowId: "order-1"}
+		info := &grpc.UnaryServerInfo{FullMethod: workflowServicePrefix + "SignalWithStartWorkflowExecution"}
+		_, _ = s.barriers.Intercept(context.Background(), req, info, func(context.Context, interface{}) (interface{}, error) { return nil, nil })
+		close(handled)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := held.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	s.barriers.stop()
+	select {
+	case <-handled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stop did not release the held call")
+	}
+	// Barriers added after stopping are released right away.
+	late, err := s.AddBarrier("SignalWithStartWorkflowExecution", "order-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-late.released:
+	default:
+		t.Error("barrier added after stop was not released")
+	}
+}
@@ -0,0 +1,143 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+// Package benchmark measures workflow throughput and latency against a Temporal server.
+//
+// The same workload backs the package's Go benchmarks and the temporalite bench
+// command, so results from CI and from a user's machine are comparable.
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+// TaskQueue is the task queue benchmark workflows and activities run on.
+const TaskQueue = "temporalite-benchmark"
+
+// Workflow executes the no-op Activity the given number of times in sequence.
+func Workflow(ctx workflow.Context, activities int) error {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{StartToCloseTimeout: 10 * time.Second})
+	for i := 0; i < activities; i++ {
+		if err := workflow.ExecuteActivity(ctx, Activity).Get(ctx, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Activity completes immediately.
+func Activity(ctx context.Context) error {
+	return nil
+}
+
+// Register registers the benchmark workflow and activity.
+func Register(r worker.Registry) {
+	r.RegisterWorkflow(Workflow)
+	r.RegisterActivity(Activity)
+}
+
+// Options configure a benchmark run.
+type Options struct {
+	// Workflows is the number of workflows to run.
+	Workflows int
+	// Concurrency is the number of workflows in flight at once. Defaults to 1.
+	Concurrency int
+	// Activities is the number of activities each workflow executes.
+	Activities int
+}
+
+// Result summarizes a benchmark run.
+type Result struct {
+	Workflows int
+	Duration  time.Duration
+	// Latencies holds the time from start to completion of each workflow, in ascending order.
+	Latencies []time.Duration
+}
+
+// WorkflowsPerSecond returns the rate at which workflows completed.
+func (r Result) WorkflowsPerSecond() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Workflows) / r.Duration.Seconds()
+}
+
+// Percentile returns the workflow latency at percentile p, between 0 and 100.
+func (r Result) Percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	i := int(float64(len(r.Latencies)-1) * p / 100)
+	return r.Latencies[i]
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("%d workflows in %s (%.1f/s), latency p50 %s p90 %s p99 %s",
+		r.Workflows, r.Duration.Round(time.Millisecond), r.WorkflowsPerSecond(),
+		r.Percentile(50).Round(time.Microsecond), r.Percentile(90).Round(time.Microsecond), r.Percentile(99).Round(time.Microsecond))
+}
+
+// Run executes the benchmark workload using c, which must be connected to a
+// namespace served by a worker on TaskQueue with Register applied.
+func Run(ctx context.Context, c client.Client, opts Options) (Result, error) {
+	if opts.Workflows <= 0 {
+		return Result{}, errors.New("at least one workflow is required")
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var (
+		mu        sync.Mutex
+		firstErr  error
+		latencies = make([]time.Duration, 0, opts.Workflows)
+		work      = make(chan struct{})
+		wg        sync.WaitGroup
+	)
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range work {
+				began := time.Now()
+				run, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{TaskQueue: TaskQueue}, Workflow, opts.Activities)
+				if err == nil {
+					err = run.Get(ctx, nil)
+				}
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				latencies = append(latencies, time.Since(began))
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < opts.Workflows; i++ {
+		work <- struct{}{}
+	}
+	close(work)
+	wg.Wait()
+
+	if firstErr != nil {
+		return Result{}, firstErr
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return Result{
+		Workflows: opts.Workflows,
+		Duration:  time.Since(start),
+		Latencies: latencies,
+	}, nil
+}
@@ -0,0 +1,55 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package benchmark_test
+
+import (
+	"context"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/DataDog/temporalite/benchmark"
+	"github.com/DataDog/temporalite/temporaltest"
+)
+
+var checkLatency = flag.Bool("check-latency", false, "fail benchmarks whose median workflow latency exceeds their limit")
+
+// runBenchmark runs the workload b.N times. With -check-latency, it fails if the
+// median workflow takes longer than maxP50, which is set well above typical
+// results so that only regressions such as a slower task dispatch path trip it.
+func runBenchmark(b *testing.B, opts benchmark.Options, maxP50 time.Duration) {
+	ts := temporaltest.NewServer()
+	defer ts.Stop()
+	ts.Worker(benchmark.TaskQueue, benchmark.Register)
+
+	// Warm up caches and pollers before measuring.
+	if _, err := benchmark.Run(context.Background(), ts.Client(), benchmark.Options{Workflows: 1, Activities: opts.Activities}); err != nil {
+		b.Fatal(err)
+	}
+
+	opts.Workflows = b.N
+	b.ResetTimer()
+	result, err := benchmark.Run(context.Background(), ts.Client(), opts)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportMetric(float64(result.Percentile(50).Microseconds()), "p50-µs")
+	b.ReportMetric(float64(result.Percentile(99).Microseconds()), "p99-µs")
+	if p50 := result.Percentile(50); *checkLatency && p50 > maxP50 {
+		b.Errorf("median workflow latency %s exceeds %s", p50, maxP50)
+	}
+}
+
+func BenchmarkStartWorkflow(b *testing.B) {
+	runBenchmark(b, benchmark.Options{Concurrency: 1}, 25*time.Millisecond)
+}
+
+func BenchmarkCompleteActivity(b *testing.B) {
+	runBenchmark(b, benchmark.Options{Concurrency: 1, Activities: 1}, 50*time.Millisecond)
+}
+
+func BenchmarkConcurrentWorkflows(b *testing.B) {
+	runBenchmark(b, benchmark.Options{Concurrency: 10, Activities: 1}, 500*time.Millisecond)
+}
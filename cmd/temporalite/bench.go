@@ -0,0 +1,106 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/server/common/log"
+
+	"github.com/DataDog/temporalite"
+	"github.com/DataDog/temporalite/benchmark"
+)
+
+const (
+	benchWorkflowsFlag   = "workflows"
+	benchConcurrencyFlag = "concurrency"
+	benchActivitiesFlag  = "activities"
+)
+
+func benchCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "bench",
+		Usage:     "Measure workflow throughput of an embedded server on this machine",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    dbPathFlag,
+				Aliases: []string{"f"},
+				Usage:   "benchmark against a database `FILE` instead of in-memory storage",
+			},
+			&cli.IntFlag{
+				Name:  benchWorkflowsFlag,
+				Value: 1000,
+				Usage: "number of workflows to run",
+			},
+			&cli.IntFlag{
+				Name:  benchConcurrencyFlag,
+				Value: 10,
+				Usage: "number of workflows in flight at once",
+			},
+			&cli.IntFlag{
+				Name:  benchActivitiesFlag,
+				Value: 1,
+				Usage: "number of activities each workflow executes",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			opts := []temporalite.ServerOption{
+				temporalite.WithNamespaces("default"),
+				temporalite.WithDynamicPorts(),
+				temporalite.WithLogger(log.NewNoopLogger()),
+			}
+			if c.IsSet(dbPathFlag) {
+				opts = append(opts, temporalite.WithDatabaseFilePath(c.String(dbPathFlag)))
+			} else {
+				opts = append(opts, temporalite.WithPersistenceDisabled())
+			}
+			s, err := temporalite.NewServerWithContext(c.Context, opts...)
+			if err != nil {
+				return err
+			}
+			go func() {
+				if err := s.Start(); err != nil {
+					fmt.Printf("Unable to start server. Error: %v\n", err)
+				}
+			}()
+			defer s.Stop()
+
+			ctx, cancel := context.WithTimeout(c.Context, time.Minute)
+			defer cancel()
+			tc, err := s.NewClientWithOptions(ctx, client.Options{Namespace: "default"})
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: unable to connect to server: %v", err), 1)
+			}
+			defer tc.Close()
+			if err := s.AwaitNamespace(ctx, "default"); err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: %v", err), 1)
+			}
+
+			w := worker.New(tc, benchmark.TaskQueue, worker.Options{})
+			benchmark.Register(w)
+			if err := w.Start(); err != nil {
+				return err
+			}
+			defer w.Stop()
+
+			result, err := benchmark.Run(c.Context, tc, benchmark.Options{
+				Workflows:   c.Int(benchWorkflowsFlag),
+				Concurrency: c.Int(benchConcurrencyFlag),
+				Activities:  c.Int(benchActivitiesFlag),
+			})
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: benchmark failed: %v", err), 1)
+			}
+			fmt.Println(result)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,40 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/DataDog/temporalite/internal/sqlitedb"
+)
+
+func checkpointCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "checkpoint",
+		Usage:     "Copy the write-ahead log into the database file and truncate it",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    dbPathFlag,
+				Aliases: []string{"f"},
+				Value:   defaultCfg.DatabaseFilePath,
+				Usage:   "database file to checkpoint",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			result, err := sqlitedb.Checkpoint(c.Context, c.String(dbPathFlag))
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: unable to checkpoint database: %v", err), 1)
+			}
+			if result.Busy {
+				return cli.Exit(fmt.Sprintf("checkpointed %d of %d frames; database is busy, try again later", result.CheckpointedFrames, result.LogFrames), 1)
+			}
+			fmt.Printf("checkpointed %d frames\n", result.CheckpointedFrames)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,29 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+	"google.golang.org/grpc"
+
+	"github.com/DataDog/temporalite/internal/liteconfig"
+)
+
+const addressFlag = "address"
+
+func newAddressFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  addressFlag,
+		Usage: "host:port of the temporal-frontend GRPC service",
+		Value: fmt.Sprintf("127.0.0.1:%d", liteconfig.DefaultFrontendPort),
+	}
+}
+
+// dialFrontend connects to the frontend service at the address given by the address flag.
+func dialFrontend(c *cli.Context) (*grpc.ClientConn, error) {
+	return grpc.DialContext(c.Context, c.String(addressFlag), grpc.WithInsecure())
+}
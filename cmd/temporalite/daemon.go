@@ -0,0 +1,144 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	daemonFlag  = "daemon"
+	pidFileFlag = "pid-file"
+	logFileFlag = "log-file"
+
+	defaultPIDFile = "temporalite.pid"
+	defaultLogFile = "temporalite.log"
+
+	// daemonStartTimeout is how long start --daemon waits for the server to
+	// accept connections.
+	daemonStartTimeout = time.Minute
+)
+
+func readPIDFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+// checkPIDFile returns an error if the pid file at path belongs to a running process.
+func checkPIDFile(path string) error {
+	if pid, err := readPIDFile(path); err == nil && pid != os.Getpid() && processRunning(pid) {
+		return fmt.Errorf("temporalite is already running with pid %d (from %s)", pid, path)
+	}
+	return nil
+}
+
+// startDaemon runs the start command again in the background, without the
+// daemon flag, and waits for it to write its pid file.
+func startDaemon(pidFile, logFile string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if pidFile, err = filepath.Abs(pidFile); err != nil {
+		return err
+	}
+	var args []string
+	for _, arg := range os.Args[1:] {
+		if strings.HasPrefix(arg, "-") && strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)[0] == daemonFlag {
+			continue
+		}
+		args = append(args, arg)
+	}
+	args = append(args, "--"+pidFileFlag, pidFile)
+
+	out, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("ERROR: unable to open log file: %v", err), exitConfigError)
+	}
+	defer out.Close()
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := detachProcess(cmd); err != nil {
+		return cli.Exit(fmt.Sprintf("ERROR: daemon mode is %v", err), exitConfigError)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(daemonStartTimeout)
+	for {
+		if pid, err := readPIDFile(pidFile); err == nil && pid == cmd.Process.Pid {
+			fmt.Printf("Started temporalite in the background (pid %d), logging to %s\n", pid, logFile)
+			return nil
+		}
+		select {
+		case err := <-exited:
+			code := exitStartFailed
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				code = exitErr.ExitCode()
+			}
+			return cli.Exit(fmt.Sprintf("ERROR: server exited during startup; see %s", logFile), code)
+		case <-timeout:
+			return cli.Exit(fmt.Sprintf("ERROR: server did not start within %s; see %s", daemonStartTimeout, logFile), exitStartFailed)
+		case <-ticker.C:
+		}
+	}
+}
+
+func stopCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "stop",
+		Usage:     "Stop a server started with --daemon",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  pidFileFlag,
+				Usage: "pid file written by the server",
+				Value: defaultPIDFile,
+			},
+			&cli.DurationFlag{
+				Name:  timeoutFlag,
+				Usage: "how long to wait for the server to stop",
+				Value: time.Minute,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			path := c.String(pidFileFlag)
+			pid, err := readPIDFile(path)
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: unable to read pid file: %v", err), 1)
+			}
+			if !processRunning(pid) {
+				_ = os.Remove(path)
+				return cli.Exit(fmt.Sprintf("ERROR: no server is running with pid %d; removed stale pid file", pid), 1)
+			}
+			if err := stopProcess(pid, c.Duration(timeoutFlag)); err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: unable to stop server: %v", err), 1)
+			}
+			fmt.Printf("Stopped temporalite (pid %d)\n", pid)
+			return nil
+		},
+	}
+}
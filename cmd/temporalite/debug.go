@@ -0,0 +1,94 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"fmt"
+	goLog "log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/DataDog/temporalite"
+)
+
+const (
+	pidFlag     = "pid"
+	dumpDirFlag = "dump-dir"
+)
+
+// diagnosticsFilePath returns the location a server process with the given pid
+// writes its diagnostics dump to.
+func diagnosticsFilePath(dir string, pid int) string {
+	return filepath.Join(dir, fmt.Sprintf("temporalite-%d.dump", pid))
+}
+
+func writeDiagnosticsFile(s *temporalite.Server, dir string) {
+	path := diagnosticsFilePath(dir, os.Getpid())
+	f, err := os.Create(path)
+	if err != nil {
+		goLog.Printf("unable to create diagnostics file: %v", err)
+		return
+	}
+	defer f.Close()
+
+	if err := s.DumpDiagnostics(f); err != nil {
+		goLog.Printf("unable to write diagnostics: %v", err)
+		return
+	}
+	goLog.Printf("wrote diagnostics to %s", path)
+}
+
+func newDumpDirFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  dumpDirFlag,
+		Usage: "directory in which diagnostics dumps are written",
+		Value: os.TempDir(),
+	}
+}
+
+func debugCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "debug",
+		Usage: "Debug a running Temporal server",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "dump",
+				Usage:     "Write goroutine stacks, config, and persistence stats of a running server to a file",
+				ArgsUsage: " ",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:     pidFlag,
+						Usage:    "process id of the running server",
+						Required: true,
+					},
+					newDumpDirFlag(),
+				},
+				Action: func(c *cli.Context) error {
+					var (
+						pid  = c.Int(pidFlag)
+						path = diagnosticsFilePath(c.String(dumpDirFlag), pid)
+						sent = time.Now()
+					)
+					if err := sendDiagnosticsSignal(pid); err != nil {
+						return cli.Exit(fmt.Sprintf("ERROR: unable to signal process %d: %v", pid, err), 1)
+					}
+
+					deadline := time.Now().Add(10 * time.Second)
+					for time.Now().Before(deadline) {
+						if info, err := os.Stat(path); err == nil && !info.ModTime().Before(sent) {
+							fmt.Println(path)
+							return nil
+						}
+						time.Sleep(100 * time.Millisecond)
+					}
+					return cli.Exit(fmt.Sprintf("ERROR: timed out waiting for diagnostics at %s", path), 1)
+				},
+			},
+		},
+	}
+}
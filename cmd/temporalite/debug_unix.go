@@ -0,0 +1,31 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/DataDog/temporalite"
+)
+
+// handleDiagnosticsSignal writes a diagnostics dump to dir each time the process receives SIGUSR1.
+func handleDiagnosticsSignal(s *temporalite.Server, dir string) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGUSR1)
+	go func() {
+		for range sigCh {
+			writeDiagnosticsFile(s, dir)
+		}
+	}()
+}
+
+func sendDiagnosticsSignal(pid int) error {
+	return syscall.Kill(pid, syscall.SIGUSR1)
+}
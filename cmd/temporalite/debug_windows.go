@@ -0,0 +1,21 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"errors"
+
+	"github.com/DataDog/temporalite"
+)
+
+// handleDiagnosticsSignal is a no-op because SIGUSR1 is not available on Windows.
+func handleDiagnosticsSignal(*temporalite.Server, string) {}
+
+func sendDiagnosticsSignal(int) error {
+	return errors.New("diagnostics dumps are not supported on windows")
+}
@@ -0,0 +1,204 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"time"
+
+	uiserver "github.com/temporalio/ui-server/server"
+	uiconfig "github.com/temporalio/ui-server/server/config"
+	uiserveroptions "github.com/temporalio/ui-server/server/server_options"
+	"github.com/urfave/cli/v2"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/server/common/log"
+
+	"github.com/DataDog/temporalite"
+	"github.com/DataDog/temporalite/demo"
+	"github.com/DataDog/temporalite/internal/liteconfig"
+)
+
+const demoNamespace = "default"
+
+// demoWorkflowURL returns the address of a workflow run in the web UI at uiAddress.
+func demoWorkflowURL(uiAddress string, run client.WorkflowRun) string {
+	return fmt.Sprintf("%s/namespaces/%s/workflows/%s/%s", uiAddress, demoNamespace, url.PathEscape(run.GetID()), url.PathEscape(run.GetRunID()))
+}
+
+// demoFlags are the flags of the demo and learn commands.
+func demoFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.IntFlag{
+			Name:    portFlag,
+			Aliases: []string{"p"},
+			Usage:   "port for the temporal-frontend GRPC service",
+			Value:   liteconfig.DefaultFrontendPort,
+		},
+		&cli.IntFlag{
+			Name:        uiPortFlag,
+			Usage:       "port for the temporal web UI",
+			DefaultText: fmt.Sprintf("--%s + 1000", portFlag),
+		},
+	}
+}
+
+// demoEnv is an in-memory server with a worker running the demo workflows.
+type demoEnv struct {
+	server    *temporalite.Server
+	client    client.Client
+	worker    worker.Worker
+	uiAddress string
+}
+
+// startDemo starts a demoEnv on the ports given by demoFlags.
+func startDemo(ctx context.Context, c *cli.Context) (*demoEnv, error) {
+	uiPort := c.Int(portFlag) + 1000
+	if c.IsSet(uiPortFlag) {
+		uiPort = c.Int(uiPortFlag)
+	}
+	ui := uiserver.NewServer(uiserveroptions.WithConfig(&uiconfig.Config{
+		TemporalGRPCAddress: fmt.Sprintf(":%d", c.Int(portFlag)),
+		Host:                "127.0.0.1",
+		Port:                uiPort,
+		EnableUI:            true,
+	}))
+	s, err := temporalite.NewServerWithContext(ctx,
+		temporalite.WithNamespaces(demoNamespace),
+		temporalite.WithPersistenceDisabled(),
+		temporalite.WithFrontendPort(c.Int(portFlag)),
+		temporalite.WithUI(ui),
+		temporalite.WithLogger(log.NewNoopLogger()),
+	)
+	if err != nil {
+		return nil, serverExit(err.Error(), err, exitConfigError)
+	}
+	if err := s.Start(); err != nil {
+		return nil, serverExit(fmt.Sprintf("Unable to start server. Error: %v", err), err, exitStartFailed)
+	}
+	env := &demoEnv{server: s, uiAddress: fmt.Sprintf("http://127.0.0.1:%d", uiPort)}
+
+	connectCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	if env.client, err = s.NewClientWithOptions(connectCtx, client.Options{Namespace: demoNamespace}); err != nil {
+		env.stop()
+		return nil, cli.Exit(fmt.Sprintf("ERROR: unable to connect to server: %v", err), 1)
+	}
+	if err := s.AwaitNamespace(connectCtx, demoNamespace); err != nil {
+		env.stop()
+		return nil, cli.Exit(fmt.Sprintf("ERROR: %v", err), 1)
+	}
+
+	w := worker.New(env.client, demo.TaskQueue, worker.Options{})
+	demo.Register(w)
+	if err := w.Start(); err != nil {
+		env.stop()
+		return nil, err
+	}
+	env.worker = w
+	return env, nil
+}
+
+func (env *demoEnv) stop() {
+	if env.worker != nil {
+		env.worker.Stop()
+	}
+	if env.client != nil {
+		env.client.Close()
+	}
+	env.server.Stop()
+}
+
+// wait tells the user where to find the server and blocks until ctx is done.
+func (env *demoEnv) wait(ctx context.Context) {
+	fmt.Printf("\nBrowse all workflows at %s/namespaces/%s/workflows, or connect your own workers to %s.\n",
+		env.uiAddress, demoNamespace, env.server.FrontendHostPort())
+	fmt.Println("Press Ctrl+C to stop the server.")
+	<-ctx.Done()
+}
+
+func demoCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "demo",
+		Usage:     "Run example workflows on an in-memory server to explore in the web UI",
+		ArgsUsage: " ",
+		Description: "Starts an in-memory server and a worker, then runs workflows showing activity retries, " +
+			"child workflows, and signals, and prints links to them in the web UI. The server keeps running " +
+			"until interrupted, and nothing is saved.",
+		Flags: demoFlags(),
+		Action: func(c *cli.Context) error {
+			ctx, stop := signal.NotifyContext(c.Context, os.Interrupt)
+			defer stop()
+
+			env, err := startDemo(ctx, c)
+			if err != nil {
+				return err
+			}
+			defer env.stop()
+
+			fmt.Printf("Temporal server listening on %s, web UI at %s\n\n", env.server.FrontendHostPort(), env.uiAddress)
+			if err := runDemo(ctx, env.client, env.uiAddress); err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: %v", err), 1)
+			}
+			env.wait(ctx)
+			return nil
+		},
+	}
+}
+
+// runDemo runs each demo workflow in turn, describing what it shows.
+func runDemo(ctx context.Context, tc client.Client, uiAddress string) error {
+	opts := func(id string) client.StartWorkflowOptions {
+		return client.StartWorkflowOptions{ID: id, TaskQueue: demo.TaskQueue}
+	}
+
+	fmt.Println("1. Retries: an activity fails twice and the server retries it with backoff.")
+	run, err := tc.ExecuteWorkflow(ctx, opts("demo-retry"), demo.RetryWorkflow)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("   %s\n", demoWorkflowURL(uiAddress, run))
+	var result string
+	if err := run.Get(ctx, &result); err != nil {
+		return err
+	}
+	fmt.Printf("   Result: %s\n\n", result)
+
+	fmt.Println("2. Child workflows: a parent starts a child workflow per name and gathers their results.")
+	run, err = tc.ExecuteWorkflow(ctx, opts("demo-parent"), demo.ParentWorkflow, []string{"Alice", "Bob", "Carol"})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("   %s\n", demoWorkflowURL(uiAddress, run))
+	var greetings []string
+	if err := run.Get(ctx, &greetings); err != nil {
+		return err
+	}
+	fmt.Printf("   Result: %q\n\n", greetings)
+
+	fmt.Printf("3. Signals: a workflow waits for an %q signal, which is sent in five seconds.\n", demo.ApprovalSignal)
+	run, err = tc.ExecuteWorkflow(ctx, opts("demo-approval"), demo.ApprovalWorkflow)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("   %s\n", demoWorkflowURL(uiAddress, run))
+	select {
+	case <-time.After(5 * time.Second):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if err := tc.SignalWorkflow(ctx, run.GetID(), run.GetRunID(), demo.ApprovalSignal, "temporalite demo"); err != nil {
+		return err
+	}
+	if err := run.Get(ctx, &result); err != nil {
+		return err
+	}
+	fmt.Printf("   Result: %s\n", result)
+	return nil
+}
@@ -0,0 +1,210 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	historypb "go.temporal.io/api/history/v1"
+	"go.temporal.io/api/workflowservice/v1"
+)
+
+const (
+	workflowIDFlag = "workflow-id"
+	runIDFlag      = "run-id"
+)
+
+// pendingTimer is a timer started but not yet fired or canceled.
+type pendingTimer struct {
+	id     string
+	fireAt time.Time
+}
+
+// workflowTaskState describes the most recent workflow task.
+type workflowTaskState struct {
+	state    string
+	attempt  int32
+	identity string
+	since    time.Time
+	failure  string
+}
+
+// replayPendingState derives pending timers and the current workflow task from history.
+func replayPendingState(events []*historypb.HistoryEvent) ([]pendingTimer, workflowTaskState) {
+	timers := make(map[string]pendingTimer)
+	var task workflowTaskState
+	for _, e := range events {
+		var eventTime time.Time
+		if e.GetEventTime() != nil {
+			eventTime = *e.GetEventTime()
+		}
+		switch e.GetEventType() {
+		case enumspb.EVENT_TYPE_TIMER_STARTED:
+			attrs := e.GetTimerStartedEventAttributes()
+			var timeout time.Duration
+			if attrs.GetStartToFireTimeout() != nil {
+				timeout = *attrs.GetStartToFireTimeout()
+			}
+			timers[attrs.GetTimerId()] = pendingTimer{id: attrs.GetTimerId(), fireAt: eventTime.Add(timeout)}
+		case enumspb.EVENT_TYPE_TIMER_FIRED:
+			delete(timers, e.GetTimerFiredEventAttributes().GetTimerId())
+		case enumspb.EVENT_TYPE_TIMER_CANCELED:
+			delete(timers, e.GetTimerCanceledEventAttributes().GetTimerId())
+		case enumspb.EVENT_TYPE_WORKFLOW_TASK_SCHEDULED:
+			task = workflowTaskState{state: "scheduled", attempt: e.GetWorkflowTaskScheduledEventAttributes().GetAttempt(), since: eventTime, failure: task.failure}
+		case enumspb.EVENT_TYPE_WORKFLOW_TASK_STARTED:
+			task.state, task.identity, task.since = "started", e.GetWorkflowTaskStartedEventAttributes().GetIdentity(), eventTime
+		case enumspb.EVENT_TYPE_WORKFLOW_TASK_COMPLETED:
+			task = workflowTaskState{state: "completed", since: eventTime}
+		case enumspb.EVENT_TYPE_WORKFLOW_TASK_FAILED:
+			attrs := e.GetWorkflowTaskFailedEventAttributes()
+			task.state, task.since = "failed", eventTime
+			task.failure = fmt.Sprintf("%s: %s", attrs.GetCause(), attrs.GetFailure().GetMessage())
+		case enumspb.EVENT_TYPE_WORKFLOW_TASK_TIMED_OUT:
+			task.state, task.since = "timed out", eventTime
+			task.failure = fmt.Sprintf("timed out (%s)", e.GetWorkflowTaskTimedOutEventAttributes().GetTimeoutType())
+		}
+	}
+
+	pending := make([]pendingTimer, 0, len(timers))
+	for _, t := range timers {
+		pending = append(pending, t)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].fireAt.Before(pending[j].fireAt) })
+	return pending, task
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+func describeCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "describe",
+		Usage:     "Print the pending activities, timers, and workflow task of a workflow",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			newAddressFlag(),
+			&cli.StringFlag{
+				Name:    namespaceFlag,
+				Aliases: []string{"n"},
+				Value:   "default",
+				Usage:   "namespace of the workflow",
+			},
+			&cli.StringFlag{
+				Name:     workflowIDFlag,
+				Aliases:  []string{"w"},
+				Usage:    "workflow ID",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:    runIDFlag,
+				Aliases: []string{"r"},
+				Usage:   "run ID, defaulting to the latest run",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			conn, err := dialFrontend(c)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			svc := workflowservice.NewWorkflowServiceClient(conn)
+
+			execution := &commonpb.WorkflowExecution{WorkflowId: c.String(workflowIDFlag), RunId: c.String(runIDFlag)}
+			desc, err := svc.DescribeWorkflowExecution(c.Context, &workflowservice.DescribeWorkflowExecutionRequest{
+				Namespace: c.String(namespaceFlag),
+				Execution: execution,
+			})
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: unable to describe workflow: %v", err), 1)
+			}
+			info := desc.GetWorkflowExecutionInfo()
+			execution.RunId = info.GetExecution().GetRunId()
+
+			var events []*historypb.HistoryEvent
+			var pageToken []byte
+			for {
+				resp, err := svc.GetWorkflowExecutionHistory(c.Context, &workflowservice.GetWorkflowExecutionHistoryRequest{
+					Namespace:     c.String(namespaceFlag),
+					Execution:     execution,
+					NextPageToken: pageToken,
+				})
+				if err != nil {
+					return cli.Exit(fmt.Sprintf("ERROR: unable to get workflow history: %v", err), 1)
+				}
+				events = append(events, resp.GetHistory().GetEvents()...)
+				if pageToken = resp.GetNextPageToken(); len(pageToken) == 0 {
+					break
+				}
+			}
+			timers, task := replayPendingState(events)
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintf(w, "Workflow:\t%s (%s)\n", info.GetExecution().GetWorkflowId(), info.GetType().GetName())
+			fmt.Fprintf(w, "Run ID:\t%s\n", info.GetExecution().GetRunId())
+			fmt.Fprintf(w, "Status:\t%s\n", info.GetStatus())
+			fmt.Fprintf(w, "Task queue:\t%s\n", info.GetTaskQueue())
+			fmt.Fprintf(w, "Started:\t%s\n", formatTime(info.GetStartTime()))
+			fmt.Fprintf(w, "History length:\t%d\n", info.GetHistoryLength())
+			if task.state != "" {
+				line := task.state
+				if task.attempt > 1 {
+					line += fmt.Sprintf(", attempt %d", task.attempt)
+				}
+				if task.identity != "" && task.state == "started" {
+					line += " by " + task.identity
+				}
+				fmt.Fprintf(w, "Workflow task:\t%s since %s\n", line, formatTime(&task.since))
+				if task.failure != "" && task.state != "completed" {
+					fmt.Fprintf(w, "Last failure:\t%s\n", task.failure)
+				}
+			}
+			_ = w.Flush()
+
+			fmt.Printf("\nPending activities: %d\n", len(desc.GetPendingActivities()))
+			if len(desc.GetPendingActivities()) > 0 {
+				w = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "  ID\tTYPE\tSTATE\tATTEMPT\tSCHEDULED\tLAST HEARTBEAT\tLAST FAILURE")
+				for _, a := range desc.GetPendingActivities() {
+					fmt.Fprintf(w, "  %s\t%s\t%s\t%d\t%s\t%s\t%s\n", a.GetActivityId(), a.GetActivityType().GetName(), a.GetState(),
+						a.GetAttempt(), formatTime(a.GetScheduledTime()), formatTime(a.GetLastHeartbeatTime()), a.GetLastFailure().GetMessage())
+				}
+				_ = w.Flush()
+			}
+
+			fmt.Printf("\nPending timers: %d\n", len(timers))
+			if len(timers) > 0 {
+				w = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "  ID\tFIRES")
+				for _, t := range timers {
+					fmt.Fprintf(w, "  %s\t%s\n", t.id, formatTime(&t.fireAt))
+				}
+				_ = w.Flush()
+			}
+
+			if len(desc.GetPendingChildren()) > 0 {
+				fmt.Printf("\nPending child workflows: %d\n", len(desc.GetPendingChildren()))
+				w = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "  WORKFLOW ID\tTYPE")
+				for _, child := range desc.GetPendingChildren() {
+					fmt.Fprintf(w, "  %s\t%s\n", child.GetWorkflowId(), child.GetWorkflowTypeName())
+				}
+				_ = w.Flush()
+			}
+			return nil
+		},
+	}
+}
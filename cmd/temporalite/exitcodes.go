@@ -0,0 +1,83 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/DataDog/temporalite"
+)
+
+// Exit codes of the start command, letting wrapper scripts and orchestrators
+// tell failures apart. Other commands exit with 1 on any error.
+const (
+	// exitConfigError is returned for invalid flags and configuration. It is
+	// EX_USAGE from sysexits.h rather than 2, which Go uses for panics.
+	exitConfigError = 64
+	// exitPortInUse is returned when a port the server listens on is already bound.
+	exitPortInUse = 3
+	// exitSchemaMismatch is returned when the database file is not a temporalite database.
+	exitSchemaMismatch = 4
+	// exitFatal is returned when a running server stops on an unrecoverable error.
+	exitFatal = 5
+	// exitDatabaseLocked is returned when another process holds the database lock.
+	exitDatabaseLocked = 6
+	// exitStartFailed is returned when the server fails to start for any other
+	// reason.
+	exitStartFailed = 7
+)
+
+// exitCode returns the exit code for a server error, or fallback if the error
+// is not one of the errors exported by temporalite.
+func exitCode(err error, fallback int) int {
+	switch {
+	case errors.Is(err, temporalite.ErrPortInUse):
+		return exitPortInUse
+	case errors.Is(err, temporalite.ErrSchemaMismatch):
+		return exitSchemaMismatch
+	case errors.Is(err, temporalite.ErrDatabaseLocked):
+		return exitDatabaseLocked
+	}
+	return fallback
+}
+
+// serverExit wraps a server error with its exit code.
+func serverExit(message string, err error, fallback int) cli.ExitCoder {
+	return cli.Exit(message, exitCode(err, fallback))
+}
+
+// runnableServer is the part of temporalite.Server used by runServer.
+type runnableServer interface {
+	Start() error
+	Stop()
+	Err() <-chan error
+}
+
+// runServer starts s and blocks until it is interrupted or stops on a fatal
+// error, returning the exit error for the way it stopped.
+func runServer(s runnableServer) cli.ExitCoder {
+	started := make(chan error, 1)
+	go func() { started <- s.Start() }()
+	select {
+	case err := <-started:
+		if err != nil {
+			return serverExit(fmt.Sprintf("Unable to start server. Error: %v", err), err, exitStartFailed)
+		}
+		return cli.Exit("All services are stopped.", 0)
+	case err := <-s.Err():
+		// Upstream stops its services on an interrupt as well, and stopping
+		// them twice panics, so interrupts are ignored while the server shuts
+		// down.
+		signal.Ignore(os.Interrupt, syscall.SIGTERM)
+		s.Stop()
+		return serverExit(err.Error(), err, exitFatal)
+	}
+}
@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+
+	"github.com/DataDog/temporalite"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "port in use", err: fmt.Errorf("listen: %w", temporalite.ErrPortInUse), want: exitPortInUse},
+		{name: "schema mismatch", err: temporalite.ErrSchemaMismatch, want: exitSchemaMismatch},
+		{name: "database locked", err: temporalite.ErrDatabaseLocked, want: exitDatabaseLocked},
+		{name: "other", err: errors.New("unable to start"), want: exitStartFailed},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := exitCode(tc.err, exitStartFailed); got != tc.want {
+				t.Errorf("exitCode() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+// fakeRunnableServer runs until stopped, or returns startErr from Start.
+type fakeRunnableServer struct {
+	startErr error
+	errCh    chan error
+	stopped  chan struct{}
+}
+
+func (f *fakeRunnableServer) Start() error {
+	if f.startErr != nil {
+		return f.startErr
+	}
+	<-f.stopped
+	return nil
+}
+
+func (f *fakeRunnableServer) Stop() {
+	close(f.stopped)
+}
+
+func (f *fakeRunnableServer) Err() <-chan error {
+	return f.errCh
+}
+
+func TestRunServer(t *testing.T) {
+	t.Cleanup(func() { signal.Reset(os.Interrupt, syscall.SIGTERM) })
+	tests := []struct {
+		name        string
+		interrupted bool
+		startErr    error
+		fatalErr    error
+		want        int
+	}{
+		{name: "interrupted", interrupted: true, want: 0},
+		{name: "start failed", startErr: errors.New("unable to start"), want: exitStartFailed},
+		{name: "port in use", startErr: temporalite.ErrPortInUse, want: exitPortInUse},
+		{name: "fatal error", fatalErr: errors.New("temporal service fatal error"), want: exitFatal},
+		{name: "fatal port in use", fatalErr: fmt.Errorf("listen: %w", temporalite.ErrPortInUse), want: exitPortInUse},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &fakeRunnableServer{startErr: tc.startErr, errCh: make(chan error, 1), stopped: make(chan struct{})}
+			if tc.interrupted {
+				close(s.stopped)
+			}
+			if tc.fatalErr != nil {
+				s.errCh <- tc.fatalErr
+			}
+			if got := runServer(s).ExitCode(); got != tc.want {
+				t.Errorf("runServer() exit code = %d, want %d", got, tc.want)
+			}
+			if tc.fatalErr != nil {
+				select {
+				case <-s.stopped:
+				default:
+					t.Error("server was not stopped after a fatal error")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/DataDog/temporalite/internal/liteconfig"
+)
+
+const (
+	imageFlag      = "image"
+	prometheusFlag = "prometheus"
+	volumeSizeFlag = "volume-size"
+
+	// prometheusPort is the port of the Prometheus server added by --prometheus.
+	prometheusPort = 9090
+	// dataDir is where the data volume is mounted in the container.
+	dataDir = "/data"
+)
+
+// composeQuote quotes s for a compose file, escaping variable interpolation.
+func composeQuote(s string) string {
+	return strings.ReplaceAll(strconv.Quote(s), "$", "$$")
+}
+
+// quoteList quotes each of args for a YAML flow sequence.
+func quoteList(args []string, quote func(string) string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quote(arg)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+var deploymentFuncs = template.FuncMap{
+	"quote":        strconv.Quote,
+	"composeQuote": composeQuote,
+	"quoteList":    func(args []string) string { return quoteList(args, strconv.Quote) },
+	"composeQuoteList": func(args []string) string {
+		return quoteList(args, composeQuote)
+	},
+}
+
+var composeFile = template.Must(template.New("compose").Funcs(deploymentFuncs).Parse(`# Generated by temporalite generate compose. Build the image from the
+# temporalite repository with: docker build -t temporalite .
+services:
+  temporalite:
+    image: {{ .Image }}
+    entrypoint: [{{ composeQuoteList .Args }}]
+    ports:
+      - "{{ .Port }}:{{ .Port }}"
+{{- if not .Headless }}
+      - "{{ .UIPort }}:{{ .UIPort }}"
+{{- end }}
+{{- if .Prometheus }}
+      - "{{ .PrometheusPort }}:{{ .PrometheusPort }}"
+{{- end }}
+{{- if not .Ephemeral }}
+    volumes:
+      - temporalite-data:{{ .DataDir }}
+{{- end }}
+    restart: unless-stopped
+{{- if .Prometheus }}
+  prometheus:
+    image: prom/prometheus
+    # Metrics are served on the loopback interface of the temporalite
+    # container, so Prometheus shares its network and is published there.
+    network_mode: service:temporalite
+    entrypoint: ["/bin/sh", "-c", {{ composeQuote .PrometheusCommand }}]
+{{- end }}
+{{- if not .Ephemeral }}
+
+volumes:
+  temporalite-data:
+{{- end }}
+`))
+
+var kubernetesManifest = template.Must(template.New("k8s").Funcs(deploymentFuncs).Parse(`# Generated by temporalite generate k8s. Build the image from the temporalite
+# repository with: docker build -t temporalite .
+{{- if not .Ephemeral }}
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: temporalite-data
+spec:
+  accessModes: [ReadWriteOnce]
+  resources:
+    requests:
+      storage: {{ .VolumeSize }}
+---
+{{- end }}
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: temporalite
+  labels:
+    app: temporalite
+spec:
+  # A single server owns the database, so the old pod must stop first.
+  replicas: 1
+  strategy:
+    type: Recreate
+  selector:
+    matchLabels:
+      app: temporalite
+  template:
+    metadata:
+      labels:
+        app: temporalite
+    spec:
+      containers:
+        - name: temporalite
+          image: {{ .Image }}
+          command: [{{ quoteList .Args }}]
+          ports:
+            - name: frontend
+              containerPort: {{ .Port }}
+{{- if not .Headless }}
+            - name: ui
+              containerPort: {{ .UIPort }}
+{{- end }}
+          readinessProbe:
+            tcpSocket:
+              port: frontend
+{{- if not .Ephemeral }}
+          volumeMounts:
+            - name: data
+              mountPath: {{ .DataDir }}
+{{- end }}
+{{- if .Prometheus }}
+        # Metrics are served on the pod's loopback interface.
+        - name: prometheus
+          image: prom/prometheus
+          command: ["/bin/sh", "-c", {{ quote .PrometheusCommand }}]
+          ports:
+            - name: prometheus
+              containerPort: {{ .PrometheusPort }}
+{{- end }}
+{{- if not .Ephemeral }}
+      volumes:
+        - name: data
+          persistentVolumeClaim:
+            claimName: temporalite-data
+{{- end }}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: temporalite
+spec:
+  selector:
+    app: temporalite
+  ports:
+    - name: frontend
+      port: {{ .Port }}
+      targetPort: frontend
+{{- if not .Headless }}
+    - name: ui
+      port: {{ .UIPort }}
+      targetPort: ui
+{{- end }}
+{{- if .Prometheus }}
+    - name: prometheus
+      port: {{ .PrometheusPort }}
+      targetPort: prometheus
+{{- end }}
+`))
+
+// deployment fills in composeFile and kubernetesManifest.
+type deployment struct {
+	Image             string
+	Args              []string
+	Port              int
+	UIPort            int
+	Headless          bool
+	Ephemeral         bool
+	Prometheus        bool
+	PrometheusPort    int
+	PrometheusCommand string
+	DataDir           string
+	VolumeSize        string
+}
+
+// newDeployment returns the deployment configured by the flags of c. Arguments
+// after the flags are passed on to temporalite start.
+func newDeployment(c *cli.Context) deployment {
+	d := deployment{
+		Image:          c.String(imageFlag),
+		Port:           c.Int(portFlag),
+		UIPort:         c.Int(portFlag) + 1000,
+		Headless:       c.Bool(headlessFlag),
+		Ephemeral:      c.Bool(ephemeralFlag),
+		Prometheus:     c.Bool(prometheusFlag),
+		PrometheusPort: prometheusPort,
+		DataDir:        dataDir,
+		VolumeSize:     c.String(volumeSizeFlag),
+	}
+	if c.IsSet(uiPortFlag) {
+		d.UIPort = c.Int(uiPortFlag)
+	}
+
+	d.Args = []string{"/temporalite", "start", "--" + ipFlag, "0.0.0.0", "--" + portFlag, strconv.Itoa(d.Port)}
+	if d.Headless {
+		d.Args = append(d.Args, "--"+headlessFlag)
+	} else {
+		d.Args = append(d.Args, "--"+uiPortFlag, strconv.Itoa(d.UIPort))
+	}
+	if d.Ephemeral {
+		d.Args = append(d.Args, "--"+ephemeralFlag)
+	} else {
+		d.Args = append(d.Args, "--"+dbPathFlag, dataDir+"/temporalite.db")
+	}
+	for _, ns := range c.StringSlice(namespaceFlag) {
+		d.Args = append(d.Args, "--"+namespaceFlag, ns)
+	}
+	d.Args = append(d.Args, c.Args().Slice()...)
+
+	d.PrometheusCommand = fmt.Sprintf("printf 'scrape_configs:\\n  - job_name: temporalite\\n    static_configs:\\n      - targets: [\"127.0.0.1:%d\"]\\n' "+
+		"> /tmp/prometheus.yml && exec /bin/prometheus --config.file=/tmp/prometheus.yml --storage.tsdb.path=/prometheus",
+		d.Port+200)
+	return d
+}
+
+// generateSubcommand returns a subcommand writing a file from tmpl to stdout.
+func generateSubcommand(name, usage string, tmpl *template.Template, extraFlags ...cli.Flag) *cli.Command {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:  imageFlag,
+			Usage: "temporalite container image, built from the repository's Dockerfile",
+			Value: "temporalite",
+		},
+		&cli.IntFlag{
+			Name:    portFlag,
+			Aliases: []string{"p"},
+			Usage:   "port for the temporal-frontend GRPC service",
+			Value:   liteconfig.DefaultFrontendPort,
+		},
+		&cli.IntFlag{
+			Name:        uiPortFlag,
+			Usage:       "port for the temporal web UI",
+			DefaultText: fmt.Sprintf("--%s + 1000", portFlag),
+		},
+		&cli.BoolFlag{
+			Name:  headlessFlag,
+			Usage: "disable the web UI",
+		},
+		&cli.StringSliceFlag{
+			Name:    namespaceFlag,
+			Aliases: []string{"n"},
+			Usage:   "namespaces to pre-create",
+			Value:   cli.NewStringSlice("default"),
+		},
+		&cli.BoolFlag{
+			Name:  ephemeralFlag,
+			Usage: "use in-memory storage instead of a data volume",
+		},
+		&cli.BoolFlag{
+			Name:  prometheusFlag,
+			Usage: fmt.Sprintf("add a Prometheus server scraping the server's metrics, on port %d", prometheusPort),
+		},
+	}
+	return &cli.Command{
+		Name:      name,
+		Usage:     usage,
+		ArgsUsage: "[-- START FLAGS]",
+		Description: "Writes the file to stdout. Flags after -- are passed to temporalite start in the container, " +
+			"for example: -- --max-blob-size 256KiB",
+		Flags: append(flags, extraFlags...),
+		Action: func(c *cli.Context) error {
+			if c.Bool(headlessFlag) && c.IsSet(uiPortFlag) {
+				return cli.Exit(fmt.Sprintf("ERROR: only one of %q or %q flags may be passed at a time", headlessFlag, uiPortFlag), exitConfigError)
+			}
+			return tmpl.Execute(c.App.Writer, newDeployment(c))
+		},
+	}
+}
+
+func generateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "generate",
+		Usage: "Generate configuration for running temporalite in a shared environment",
+		Subcommands: []*cli.Command{
+			generateSubcommand("compose", "Generate a docker-compose file", composeFile),
+			generateSubcommand("k8s", "Generate Kubernetes manifests", kubernetesManifest,
+				&cli.StringFlag{
+					Name:  volumeSizeFlag,
+					Usage: "size of the persistent volume holding the database",
+					Value: "1Gi",
+				},
+			),
+		},
+	}
+}
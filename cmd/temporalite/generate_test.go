@@ -0,0 +1,150 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenerateCompose(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		wantArgs     []string
+		wantPorts    []string
+		wantServices []string
+		wantVolumes  bool
+		wantErr      bool
+	}{
+		{
+			name:         "defaults",
+			wantArgs:     []string{"/temporalite", "start", "--ip", "0.0.0.0", "--port", "7233", "--ui-port", "8233", "--filename", "/data/temporalite.db", "--namespace", "default"},
+			wantPorts:    []string{"7233:7233", "8233:8233"},
+			wantServices: []string{"temporalite"},
+			wantVolumes:  true,
+		},
+		{
+			name:         "ephemeral headless with prometheus",
+			args:         []string{"--ephemeral", "--headless", "--prometheus", "--port", "7000", "-n", "orders", "--", "--max-blob-size", "$1MiB"},
+			wantArgs:     []string{"/temporalite", "start", "--ip", "0.0.0.0", "--port", "7000", "--headless", "--ephemeral", "--namespace", "orders", "--max-blob-size", "$1MiB"},
+			wantPorts:    []string{"7000:7000", "9090:9090"},
+			wantServices: []string{"prometheus", "temporalite"},
+		},
+		{
+			name:    "headless with UI port",
+			args:    []string{"--headless", "--ui-port", "9000"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := runCommand(generateCommand(), append([]string{"generate", "compose"}, tc.args...)...)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("generate compose error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			var compose struct {
+				Services map[string]struct {
+					Entrypoint []string `yaml:"entrypoint"`
+					Ports      []string `yaml:"ports"`
+				} `yaml:"services"`
+				Volumes map[string]interface{} `yaml:"volumes"`
+			}
+			if err := yaml.Unmarshal([]byte(out), &compose); err != nil {
+				t.Fatalf("invalid compose file: %v\n%s", err, out)
+			}
+			var services []string
+			for name := range compose.Services {
+				services = append(services, name)
+			}
+			sort.Strings(services)
+			if !reflect.DeepEqual(services, tc.wantServices) {
+				t.Errorf("services = %v, want %v", services, tc.wantServices)
+			}
+			server := compose.Services["temporalite"]
+			// Compose interpolates variables, so a literal $ is written as $$.
+			var args []string
+			for _, arg := range server.Entrypoint {
+				args = append(args, strings.ReplaceAll(arg, "$$", "$"))
+			}
+			if !reflect.DeepEqual(args, tc.wantArgs) {
+				t.Errorf("entrypoint = %q, want %q", args, tc.wantArgs)
+			}
+			if !reflect.DeepEqual(server.Ports, tc.wantPorts) {
+				t.Errorf("ports = %v, want %v", server.Ports, tc.wantPorts)
+			}
+			if gotVolumes := compose.Volumes != nil; gotVolumes != tc.wantVolumes {
+				t.Errorf("volumes declared = %v, want %v", gotVolumes, tc.wantVolumes)
+			}
+		})
+	}
+}
+
+func TestGenerateKubernetes(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantKinds []string
+		want      []string
+	}{
+		{
+			name:      "defaults",
+			wantKinds: []string{"PersistentVolumeClaim", "Deployment", "Service"},
+			want:      []string{"storage: 1Gi", "containerPort: 8233", "claimName: temporalite-data"},
+		},
+		{
+			name:      "ephemeral with prometheus",
+			args:      []string{"--ephemeral", "--prometheus", "--image", "registry.local/temporalite:1.0"},
+			wantKinds: []string{"Deployment", "Service"},
+			want:      []string{"image: registry.local/temporalite:1.0", "containerPort: 9090", `"--ephemeral"`},
+		},
+		{
+			name:      "volume size",
+			args:      []string{"--volume-size", "10Gi"},
+			wantKinds: []string{"PersistentVolumeClaim", "Deployment", "Service"},
+			want:      []string{"storage: 10Gi"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := runCommand(generateCommand(), append([]string{"generate", "k8s"}, tc.args...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var kinds []string
+			dec := yaml.NewDecoder(strings.NewReader(out))
+			for {
+				var doc struct {
+					Kind string `yaml:"kind"`
+				}
+				if err := dec.Decode(&doc); errors.Is(err, io.EOF) {
+					break
+				} else if err != nil {
+					t.Fatalf("invalid manifest: %v\n%s", err, out)
+				}
+				kinds = append(kinds, doc.Kind)
+			}
+			if !reflect.DeepEqual(kinds, tc.wantKinds) {
+				t.Errorf("kinds = %v, want %v", kinds, tc.wantKinds)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("manifest does not contain %q", want)
+				}
+			}
+		})
+	}
+}
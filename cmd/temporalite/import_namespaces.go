@@ -0,0 +1,179 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/urfave/cli/v2"
+	enumspb "go.temporal.io/api/enums/v1"
+	namespacepb "go.temporal.io/api/namespace/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/api/adminservice/v1"
+	"go.temporal.io/server/common"
+)
+
+const fromJSONFlag = "from-json"
+
+// namespaceExport is the namespace configuration read by import-namespace-config.
+type namespaceExport struct {
+	namespaces       []*workflowservice.DescribeNamespaceResponse
+	searchAttributes map[string]enumspb.IndexedValueType
+}
+
+// parseNamespaceExport reads namespaces in the JSON form of a DescribeNamespace
+// response, a list of them, or a ListNamespaces response. An object may also
+// carry custom search attributes under customAttributes, as in the JSON form of
+// the admin service's GetSearchAttributes response.
+func parseNamespaceExport(data []byte) (*namespaceExport, error) {
+	unmarshaler := jsonpb.Unmarshaler{AllowUnknownFields: true}
+	var export namespaceExport
+	var raw []json.RawMessage
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	} else {
+		var doc struct {
+			Namespaces       []json.RawMessage `json:"namespaces"`
+			NamespaceInfo    json.RawMessage   `json:"namespaceInfo"`
+			CustomAttributes json.RawMessage   `json:"customAttributes"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		raw = doc.Namespaces
+		if doc.NamespaceInfo != nil {
+			raw = append(raw, data)
+		}
+		if doc.CustomAttributes != nil {
+			attrs := &adminservice.GetSearchAttributesResponse{}
+			if err := unmarshaler.Unmarshal(bytes.NewReader(data), attrs); err != nil {
+				return nil, fmt.Errorf("invalid customAttributes: %w", err)
+			}
+			export.searchAttributes = attrs.GetCustomAttributes()
+		}
+	}
+	for _, r := range raw {
+		ns := &workflowservice.DescribeNamespaceResponse{}
+		if err := unmarshaler.Unmarshal(bytes.NewReader(r), ns); err != nil {
+			return nil, err
+		}
+		if ns.GetNamespaceInfo().GetName() == "" {
+			return nil, fmt.Errorf("namespace without a name in %s", r)
+		}
+		export.namespaces = append(export.namespaces, ns)
+	}
+	return &export, nil
+}
+
+func importNamespaceConfigCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "import-namespace-config",
+		Usage:     "Recreate namespaces and custom search attributes exported from another cluster",
+		ArgsUsage: " ",
+		Description: "Reads namespaces in the JSON form of DescribeNamespace or ListNamespaces API responses and " +
+			"registers them with the same description, owner, data, and retention, updating namespaces that already " +
+			"exist. Custom search attributes listed under customAttributes are registered too. Replication and " +
+			"archival settings are not imported.",
+		Flags: []cli.Flag{
+			newAddressFlag(),
+			&cli.StringFlag{
+				Name:     fromJSONFlag,
+				Usage:    "exported namespace configuration `FILE`",
+				Required: true,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			data, err := os.ReadFile(c.String(fromJSONFlag))
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: %v", err), 1)
+			}
+			export, err := parseNamespaceExport(data)
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: unable to parse %s: %v", c.String(fromJSONFlag), err), 1)
+			}
+
+			conn, err := dialFrontend(c)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			svc := workflowservice.NewWorkflowServiceClient(conn)
+
+			for _, ns := range export.namespaces {
+				info, cfg := ns.GetNamespaceInfo(), ns.GetConfig()
+				if info.GetName() == common.SystemLocalNamespace {
+					continue
+				}
+				_, err := svc.RegisterNamespace(c.Context, &workflowservice.RegisterNamespaceRequest{
+					Namespace:                        info.GetName(),
+					Description:                      info.GetDescription(),
+					OwnerEmail:                       info.GetOwnerEmail(),
+					Data:                             info.GetData(),
+					WorkflowExecutionRetentionPeriod: cfg.GetWorkflowExecutionRetentionTtl(),
+				})
+				if _, ok := err.(*serviceerror.NamespaceAlreadyExists); ok {
+					_, err = svc.UpdateNamespace(c.Context, &workflowservice.UpdateNamespaceRequest{
+						Namespace: info.GetName(),
+						UpdateInfo: &namespacepb.UpdateNamespaceInfo{
+							Description: info.GetDescription(),
+							OwnerEmail:  info.GetOwnerEmail(),
+							Data:        info.GetData(),
+						},
+						Config: &namespacepb.NamespaceConfig{WorkflowExecutionRetentionTtl: cfg.GetWorkflowExecutionRetentionTtl()},
+					})
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("ERROR: unable to update namespace %s: %v", info.GetName(), err), 1)
+					}
+					fmt.Printf("Updated namespace %s\n", info.GetName())
+					continue
+				} else if err != nil {
+					return cli.Exit(fmt.Sprintf("ERROR: unable to register namespace %s: %v", info.GetName(), err), 1)
+				}
+				fmt.Printf("Registered namespace %s\n", info.GetName())
+			}
+
+			if len(export.searchAttributes) == 0 {
+				return nil
+			}
+			admin := adminservice.NewAdminServiceClient(conn)
+			registered, err := admin.GetSearchAttributes(c.Context, &adminservice.GetSearchAttributesRequest{})
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: unable to get search attributes: %v", err), 1)
+			}
+			missing := make(map[string]enumspb.IndexedValueType)
+			for name, typ := range export.searchAttributes {
+				existing, ok := registered.GetCustomAttributes()[name]
+				if !ok {
+					missing[name] = typ
+				} else if existing != typ {
+					return cli.Exit(fmt.Sprintf("ERROR: search attribute %s is already registered as %s, not %s", name, existing, typ), 1)
+				}
+			}
+			if len(missing) == 0 {
+				return nil
+			}
+			if _, err := admin.AddSearchAttributes(c.Context, &adminservice.AddSearchAttributesRequest{SearchAttributes: missing}); err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: unable to register search attributes: %v", err), 1)
+			}
+			names := make([]string, 0, len(missing))
+			for name := range missing {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Printf("Registered search attribute %s\t%s\n", name, missing[name])
+			}
+			return nil
+		},
+	}
+}
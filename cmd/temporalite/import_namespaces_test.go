@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	enumspb "go.temporal.io/api/enums/v1"
+)
+
+func TestParseNamespaceExport(t *testing.T) {
+	tests := []struct {
+		name                 string
+		data                 string
+		wantNamespaces       []string
+		wantSearchAttributes map[string]enumspb.IndexedValueType
+		wantErr              bool
+	}{
+		{
+			name:           "DescribeNamespace response",
+			data:           `{"namespaceInfo": {"name": "orders", "owner": "team"}, "isGlobalNamespace": false}`,
+			wantNamespaces: []string{"orders"},
+		},
+		{
+			name:           "list of DescribeNamespace responses",
+			data:           ` [{"namespaceInfo": {"name": "orders"}}, {"namespaceInfo": {"name": "billing"}}]`,
+			wantNamespaces: []string{"orders", "billing"},
+		},
+		{
+			name:           "ListNamespaces response",
+			data:           `{"namespaces": [{"namespaceInfo": {"name": "orders"}}], "nextPageToken": null}`,
+			wantNamespaces: []string{"orders"},
+		},
+		{
+			name:                 "custom search attributes",
+			data:                 `{"namespaces": [{"namespaceInfo": {"name": "orders"}}], "customAttributes": {"CustomerId": "Keyword"}}`,
+			wantNamespaces:       []string{"orders"},
+			wantSearchAttributes: map[string]enumspb.IndexedValueType{"CustomerId": enumspb.INDEXED_VALUE_TYPE_KEYWORD},
+		},
+		{
+			name:    "invalid search attribute type",
+			data:    `{"namespaces": [], "customAttributes": {"CustomerId": "String"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "namespace without a name",
+			data:    `[{"namespaceInfo": {"owner": "team"}}]`,
+			wantErr: true,
+		},
+		{
+			name:    "not JSON",
+			data:    `orders`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseNamespaceExport([]byte(tc.data))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseNamespaceExport() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			var names []string
+			for _, ns := range got.namespaces {
+				names = append(names, ns.GetNamespaceInfo().GetName())
+			}
+			if !reflect.DeepEqual(names, tc.wantNamespaces) {
+				t.Errorf("namespaces = %v, want %v", names, tc.wantNamespaces)
+			}
+			if !reflect.DeepEqual(got.searchAttributes, tc.wantSearchAttributes) {
+				t.Errorf("search attributes = %v, want %v", got.searchAttributes, tc.wantSearchAttributes)
+			}
+		})
+	}
+}
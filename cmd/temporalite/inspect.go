@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	uiserver "github.com/temporalio/ui-server/server"
+	uiconfig "github.com/temporalio/ui-server/server/config"
+	uiserveroptions "github.com/temporalio/ui-server/server/server_options"
+	"github.com/urfave/cli/v2"
+	"go.temporal.io/server/temporal"
+
+	"github.com/DataDog/temporalite"
+	"github.com/DataDog/temporalite/internal/liteconfig"
+)
+
+// copyDatabase copies the database at src, along with any write-ahead log, into dir.
+func copyDatabase(src, dir string) (string, error) {
+	dst := filepath.Join(dir, filepath.Base(src))
+	for _, suffix := range []string{"", "-wal"} {
+		if err := copyFile(src+suffix, dst+suffix); err != nil {
+			if suffix != "" && os.IsNotExist(err) {
+				continue
+			}
+			return "", err
+		}
+	}
+	return dst, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func inspectCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "inspect",
+		Usage:     "Browse workflow histories in a copy of a database without modifying it",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     dbPathFlag,
+				Aliases:  []string{"f"},
+				Usage:    "database `FILE` to inspect",
+				Required: true,
+			},
+			&cli.IntFlag{
+				Name:    portFlag,
+				Aliases: []string{"p"},
+				Usage:   "port for the temporal-frontend GRPC service",
+				Value:   liteconfig.DefaultFrontendPort,
+			},
+			&cli.IntFlag{
+				Name:        uiPortFlag,
+				Usage:       "port for the temporal web UI",
+				DefaultText: fmt.Sprintf("--port + 1000, eg. %d", liteconfig.DefaultFrontendPort+1000),
+			},
+		},
+		Action: func(c *cli.Context) error {
+			serverPort := c.Int(portFlag)
+			uiPort := serverPort + 1000
+			if c.IsSet(uiPortFlag) {
+				uiPort = c.Int(uiPortFlag)
+			}
+			uiOpts := uiconfig.Config{
+				TemporalGRPCAddress: fmt.Sprintf(":%d", serverPort),
+				Port:                uiPort,
+				EnableUI:            true,
+			}
+
+			s, err := temporalite.NewServer(
+				temporalite.WithFrontendPort(serverPort),
+				// Read-only servers leave the file untouched.
+				temporalite.WithDatabaseFilePath(c.String(dbPathFlag)),
+				temporalite.WithReadOnly(),
+				temporalite.WithUpstreamOptions(
+					temporal.InterruptOn(temporal.InterruptCh()),
+				),
+				temporalite.WithUI(uiserver.NewServer(uiserveroptions.WithConfig(&uiOpts))),
+			)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Inspecting %s at http://localhost:%d\n", c.String(dbPathFlag), uiPort)
+			if err := s.Start(); err != nil {
+				return cli.Exit(fmt.Sprintf("Unable to start server. Error: %v", err), 1)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,217 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/urfave/cli/v2"
+	enumspb "go.temporal.io/api/enums/v1"
+	historypb "go.temporal.io/api/history/v1"
+	"go.temporal.io/sdk/client"
+
+	"github.com/DataDog/temporalite/demo"
+)
+
+// A lesson runs one demo workflow, explaining each event of its history as it
+// is recorded.
+type lesson struct {
+	title string
+	intro string
+	start func(ctx context.Context, tc client.Client, opts client.StartWorkflowOptions) (client.WorkflowRun, error)
+	// prompt and act, if set, drive the workflow once its first workflow task
+	// completes and it is waiting for them.
+	prompt string
+	act    func(ctx context.Context, tc client.Client, run client.WorkflowRun) error
+	outro  string
+}
+
+var lessons = []lesson{
+	{
+		title: "Workflows and activities",
+		intro: "Workflow code decides what happens next, while activities do the actual work, such as calling other " +
+			"services. Activities fail, so the server retries them according to a retry policy. This workflow runs an " +
+			"activity that fails twice before succeeding.",
+		start: func(ctx context.Context, tc client.Client, opts client.StartWorkflowOptions) (client.WorkflowRun, error) {
+			return tc.ExecuteWorkflow(ctx, opts, demo.RetryWorkflow)
+		},
+		outro: "The failed attempts are not in the history: the server only records the attempt that completed, " +
+			"along with the last failure. Workflow code never saw the failures.",
+	},
+	{
+		title: "Child workflows",
+		intro: "A workflow can start other workflows as its children, to split up work or run it in parallel. Each " +
+			"child has its own history. This workflow starts a child per name and gathers their greetings.",
+		start: func(ctx context.Context, tc client.Client, opts client.StartWorkflowOptions) (client.WorkflowRun, error) {
+			return tc.ExecuteWorkflow(ctx, opts, demo.ParentWorkflow, []string{"Alice", "Bob"})
+		},
+		outro: "The parent only recorded that its children started and completed; their activities are in their own histories.",
+	},
+	{
+		title: "Signals",
+		intro: "Signals deliver data to running workflows, for example a human approval. A workflow waiting for a " +
+			"signal uses no worker resources, and can wait for days or months. This workflow waits for an approval.",
+		start: func(ctx context.Context, tc client.Client, opts client.StartWorkflowOptions) (client.WorkflowRun, error) {
+			return tc.ExecuteWorkflow(ctx, opts, demo.ApprovalWorkflow)
+		},
+		prompt: fmt.Sprintf("The workflow is now blocked until it receives an %q signal. Press Enter to send it.", demo.ApprovalSignal),
+		act: func(ctx context.Context, tc client.Client, run client.WorkflowRun) error {
+			return tc.SignalWorkflow(ctx, run.GetID(), run.GetRunID(), demo.ApprovalSignal, "temporalite learn")
+		},
+		outro: "The signal woke the workflow up with a new workflow task, in which it read the signal and completed.",
+	},
+}
+
+// explainEvent returns what happened when event was recorded.
+func explainEvent(event *historypb.HistoryEvent) string {
+	switch event.GetEventType() {
+	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_STARTED:
+		return "The server accepted the request to start the workflow and recorded its input."
+	case enumspb.EVENT_TYPE_WORKFLOW_TASK_SCHEDULED:
+		return "The server queued a workflow task, asking a worker to run the workflow code."
+	case enumspb.EVENT_TYPE_WORKFLOW_TASK_STARTED:
+		return "A worker picked up the workflow task."
+	case enumspb.EVENT_TYPE_WORKFLOW_TASK_COMPLETED:
+		return "The worker ran the workflow code until it had to wait, and sent back what to do next as commands."
+	case enumspb.EVENT_TYPE_ACTIVITY_TASK_SCHEDULED:
+		return fmt.Sprintf("The workflow asked for activity %s to run, and the server queued it for a worker.",
+			event.GetActivityTaskScheduledEventAttributes().GetActivityType().GetName())
+	case enumspb.EVENT_TYPE_ACTIVITY_TASK_STARTED:
+		attrs := event.GetActivityTaskStartedEventAttributes()
+		if attrs.GetAttempt() > 1 {
+			return fmt.Sprintf("A worker ran the activity on attempt %d, after the last attempt failed with: %s",
+				attrs.GetAttempt(), attrs.GetLastFailure().GetMessage())
+		}
+		return "A worker picked up the activity."
+	case enumspb.EVENT_TYPE_ACTIVITY_TASK_COMPLETED:
+		return "The activity returned a result, and the server scheduled a workflow task to hand it to the workflow."
+	case enumspb.EVENT_TYPE_ACTIVITY_TASK_FAILED:
+		return "The activity failed for good, and the workflow receives the error."
+	case enumspb.EVENT_TYPE_START_CHILD_WORKFLOW_EXECUTION_INITIATED:
+		return fmt.Sprintf("The workflow asked to start a child %s workflow.",
+			event.GetStartChildWorkflowExecutionInitiatedEventAttributes().GetWorkflowType().GetName())
+	case enumspb.EVENT_TYPE_CHILD_WORKFLOW_EXECUTION_STARTED:
+		return fmt.Sprintf("The child workflow %s started.",
+			event.GetChildWorkflowExecutionStartedEventAttributes().GetWorkflowExecution().GetWorkflowId())
+	case enumspb.EVENT_TYPE_CHILD_WORKFLOW_EXECUTION_COMPLETED:
+		return fmt.Sprintf("The child workflow %s completed, and its result is handed to the parent.",
+			event.GetChildWorkflowExecutionCompletedEventAttributes().GetWorkflowExecution().GetWorkflowId())
+	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_SIGNALED:
+		return fmt.Sprintf("The server recorded the %q signal and scheduled a workflow task to deliver it.",
+			event.GetWorkflowExecutionSignaledEventAttributes().GetSignalName())
+	case enumspb.EVENT_TYPE_TIMER_STARTED:
+		return "The workflow started a durable timer, which fires even if workers restart."
+	case enumspb.EVENT_TYPE_TIMER_FIRED:
+		return "The timer fired, and the server scheduled a workflow task to wake the workflow up."
+	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_COMPLETED:
+		return "The workflow returned, and its result was recorded. The history is now closed."
+	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_FAILED:
+		return "The workflow returned an error. The history is now closed."
+	}
+	return ""
+}
+
+// awaitEnter waits for the user to press Enter, or returns immediately when
+// stdin is not a terminal.
+func awaitEnter(ctx context.Context, stdin *bufio.Reader, interactive bool, prompt string) error {
+	fmt.Println(prompt)
+	if !interactive {
+		return nil
+	}
+	read := make(chan error, 1)
+	go func() {
+		_, err := stdin.ReadString('\n')
+		read <- err
+	}()
+	select {
+	case err := <-read:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runLesson starts the lesson's workflow and explains its history until it closes.
+func runLesson(ctx context.Context, env *demoEnv, l lesson, id string, stdin *bufio.Reader, interactive bool) error {
+	run, err := l.start(ctx, env.client, client.StartWorkflowOptions{ID: id, TaskQueue: demo.TaskQueue})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Started workflow %s: %s\n\n", id, demoWorkflowURL(env.uiAddress, run))
+
+	acted := l.act == nil
+	iter := env.client.GetWorkflowHistory(ctx, run.GetID(), run.GetRunID(), true, enumspb.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT)
+	for iter.HasNext() {
+		event, err := iter.Next()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("  %3d %s\n", event.GetEventId(), event.GetEventType())
+		if text := explainEvent(event); text != "" {
+			fmt.Printf("      %s\n", text)
+		}
+		if !acted && event.GetEventType() == enumspb.EVENT_TYPE_WORKFLOW_TASK_COMPLETED {
+			acted = true
+			fmt.Println()
+			if err := awaitEnter(ctx, stdin, interactive, l.prompt); err != nil {
+				return err
+			}
+			if err := l.act(ctx, env.client, run); err != nil {
+				return err
+			}
+		}
+	}
+	fmt.Printf("\n%s\n", l.outro)
+	return run.Get(ctx, nil)
+}
+
+func learnCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "learn",
+		Usage:     "Walk through how Temporal runs workflows, one event at a time",
+		ArgsUsage: " ",
+		Description: "Starts an in-memory server and a worker like the demo command, then runs its workflows one " +
+			"lesson at a time, explaining each event as the server records it. Press Enter to move on when " +
+			"prompted; prompts are skipped when stdin is not a terminal.",
+		Flags: demoFlags(),
+		Action: func(c *cli.Context) error {
+			ctx, stop := signal.NotifyContext(c.Context, os.Interrupt)
+			defer stop()
+
+			env, err := startDemo(ctx, c)
+			if err != nil {
+				return err
+			}
+			defer env.stop()
+
+			stat, err := os.Stdin.Stat()
+			interactive := err == nil && stat.Mode()&os.ModeCharDevice != 0
+			stdin := bufio.NewReader(os.Stdin)
+
+			fmt.Printf("Temporal server listening on %s, web UI at %s\n", env.server.FrontendHostPort(), env.uiAddress)
+			fmt.Println("Every workflow has a history of events, which the server records as the workflow runs and " +
+				"workers replay to recover its state.")
+			for i, l := range lessons {
+				fmt.Printf("\n%d. %s\n\n%s\n\n", i+1, l.title, l.intro)
+				if err := awaitEnter(ctx, stdin, interactive, "Press Enter to start the workflow."); err != nil {
+					return nil
+				}
+				if err := runLesson(ctx, env, l, fmt.Sprintf("learn-%d", i+1), stdin, interactive); err != nil {
+					if ctx.Err() != nil {
+						return nil
+					}
+					return cli.Exit(fmt.Sprintf("ERROR: %v", err), 1)
+				}
+			}
+			fmt.Println("\nThat's it! The demo package has the code of these workflows.")
+			env.wait(ctx)
+			return nil
+		},
+	}
+}
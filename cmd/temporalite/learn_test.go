@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	failurepb "go.temporal.io/api/failure/v1"
+	historypb "go.temporal.io/api/history/v1"
+
+	"github.com/DataDog/temporalite/demo"
+	"github.com/DataDog/temporalite/temporaltest"
+)
+
+func TestExplainEvent(t *testing.T) {
+	tests := []struct {
+		name  string
+		event *historypb.HistoryEvent
+		want  string
+	}{
+		{
+			name:  "first activity attempt",
+			event: &historypb.HistoryEvent{EventType: enumspb.EVENT_TYPE_ACTIVITY_TASK_STARTED},
+			want:  "A worker picked up the activity.",
+		},
+		{
+			name: "retried activity",
+			event: &historypb.HistoryEvent{
+				EventType: enumspb.EVENT_TYPE_ACTIVITY_TASK_STARTED,
+				Attributes: &historypb.HistoryEvent_ActivityTaskStartedEventAttributes{ActivityTaskStartedEventAttributes: &historypb.ActivityTaskStartedEventAttributes{
+					Attempt:     3,
+					LastFailure: &failurepb.Failure{Message: "connection refused"},
+				}},
+			},
+			want: "attempt 3, after the last attempt failed with: connection refused",
+		},
+		{
+			name: "signal",
+			event: &historypb.HistoryEvent{
+				EventType: enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_SIGNALED,
+				Attributes: &historypb.HistoryEvent_WorkflowExecutionSignaledEventAttributes{WorkflowExecutionSignaledEventAttributes: &historypb.WorkflowExecutionSignaledEventAttributes{
+					SignalName: "approve",
+				}},
+			},
+			want: `the "approve" signal`,
+		},
+		{
+			name:  "unexplained",
+			event: &historypb.HistoryEvent{EventType: enumspb.EVENT_TYPE_MARKER_RECORDED},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := explainEvent(tc.event)
+			if (tc.want == "") != (got == "") || !strings.Contains(got, tc.want) {
+				t.Errorf("explainEvent() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAwaitEnter(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		interactive bool
+		wantErr     bool
+	}{
+		{name: "not interactive"},
+		{name: "enter", input: "\n", interactive: true},
+		{name: "end of input", interactive: true, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stdin := bufio.NewReader(strings.NewReader(tc.input))
+			if err := awaitEnter(context.Background(), stdin, tc.interactive, "Press Enter."); (err != nil) != tc.wantErr {
+				t.Errorf("awaitEnter() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestLessons(t *testing.T) {
+	ts := temporaltest.NewServer(temporaltest.WithT(t))
+	ts.Worker(demo.TaskQueue, demo.Register)
+	env := &demoEnv{client: ts.Client(), uiAddress: "http://127.0.0.1:8233"}
+
+	for i, l := range lessons {
+		t.Run(l.title, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			if err := runLesson(ctx, env, l, fmt.Sprintf("learn-%d", i+1), nil, false); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"go.temporal.io/server/common/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// logFormats are the values accepted by --log-format.
+var logFormats = []string{"json", "pretty", "compact", "none"}
+
+// compactFields are the only log fields kept by the compact log format.
+var compactFields = map[string]bool{
+	"error":        true,
+	"address":      true,
+	"wf-namespace": true,
+	"wf-id":        true,
+	"wf-run-id":    true,
+	"wf-type":      true,
+}
+
+// newLogger builds the server logger for a --log-format value. Components
+// named in levels, such as history or matching, log at their own level.
+func newLogger(format string, levels map[string]zapcore.Level) (log.Logger, error) {
+	var (
+		l            *zap.Logger
+		defaultLevel = zap.DebugLevel
+		err          error
+	)
+	switch format {
+	case "none":
+		return log.NewNoopLogger(), nil
+	case "pretty":
+		lcfg := zap.NewDevelopmentConfig()
+		lcfg.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		l, err = lcfg.Build(
+			zap.WithCaller(false),
+			zap.AddStacktrace(zapcore.ErrorLevel),
+		)
+	case "compact":
+		defaultLevel = zap.InfoLevel
+		lcfg := zap.NewDevelopmentConfig()
+		lcfg.DisableCaller = true
+		lcfg.DisableStacktrace = true
+		lcfg.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		lcfg.EncoderConfig.EncodeTime = zapcore.TimeEncoderOfLayout("15:04:05")
+		lcfg.EncoderConfig.NameKey = ""
+		l, err = lcfg.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return compactCore{core}
+		}))
+	default:
+		l = log.BuildZapLogger(log.Config{Stdout: true, Level: "debug"})
+	}
+	if err != nil {
+		return nil, err
+	}
+	l = l.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return componentLevelCore{Core: core, levels: levels, level: defaultLevel}
+	}))
+	return log.NewZapLogger(l), nil
+}
+
+// parseLogLevels parses --log-level-override values of the form COMPONENT=LEVEL.
+func parseLogLevels(overrides []string) (map[string]zapcore.Level, error) {
+	levels := make(map[string]zapcore.Level, len(overrides))
+	for _, override := range overrides {
+		vals := strings.SplitN(override, "=", 2)
+		if len(vals) != 2 {
+			return nil, fmt.Errorf("log level overrides must be in COMPONENT=LEVEL format, got %q", override)
+		}
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(vals[1])); err != nil {
+			return nil, err
+		}
+		levels[vals[0]] = level
+	}
+	return levels, nil
+}
+
+// componentLevelCore filters log entries by the level of the service or
+// component a logger was created for, as given by its "service" and
+// "component" fields.
+type componentLevelCore struct {
+	zapcore.Core
+	levels map[string]zapcore.Level
+	level  zapcore.Level
+}
+
+func (c componentLevelCore) Enabled(level zapcore.Level) bool {
+	return level >= c.level
+}
+
+func (c componentLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	level := c.level
+	for _, f := range fields {
+		if f.Key != "service" && f.Key != "component" {
+			continue
+		}
+		if l, ok := c.levels[f.String]; ok {
+			level = l
+		}
+	}
+	return componentLevelCore{Core: c.Core.With(fields), levels: c.levels, level: level}
+}
+
+func (c componentLevelCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+	return ce
+}
+
+// compactCore drops all log fields not in compactFields.
+type compactCore struct {
+	zapcore.Core
+}
+
+func filterCompactFields(fields []zapcore.Field) []zapcore.Field {
+	kept := make([]zapcore.Field, 0, len(fields))
+	for _, f := range fields {
+		if compactFields[f.Key] {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+func (c compactCore) With(fields []zapcore.Field) zapcore.Core {
+	return compactCore{c.Core.With(filterCompactFields(fields))}
+}
+
+func (c compactCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+	return ce
+}
+
+func (c compactCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, filterCompactFields(fields))
+}
@@ -0,0 +1,72 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParseLogLevels(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides []string
+		want      map[string]zapcore.Level
+		wantErr   bool
+	}{
+		{
+			name: "none",
+			want: map[string]zapcore.Level{},
+		},
+		{
+			name:      "components",
+			overrides: []string{"matching=debug", "history=ERROR"},
+			want:      map[string]zapcore.Level{"matching": zapcore.DebugLevel, "history": zapcore.ErrorLevel},
+		},
+		{name: "missing level", overrides: []string{"matching"}, wantErr: true},
+		{name: "unknown level", overrides: []string{"matching=verbose"}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseLogLevels(tc.overrides)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseLogLevels() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("parseLogLevels() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestComponentLevelCore(t *testing.T) {
+	core := componentLevelCore{
+		Core:   zapcore.NewNopCore(),
+		levels: map[string]zapcore.Level{"matching": zapcore.DebugLevel, "history": zapcore.ErrorLevel},
+		level:  zapcore.InfoLevel,
+	}
+	tests := []struct {
+		name   string
+		fields []zapcore.Field
+		level  zapcore.Level
+		want   bool
+	}{
+		{name: "default level", level: zapcore.InfoLevel, want: true},
+		{name: "below default level", level: zapcore.DebugLevel},
+		{name: "lowered service", fields: []zapcore.Field{zap.String("service", "matching")}, level: zapcore.DebugLevel, want: true},
+		{name: "raised component", fields: []zapcore.Field{zap.String("component", "history")}, level: zapcore.WarnLevel},
+		{name: "other field", fields: []zapcore.Field{zap.String("namespace", "matching")}, level: zapcore.DebugLevel},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := core.With(tc.fields).Enabled(tc.level); got != tc.want {
+				t.Errorf("Enabled(%v) = %v, want %v", tc.level, got, tc.want)
+			}
+		})
+	}
+}
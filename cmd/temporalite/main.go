@@ -5,21 +5,22 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	goLog "log"
+	"math"
 	"net"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
-	uiserver "github.com/temporalio/ui-server/server"
 	uiconfig "github.com/temporalio/ui-server/server/config"
-	uiserveroptions "github.com/temporalio/ui-server/server/server_options"
 	"github.com/urfave/cli/v2"
 	"go.temporal.io/server/common/headers"
-	"go.temporal.io/server/common/log"
 	"go.temporal.io/server/temporal"
-	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
 
 	// Load sqlite storage driver
 	_ "go.temporal.io/server/common/persistence/sql/sqlplugin/sqlite"
@@ -33,14 +34,80 @@ var (
 )
 
 const (
-	ephemeralFlag = "ephemeral"
-	dbPathFlag    = "filename"
-	portFlag      = "port"
-	uiPortFlag    = "ui-port"
-	ipFlag        = "ip"
-	logFormatFlag = "log-format"
-	namespaceFlag = "namespace"
-	pragmaFlag    = "sqlite-pragma"
+	ephemeralFlag         = "ephemeral"
+	dbPathFlag            = "filename"
+	portFlag              = "port"
+	portRetryFlag         = "port-retries"
+	dynamicPortsFlag      = "dynamic-ports"
+	portsFileFlag         = "ports-file"
+	exitWithParentFlag    = "exit-with-parent"
+	uiPortFlag            = "ui-port"
+	ipFlag                = "ip"
+	broadcastFlag         = "broadcast-address"
+	logFormatFlag         = "log-format"
+	logLevelFlag          = "log-level-override"
+	cliLogFormatFlag      = "cli-log-format"
+	idSeedFlag            = "id-seed"
+	namespaceFlag         = "namespace"
+	retentionFlag         = "namespace-retention"
+	archivalURIFlag       = "archival-uri"
+	visArchivalURIFlag    = "visibility-archival-uri"
+	archivalRegionFlag    = "archival-s3-region"
+	archivalEndpointFlag  = "archival-s3-endpoint"
+	reconcileFlag         = "reconcile-namespaces"
+	pragmaFlag            = "sqlite-pragma"
+	searchAttributeFlag   = "search-attribute"
+	partitionFlag         = "task-queue-partitions"
+	deterministicFlag     = "deterministic-task-ordering"
+	timeScaleFlag         = "time-scale"
+	maxHeartbeatFlag      = "max-heartbeat-timeout"
+	taskHeartbeatFlag     = "workflow-task-heartbeat-timeout"
+	batcherFlag           = "batcher-max-concurrent"
+	replicateFlag         = "replicate-to"
+	seedFlag              = "seed-from"
+	readOnlyFlag          = "read-only"
+	checkpointFlag        = "checkpoint-interval"
+	dbSizeWarningFlag     = "db-size-warning"
+	durabilityFlag        = "durability"
+	profileFlag           = "resource-profile"
+	concurrencyFlag       = "concurrency-profile"
+	persistenceQPSFlag    = "persistence-qps"
+	identityRPSFlag       = "identity-rps"
+	identityOverrideFlag  = "identity-rps-override"
+	maxMemoryFlag         = "max-memory"
+	shrinkCachesFlag      = "shrink-caches-above"
+	maxOpenFlag           = "max-open-workflows"
+	maxBlobSizeFlag       = "max-blob-size"
+	maxHistoryEventsFlag  = "max-history-events"
+	maxHistorySizeFlag    = "max-history-size"
+	historyGuardFlag      = "history-guard-events"
+	historyGuardTermFlag  = "history-guard-terminate"
+	stuckAttemptsFlag     = "stuck-task-attempts"
+	stuckTimeoutFlag      = "stuck-task-timeout"
+	retentionReportFlag   = "retention-report"
+	memoryReportFlag      = "memory-report-interval"
+	metricsExporterFlag   = "metrics-exporter"
+	metricsEndpointFlag   = "metrics-endpoint"
+	metricsIntervalFlag   = "metrics-interval"
+	metricsTagFlag        = "metrics-tag"
+	metricsPrefixFlag     = "metrics-prefix"
+	auditLogFlag          = "audit-log"
+	apiKeyFileFlag        = "api-key-file"
+	tlsCertFlag           = "tls-cert"
+	tlsKeyFlag            = "tls-key"
+	tlsClientCAFlag       = "tls-client-ca"
+	tlsClaimsFlag         = "tls-claims-file"
+	localFrontendFlag     = "local-frontend-address"
+	mirrorFlag            = "mirror-to"
+	mirrorMethodFlag      = "mirror-method"
+	mirrorForwardFlag     = "mirror-forward"
+	mirrorTLSFlag         = "mirror-tls"
+	headlessFlag          = "headless"
+	uiAssetPathFlag       = "ui-asset-path"
+	uiPublicPathFlag      = "ui-public-path"
+	uiTrustedProxyFlag    = "ui-trusted-proxy"
+	corsOriginsFlag       = "cors-origins"
+	replicateIntervalFlag = "replicate-interval"
 )
 
 func init() {
@@ -48,6 +115,8 @@ func init() {
 }
 
 func main() {
+	// Upstream serves http.DefaultServeMux on the pprof port.
+	http.Handle("/debug/", temporalite.DebugHandler())
 	if err := buildCLI().Run(os.Args); err != nil {
 		goLog.Fatal(err)
 	}
@@ -82,29 +151,122 @@ func buildCLI() *cli.App {
 					EnvVars: nil,
 					Value:   nil,
 				},
+				&cli.DurationFlag{
+					Name:  retentionFlag,
+					Usage: "retention period of closed workflows in pre-created namespaces (defaults to 24h for new namespaces)",
+				},
+				&cli.BoolFlag{
+					Name:  reconcileFlag,
+					Usage: "update pre-created namespaces that already exist with a different retention period",
+				},
+				&cli.StringFlag{
+					Name:  archivalURIFlag,
+					Usage: "archive closed workflow histories past retention to `URI` (file:///path, s3://bucket/path, or gs://bucket/path)",
+				},
+				&cli.StringFlag{
+					Name:  visArchivalURIFlag,
+					Usage: "archive the visibility records of closed workflows to `URI` for ListArchivedWorkflowExecutions",
+				},
+				&cli.StringFlag{
+					Name:  archivalRegionFlag,
+					Usage: "AWS `REGION` of the archival buckets",
+					Value: liteconfig.DefaultArchivalS3Region,
+				},
+				&cli.StringFlag{
+					Name:  archivalEndpointFlag,
+					Usage: "`URL` of an S3-compatible store such as MinIO for archival, eg. http://localhost:9000",
+				},
+				&cli.StringSliceFlag{
+					Name:  searchAttributeFlag,
+					Usage: "register a custom search attribute as NAME=TYPE, where TYPE is Text, Keyword, Int, Double, Bool or Datetime",
+				},
 				&cli.IntFlag{
 					Name:    portFlag,
 					Aliases: []string{"p"},
 					Usage:   "port for the temporal-frontend GRPC service",
 					Value:   liteconfig.DefaultFrontendPort,
 				},
+				&cli.IntFlag{
+					Name:  portRetryFlag,
+					Usage: "number of successive ports to try when the frontend port is already in use",
+				},
+				&cli.BoolFlag{
+					Name:  dynamicPortsFlag,
+					Usage: "listen on system-chosen ports instead of --port and --ui-port",
+				},
+				&cli.StringFlag{
+					Name:  portsFileFlag,
+					Usage: "once started, write the frontend, UI, and metrics addresses as JSON to `FILE`",
+				},
+				&cli.BoolFlag{
+					Name:  exitWithParentFlag,
+					Usage: "stop the server when the process that started it exits",
+				},
+				&cli.BoolFlag{
+					Name:  daemonFlag,
+					Usage: "run the server in the background; stop it with temporalite stop",
+				},
+				&cli.StringFlag{
+					Name:        pidFileFlag,
+					Usage:       "once started, write the server's process ID to `FILE`",
+					DefaultText: fmt.Sprintf("%s with --%s", defaultPIDFile, daemonFlag),
+				},
+				&cli.StringFlag{
+					Name:  logFileFlag,
+					Usage: "with --daemon, append server output to `FILE`",
+					Value: defaultLogFile,
+				},
 				&cli.IntFlag{
 					Name:        uiPortFlag,
 					Usage:       "port for the temporal web UI",
 					DefaultText: fmt.Sprintf("--port + 1000, eg. %d", liteconfig.DefaultFrontendPort+1000),
 				},
+				&cli.BoolFlag{
+					Name:  headlessFlag,
+					Usage: "disable the web UI",
+				},
+				&cli.StringFlag{
+					Name:  uiAssetPathFlag,
+					Usage: "serve the web UI from a custom build in `DIR` instead of the embedded one",
+				},
+				&cli.StringFlag{
+					Name:  uiPublicPathFlag,
+					Usage: "serve the web UI under `PATH`, eg. /temporal, for use behind a reverse proxy",
+				},
+				&cli.StringSliceFlag{
+					Name:  uiTrustedProxyFlag,
+					Usage: "IP address or CIDR range of a reverse proxy whose X-Forwarded-* headers the web UI trusts; may be repeated",
+				},
+				&cli.StringSliceFlag{
+					Name:        corsOriginsFlag,
+					Usage:       "origins allowed to make cross-origin requests to the web UI's HTTP API; may be repeated",
+					DefaultText: "any origin",
+				},
 				&cli.StringFlag{
 					Name:    ipFlag,
-					Usage:   `IPv4 address to bind the frontend service to instead of localhost`,
+					Usage:   `IPv4 or IPv6 address to bind the frontend service to instead of localhost`,
 					EnvVars: nil,
 					Value:   "127.0.0.1",
 				},
+				&cli.StringFlag{
+					Name:  broadcastFlag,
+					Usage: "address advertised to other Temporal services for membership when the bind IP isn't routable",
+				},
 				&cli.StringFlag{
 					Name:    logFormatFlag,
-					Usage:   `customize the log formatting (allowed: ["json" "pretty"])`,
+					Usage:   fmt.Sprintf("customize the log formatting (allowed: %q)", logFormats),
 					EnvVars: nil,
 					Value:   "json",
 				},
+				&cli.StringSliceFlag{
+					Name:  logLevelFlag,
+					Usage: "log level for a service or component, eg. history=debug,matching=warn",
+				},
+				&cli.StringFlag{
+					Name:        cliLogFormatFlag,
+					Usage:       fmt.Sprintf("log formatting of temporalite's own startup and status messages (allowed: %q)", logFormats),
+					DefaultText: "same as --" + logFormatFlag,
+				},
 				&cli.StringSliceFlag{
 					Name:    pragmaFlag,
 					Aliases: []string{"sp"},
@@ -112,24 +274,287 @@ func buildCLI() *cli.App {
 					EnvVars: nil,
 					Value:   nil,
 				},
+				&cli.IntFlag{
+					Name:        partitionFlag,
+					Usage:       "number of read and write partitions for each task queue",
+					DefaultText: "1 with --ephemeral, otherwise 4",
+				},
+				&cli.BoolFlag{
+					Name:  deterministicFlag,
+					Usage: "process server tasks one at a time in creation order, for reproducible event orderings in tests",
+				},
+				&cli.Float64Flag{
+					Name:        timeScaleFlag,
+					Usage:       "make workflow timers fire and cron workflows run `FACTOR` times sooner, eg. 3600 to turn hours into seconds",
+					DefaultText: "1",
+				},
+				&cli.DurationFlag{
+					Name:  maxHeartbeatFlag,
+					Usage: "lower the heartbeat timeout of activities that heartbeat to at most this duration",
+				},
+				&cli.DurationFlag{
+					Name:        taskHeartbeatFlag,
+					Usage:       "how long heartbeats may extend a workflow task running local activities",
+					DefaultText: "30m",
+				},
+				&cli.IntFlag{
+					Name:        batcherFlag,
+					Usage:       "maximum number of batch operations processed concurrently",
+					DefaultText: "1000",
+				},
+				&cli.StringFlag{
+					Name:        profileFlag,
+					Usage:       "size caches and worker pools for the expected workload: small, medium, or large",
+					DefaultText: liteconfig.DefaultResourceProfile,
+				},
+				&cli.StringFlag{
+					Name:        concurrencyFlag,
+					Usage:       "raise dispatch rate limits and concurrency for load testing: default, high, or max",
+					DefaultText: "default",
+				},
+				&cli.IntFlag{
+					Name:        persistenceQPSFlag,
+					Usage:       "maximum database queries per second for each service",
+					DefaultText: "2000 for frontend, 9000 for history, 3000 for matching, 500 for worker",
+				},
+				&cli.Float64Flag{
+					Name:        identityRPSFlag,
+					Usage:       "maximum frontend requests per second from each client or worker identity in a namespace",
+					DefaultText: "unlimited",
+				},
+				&cli.StringSliceFlag{
+					Name:  identityOverrideFlag,
+					Usage: "requests per second allowed for a specific identity, eg. 1234@ci-runner=50; 0 exempts it",
+				},
+				&cli.StringFlag{
+					Name:  metricsExporterFlag,
+					Usage: fmt.Sprintf("metrics exporter, one of %v", liteconfig.MetricsExporters),
+					Value: "prometheus",
+				},
+				&cli.StringFlag{
+					Name:  metricsEndpointFlag,
+					Usage: "`HOST:PORT` of the statsd or M3 collector",
+				},
+				&cli.DurationFlag{
+					Name:        metricsIntervalFlag,
+					Usage:       "how often to flush metrics to statsd",
+					DefaultText: "1s",
+				},
+				&cli.StringSliceFlag{
+					Name:  metricsTagFlag,
+					Usage: "tag added to every metric, in KEY=VALUE format; may be repeated",
+				},
+				&cli.StringFlag{
+					Name:  metricsPrefixFlag,
+					Usage: "prefix for every metric name (service name for M3)",
+				},
+				&cli.StringFlag{
+					Name:  apiKeyFileFlag,
+					Usage: "require clients to send one of the API keys in `FILE` (one KEY or NAME:KEY per line) as their authorization header; disables the web UI",
+				},
+				&cli.StringFlag{
+					Name:  tlsCertFlag,
+					Usage: "serve the frontend over mutual TLS with the certificate in `FILE`; disables the web UI",
+				},
+				&cli.StringFlag{
+					Name:  tlsKeyFlag,
+					Usage: "private key `FILE` for --tls-cert",
+				},
+				&cli.StringFlag{
+					Name:  tlsClientCAFlag,
+					Usage: "CA certificate `FILE` that client certificates must be signed by",
+				},
+				&cli.StringFlag{
+					Name:  tlsClaimsFlag,
+					Usage: "grant namespace roles to client certificates by subject alternative name, as listed in `FILE`",
+				},
+				&cli.StringFlag{
+					Name:  localFrontendFlag,
+					Usage: "also serve the frontend without TLS on the loopback address `HOST:PORT` for local tools, eg. 127.0.0.1:7234",
+				},
+				&cli.StringFlag{
+					Name:  mirrorFlag,
+					Usage: "experimental: send a copy of calls to --mirror-method methods to the Temporal frontend at `HOST:PORT` and log differing outcomes",
+				},
+				&cli.StringSliceFlag{
+					Name:  mirrorMethodFlag,
+					Usage: "WorkflowService `METHOD` to mirror, eg. StartWorkflowExecution; may be repeated",
+				},
+				&cli.BoolFlag{
+					Name:  mirrorForwardFlag,
+					Usage: "serve --mirror-method methods from the --mirror-to cluster instead of locally",
+				},
+				&cli.BoolFlag{
+					Name:  mirrorTLSFlag,
+					Usage: "connect to the --mirror-to cluster over TLS",
+				},
+				&cli.StringFlag{
+					Name:  auditLogFlag,
+					Usage: "append namespace, search attribute, and batch operation changes with caller identity to `FILE`",
+				},
+				&cli.StringFlag{
+					Name:  maxMemoryFlag,
+					Usage: "refuse new workflows while the process uses more than `SIZE` of memory, eg. 512MiB or 2GiB",
+				},
+				&cli.StringFlag{
+					Name:  shrinkCachesFlag,
+					Usage: "halve history cache sizes each time the process uses more than `SIZE` of memory, eg. 384MiB",
+				},
+				&cli.IntFlag{
+					Name:  maxOpenFlag,
+					Usage: "refuse to start workflows in a namespace that already has `COUNT` open workflows",
+				},
+				&cli.StringFlag{
+					Name:        maxBlobSizeFlag,
+					Aliases:     []string{"max-payload-size"},
+					Usage:       "Temporal's limit on the size of a single payload, eg. 256KiB or 3MiB",
+					DefaultText: "2MiB",
+				},
+				&cli.IntFlag{
+					Name:        maxHistoryEventsFlag,
+					Usage:       "terminate workflows whose history grows beyond `COUNT` events",
+					DefaultText: "51200",
+				},
+				&cli.StringFlag{
+					Name:        maxHistorySizeFlag,
+					Usage:       "terminate workflows whose history grows beyond `SIZE`, eg. 10MiB",
+					DefaultText: "50MiB",
+				},
+				&cli.IntFlag{
+					Name:  historyGuardFlag,
+					Usage: "log a warning when a workflow's history grows beyond `COUNT` events",
+				},
+				&cli.BoolFlag{
+					Name:  historyGuardTermFlag,
+					Usage: "terminate workflows caught by --" + historyGuardFlag + " instead of only logging a warning",
+				},
+				&cli.IntFlag{
+					Name:  stuckAttemptsFlag,
+					Usage: "log a warning when a workflow task has been attempted more than `N` times",
+				},
+				&cli.DurationFlag{
+					Name:  stuckTimeoutFlag,
+					Usage: "log a warning when a workflow task has not completed within this duration",
+				},
+				&cli.BoolFlag{
+					Name:  retentionReportFlag,
+					Usage: "log closed workflows deleted after their retention period, and report them to the retention command",
+				},
+				&cli.DurationFlag{
+					Name:  memoryReportFlag,
+					Usage: "how often to log memory usage and cache sizes",
+				},
+				&cli.StringFlag{
+					Name:        durabilityFlag,
+					Usage:       "write durability: full, normal (may lose recent writes on power loss), or off (may corrupt the database on power loss)",
+					DefaultText: "full, or off with --ephemeral",
+				},
+				&cli.DurationFlag{
+					Name:        checkpointFlag,
+					Usage:       "how often to checkpoint the SQLite write-ahead log into the database file",
+					DefaultText: "when the log reaches 1000 pages",
+				},
+				&cli.StringSliceFlag{
+					Name:  dbSizeWarningFlag,
+					Usage: "log a warning listing the largest namespaces when the database grows beyond `SIZE`, eg. 1GiB; may be repeated",
+				},
+				&cli.BoolFlag{
+					Name:  readOnlyFlag,
+					Usage: "serve histories and list queries from an existing database while rejecting all modifications",
+				},
+				&cli.StringFlag{
+					Name:  replicateFlag,
+					Usage: "periodically upload a snapshot of the database file to `URL` (s3://bucket/path or gs://bucket/path)",
+				},
+				&cli.DurationFlag{
+					Name:  replicateIntervalFlag,
+					Usage: "how often to upload a snapshot when --replicate-to is set",
+					Value: time.Minute,
+				},
+				&cli.StringFlag{
+					Name:  seedFlag,
+					Usage: "start from a copy of the database snapshot at `URL` (s3://, gs://, http(s):// or a path) and discard changes on shutdown",
+				},
+				newDumpDirFlag(),
+				&cli.StringFlag{
+					Name:  recordDirFlag,
+					Usage: "directory in which to capture all frontend requests and responses for replay-requests",
+				},
+				&cli.BoolFlag{
+					Name:  apiUsageFlag,
+					Usage: "print the number of calls to each frontend API method on shutdown",
+				},
+				&cli.Int64Flag{
+					Name:  idSeedFlag,
+					Usage: "debug: derive run IDs and other generated IDs from `SEED` so demos and golden-file tests are reproducible",
+				},
+			},
+			OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+				return cli.Exit(fmt.Sprintf("ERROR: %v", err), exitConfigError)
 			},
 			Before: func(c *cli.Context) error {
 				if c.Args().Len() > 0 {
-					return cli.Exit("ERROR: start command doesn't support arguments.", 1)
+					return cli.Exit("ERROR: start command doesn't support arguments.", exitConfigError)
 				}
 				if c.IsSet(ephemeralFlag) && c.IsSet(dbPathFlag) {
-					return cli.Exit(fmt.Sprintf("ERROR: only one of %q or %q flags may be passed at a time", ephemeralFlag, dbPathFlag), 1)
+					return cli.Exit(fmt.Sprintf("ERROR: only one of %q or %q flags may be passed at a time", ephemeralFlag, dbPathFlag), exitConfigError)
+				}
+				if c.IsSet(ephemeralFlag) && c.IsSet(readOnlyFlag) {
+					return cli.Exit(fmt.Sprintf("ERROR: only one of %q or %q flags may be passed at a time", ephemeralFlag, readOnlyFlag), exitConfigError)
+				}
+				if c.IsSet(ephemeralFlag) && c.IsSet(replicateFlag) {
+					return cli.Exit(fmt.Sprintf("ERROR: only one of %q or %q flags may be passed at a time", ephemeralFlag, replicateFlag), exitConfigError)
+				}
+				if c.IsSet(seedFlag) && c.IsSet(dbPathFlag) {
+					return cli.Exit(fmt.Sprintf("ERROR: only one of %q or %q flags may be passed at a time", seedFlag, dbPathFlag), exitConfigError)
+				}
+				if c.IsSet(deterministicFlag) && c.IsSet(partitionFlag) {
+					return cli.Exit(fmt.Sprintf("ERROR: only one of %q or %q flags may be passed at a time", deterministicFlag, partitionFlag), exitConfigError)
 				}
 
-				switch c.String(logFormatFlag) {
-				case "json", "pretty":
-				default:
-					return cli.Exit(fmt.Sprintf("bad value %q passed for flag %q", c.String(logFormatFlag), logFormatFlag), 1)
+				if !containsString(logFormats, c.String(logFormatFlag)) {
+					return cli.Exit(fmt.Sprintf("bad value %q passed for flag %q", c.String(logFormatFlag), logFormatFlag), exitConfigError)
+				}
+				if c.IsSet(cliLogFormatFlag) && !containsString(logFormats, c.String(cliLogFormatFlag)) {
+					return cli.Exit(fmt.Sprintf("bad value %q passed for flag %q", c.String(cliLogFormatFlag), cliLogFormatFlag), exitConfigError)
 				}
 
 				// Check that ip address is valid
 				if c.IsSet(ipFlag) && net.ParseIP(c.String(ipFlag)) == nil {
-					return cli.Exit(fmt.Sprintf("bad value %q passed for flag %q", c.String(ipFlag), ipFlag), 1)
+					return cli.Exit(fmt.Sprintf("bad value %q passed for flag %q", c.String(ipFlag), ipFlag), exitConfigError)
+				}
+				if c.IsSet(broadcastFlag) && net.ParseIP(c.String(broadcastFlag)) == nil {
+					return cli.Exit(fmt.Sprintf("bad value %q passed for flag %q", c.String(broadcastFlag), broadcastFlag), exitConfigError)
+				}
+				if c.IsSet(tlsCertFlag) && (!c.IsSet(tlsKeyFlag) || !c.IsSet(tlsClientCAFlag)) {
+					return cli.Exit(fmt.Sprintf("ERROR: %q requires %q and %q", tlsCertFlag, tlsKeyFlag, tlsClientCAFlag), exitConfigError)
+				}
+				if c.Bool(daemonFlag) && c.Bool(exitWithParentFlag) {
+					return cli.Exit(fmt.Sprintf("ERROR: only one of %q or %q flags may be passed at a time", daemonFlag, exitWithParentFlag), exitConfigError)
+				}
+				if c.IsSet(headlessFlag) && c.IsSet(uiAssetPathFlag) {
+					return cli.Exit(fmt.Sprintf("ERROR: only one of %q or %q flags may be passed at a time", headlessFlag, uiAssetPathFlag), exitConfigError)
+				}
+				for _, flag := range []string{tlsCertFlag, apiKeyFileFlag} {
+					if !c.IsSet(flag) {
+						continue
+					}
+					// The web UI is disabled with either, as it can present
+					// neither a client certificate nor an API key.
+					for _, uiFlag := range []string{uiAssetPathFlag, uiPublicPathFlag, uiTrustedProxyFlag} {
+						if c.IsSet(uiFlag) {
+							return cli.Exit(fmt.Sprintf("ERROR: %q cannot be passed with %q, which disables the web UI", uiFlag, flag), exitConfigError)
+						}
+					}
+				}
+				if c.IsSet(uiPublicPathFlag) && !strings.HasPrefix(c.String(uiPublicPathFlag), "/") {
+					return cli.Exit(fmt.Sprintf("bad value %q passed for flag %q: must start with /", c.String(uiPublicPathFlag), uiPublicPathFlag), exitConfigError)
+				}
+				if c.IsSet(tlsClaimsFlag) && !c.IsSet(tlsCertFlag) {
+					return cli.Exit(fmt.Sprintf("ERROR: %q requires %q", tlsClaimsFlag, tlsCertFlag), exitConfigError)
+				}
+				if c.IsSet(mirrorFlag) != c.IsSet(mirrorMethodFlag) {
+					return cli.Exit(fmt.Sprintf("ERROR: %q and %q must be passed together", mirrorFlag, mirrorMethodFlag), exitConfigError)
 				}
 
 				return nil
@@ -138,63 +563,379 @@ func buildCLI() *cli.App {
 				var (
 					ip         = c.String(ipFlag)
 					serverPort = c.Int(portFlag)
-					uiPort     = serverPort + 1000
+					pidFile    = c.String(pidFileFlag)
 				)
+				if c.Bool(daemonFlag) {
+					if pidFile == "" {
+						pidFile = defaultPIDFile
+					}
+					return startDaemon(pidFile, c.String(logFileFlag))
+				}
+				if pidFile != "" {
+					if err := checkPIDFile(pidFile); err != nil {
+						return cli.Exit(fmt.Sprintf("ERROR: %v", err), exitConfigError)
+					}
+				}
+
+				// Under socket activation, systemd owns the frontend port.
+				listener, err := systemdListener()
+				if err != nil {
+					return cli.Exit(fmt.Sprintf("ERROR: %v", err), exitConfigError)
+				}
+				if listener != nil {
+					if addr, ok := listener.Addr().(*net.TCPAddr); ok {
+						serverPort = addr.Port
+					}
+				} else if c.Bool(dynamicPortsFlag) {
+					port, err := freePort(ip)
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("ERROR: %v", err), exitConfigError)
+					}
+					serverPort = port
+				} else if retries := c.Int(portRetryFlag); retries > 0 {
+					port, err := liteconfig.FindAvailablePort(ip, serverPort, retries)
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("ERROR: %v", err), exitConfigError)
+					}
+					if port != serverPort {
+						goLog.Printf("port %d is in use, using port %d instead", serverPort, port)
+					}
+					serverPort = port
+				}
 
-				if c.IsSet(uiPortFlag) {
+				uiPort := serverPort + 1000
+				if c.Bool(dynamicPortsFlag) {
+					port, err := freePort(ip)
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("ERROR: %v", err), exitConfigError)
+					}
+					uiPort = port
+				} else if c.IsSet(uiPortFlag) {
 					uiPort = c.Int(uiPortFlag)
 				}
 				uiOpts := uiconfig.Config{
-					TemporalGRPCAddress: fmt.Sprintf(":%d", c.Int(portFlag)),
+					TemporalGRPCAddress: fmt.Sprintf(":%d", serverPort),
 					Host:                ip,
 					Port:                uiPort,
 					EnableUI:            true,
+					CORS: uiconfig.CORS{
+						AllowOrigins: c.StringSlice(corsOriginsFlag),
+					},
 				}
 
 				pragmas, err := getPragmaMap(c.StringSlice(pragmaFlag))
 				if err != nil {
-					return err
+					return cli.Exit(err.Error(), exitConfigError)
 				}
 
 				opts := []temporalite.ServerOption{
-					temporalite.WithFrontendPort(serverPort),
-					temporalite.WithFrontendIP(ip),
+					temporalite.WithBroadcastAddress(c.String(broadcastFlag)),
 					temporalite.WithDatabaseFilePath(c.String(dbPathFlag)),
 					temporalite.WithNamespaces(c.StringSlice(namespaceFlag)...),
 					temporalite.WithSQLitePragmas(pragmas),
 					temporalite.WithUpstreamOptions(
 						temporal.InterruptOn(temporal.InterruptCh()),
 					),
-					temporalite.WithUI(uiserver.NewServer(uiserveroptions.WithConfig(&uiOpts))),
+				}
+				if c.Bool(dynamicPortsFlag) {
+					opts = append(opts, temporalite.WithDynamicPorts())
+				}
+				if listener != nil {
+					opts = append(opts, temporalite.WithFrontendListener(listener))
+				} else {
+					opts = append(opts, temporalite.WithFrontendPort(serverPort), temporalite.WithFrontendIP(ip))
+				}
+				if c.Bool(exitWithParentFlag) {
+					opts = append(opts, temporalite.WithParentWatchdog(os.Getppid()))
+				}
+				if c.IsSet(retentionFlag) {
+					opts = append(opts, temporalite.WithNamespaceRetention(c.Duration(retentionFlag)))
+				}
+				if c.IsSet(archivalURIFlag) {
+					opts = append(opts, temporalite.WithArchival(c.String(archivalURIFlag)))
+				}
+				if c.IsSet(visArchivalURIFlag) {
+					opts = append(opts, temporalite.WithVisibilityArchival(c.String(visArchivalURIFlag)))
+				}
+				if c.IsSet(archivalURIFlag) || c.IsSet(visArchivalURIFlag) {
+					opts = append(opts, temporalite.WithS3Archival(c.String(archivalRegionFlag), c.String(archivalEndpointFlag)))
+				}
+				if c.Bool(reconcileFlag) {
+					opts = append(opts, temporalite.WithNamespaceReconciliation())
+				}
+				if c.IsSet(searchAttributeFlag) {
+					attrs, err := parseSearchAttributes(c.StringSlice(searchAttributeFlag))
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("ERROR: %v", err), exitConfigError)
+					}
+					opts = append(opts, temporalite.WithSearchAttributes(attrs))
+				}
+				if c.IsSet(mirrorFlag) {
+					opts = append(opts, temporalite.WithTrafficMirror(c.String(mirrorFlag), c.Bool(mirrorForwardFlag), c.Bool(mirrorTLSFlag), c.StringSlice(mirrorMethodFlag)...))
+				}
+				if c.IsSet(localFrontendFlag) {
+					opts = append(opts, temporalite.WithLocalFrontend(c.String(localFrontendFlag)))
+				}
+				if c.IsSet(tlsCertFlag) {
+					opts = append(opts, temporalite.WithFrontendTLS(c.String(tlsCertFlag), c.String(tlsKeyFlag), c.String(tlsClientCAFlag)))
+				} else if c.IsSet(apiKeyFileFlag) {
+					// The web UI connects to the frontend without credentials.
+					if !c.Bool(headlessFlag) {
+						goLog.Printf("not serving the web UI, as it cannot present an API key")
+					}
+				} else if !c.Bool(headlessFlag) || c.IsSet(uiAssetPathFlag) || c.IsSet(uiPublicPathFlag) || c.IsSet(uiTrustedProxyFlag) {
+					// Upstream's ui-server exits the process when it is stopped, so it
+					// is always served through uiFrontend, which never stops it.
+					trustedProxies, err := parseTrustedProxies(c.StringSlice(uiTrustedProxyFlag))
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("bad value passed for flag %q: %v", uiTrustedProxyFlag, err), exitConfigError)
+					}
+					ui, err := newUIFrontend(uiOpts, uiFrontendOptions{
+						AssetPath:      c.String(uiAssetPathFlag),
+						PublicPath:     c.String(uiPublicPathFlag),
+						TrustedProxies: trustedProxies,
+					})
+					if errors.Is(err, syscall.EADDRINUSE) {
+						return cli.Exit(fmt.Sprintf("ERROR: unable to serve the web UI: %v", err), exitPortInUse)
+					} else if err != nil {
+						return cli.Exit(fmt.Sprintf("ERROR: %v", err), exitConfigError)
+					}
+					opts = append(opts, temporalite.WithUI(ui))
+				}
+				if c.IsSet(tlsClaimsFlag) {
+					opts = append(opts, temporalite.WithCertificateClaims(c.String(tlsClaimsFlag)))
 				}
 				if c.Bool(ephemeralFlag) {
 					opts = append(opts, temporalite.WithPersistenceDisabled())
 				}
-				if c.String(logFormatFlag) == "pretty" {
-					lcfg := zap.NewDevelopmentConfig()
-					lcfg.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-					l, err := lcfg.Build(
-						zap.WithCaller(false),
-						zap.AddStacktrace(zapcore.ErrorLevel),
-					)
+				if c.IsSet(partitionFlag) {
+					opts = append(opts, temporalite.WithTaskQueuePartitions(c.Int(partitionFlag), c.Int(partitionFlag)))
+				}
+				if c.Bool(deterministicFlag) {
+					opts = append(opts, temporalite.WithDeterministicTaskOrdering())
+				}
+				if c.IsSet(timeScaleFlag) {
+					opts = append(opts, temporalite.WithTimeScale(c.Float64(timeScaleFlag)))
+				}
+				if c.IsSet(maxHeartbeatFlag) || c.IsSet(taskHeartbeatFlag) {
+					opts = append(opts, temporalite.WithHeartbeatTimeouts(c.Duration(maxHeartbeatFlag), c.Duration(taskHeartbeatFlag)))
+				}
+				if c.IsSet(batcherFlag) {
+					opts = append(opts, temporalite.WithBatcherMaxConcurrentOperations(c.Int(batcherFlag)))
+				}
+				if c.IsSet(profileFlag) {
+					opts = append(opts, temporalite.WithResourceProfile(c.String(profileFlag)))
+				}
+				if c.IsSet(concurrencyFlag) {
+					opts = append(opts, temporalite.WithConcurrencyProfile(c.String(concurrencyFlag)))
+				}
+				if c.IsSet(persistenceQPSFlag) {
+					opts = append(opts, temporalite.WithPersistenceQPS(c.Int(persistenceQPSFlag)))
+				}
+				if c.IsSet(identityRPSFlag) {
+					opts = append(opts, temporalite.WithIdentityRateLimit(c.Float64(identityRPSFlag)))
+				}
+				for _, override := range c.StringSlice(identityOverrideFlag) {
+					i := strings.LastIndex(override, "=")
+					if i < 0 {
+						return cli.Exit(fmt.Sprintf("ERROR: identity rate limit overrides must be in IDENTITY=RPS format, got %q", override), exitConfigError)
+					}
+					rps, err := strconv.ParseFloat(override[i+1:], 64)
+					if err != nil || rps < 0 {
+						return cli.Exit(fmt.Sprintf("bad value %q passed for flag %q: invalid rate %q", override, identityOverrideFlag, override[i+1:]), exitConfigError)
+					}
+					opts = append(opts, temporalite.WithIdentityRateLimitOverride(override[:i], rps))
+				}
+				if c.IsSet(metricsExporterFlag) {
+					opts = append(opts, temporalite.WithMetricsExporter(c.String(metricsExporterFlag), c.String(metricsEndpointFlag), c.Duration(metricsIntervalFlag)))
+				}
+				if c.IsSet(metricsTagFlag) {
+					tags := make(map[string]string)
+					for _, tag := range c.StringSlice(metricsTagFlag) {
+						vals := strings.SplitN(tag, "=", 2)
+						if len(vals) != 2 {
+							return cli.Exit(fmt.Sprintf("ERROR: metrics tags must be in KEY=VALUE format, got %q", tag), exitConfigError)
+						}
+						tags[vals[0]] = vals[1]
+					}
+					opts = append(opts, temporalite.WithMetricsTags(tags))
+				}
+				if c.IsSet(metricsPrefixFlag) {
+					opts = append(opts, temporalite.WithMetricsPrefix(c.String(metricsPrefixFlag)))
+				}
+				if c.IsSet(apiKeyFileFlag) {
+					opts = append(opts, temporalite.WithAPIKeyFile(c.String(apiKeyFileFlag)))
+				}
+				if c.IsSet(auditLogFlag) {
+					opts = append(opts, temporalite.WithAuditLog(c.String(auditLogFlag)))
+				}
+				if c.IsSet(maxMemoryFlag) {
+					limit, err := parseByteSize(c.String(maxMemoryFlag))
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("bad value %q passed for flag %q: %v", c.String(maxMemoryFlag), maxMemoryFlag, err), exitConfigError)
+					}
+					opts = append(opts, temporalite.WithMaxMemory(limit))
+				}
+				if c.IsSet(shrinkCachesFlag) {
+					threshold, err := parseByteSize(c.String(shrinkCachesFlag))
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("bad value %q passed for flag %q: %v", c.String(shrinkCachesFlag), shrinkCachesFlag, err), exitConfigError)
+					}
+					opts = append(opts, temporalite.WithCacheShrinking(threshold))
+				}
+				if c.IsSet(maxOpenFlag) {
+					if c.Int(maxOpenFlag) < 1 {
+						return cli.Exit(fmt.Sprintf("bad value %d passed for flag %q: must be at least 1", c.Int(maxOpenFlag), maxOpenFlag), exitConfigError)
+					}
+					opts = append(opts, temporalite.WithMaxOpenWorkflows(c.Int(maxOpenFlag)))
+				}
+				if c.IsSet(maxBlobSizeFlag) {
+					limit, err := parseByteSize(c.String(maxBlobSizeFlag))
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("bad value %q passed for flag %q: %v", c.String(maxBlobSizeFlag), maxBlobSizeFlag, err), exitConfigError)
+					}
+					opts = append(opts, temporalite.WithBlobSizeLimit(int(limit)))
+				}
+				if c.IsSet(maxHistoryEventsFlag) || c.IsSet(maxHistorySizeFlag) {
+					var maxBytes uint64
+					if c.IsSet(maxHistorySizeFlag) {
+						var err error
+						if maxBytes, err = parseByteSize(c.String(maxHistorySizeFlag)); err != nil {
+							return cli.Exit(fmt.Sprintf("bad value %q passed for flag %q: %v", c.String(maxHistorySizeFlag), maxHistorySizeFlag, err), exitConfigError)
+						}
+					}
+					opts = append(opts, temporalite.WithHistoryLimits(c.Int(maxHistoryEventsFlag), int(maxBytes)))
+				}
+				if c.IsSet(historyGuardTermFlag) && !c.IsSet(historyGuardFlag) {
+					return cli.Exit(fmt.Sprintf("ERROR: %q requires %q", historyGuardTermFlag, historyGuardFlag), exitConfigError)
+				}
+				if c.Bool(historyGuardTermFlag) && c.IsSet(maxHistoryEventsFlag) {
+					return cli.Exit(fmt.Sprintf("ERROR: only one of %q or %q flags may be passed at a time", historyGuardTermFlag, maxHistoryEventsFlag), exitConfigError)
+				}
+				if c.IsSet(historyGuardFlag) {
+					if c.Int(historyGuardFlag) < 1 {
+						return cli.Exit(fmt.Sprintf("bad value %d passed for flag %q: must be at least 1", c.Int(historyGuardFlag), historyGuardFlag), exitConfigError)
+					}
+					opts = append(opts, temporalite.WithHistoryGuard(c.Int(historyGuardFlag), c.Bool(historyGuardTermFlag)))
+				}
+				if c.IsSet(stuckAttemptsFlag) || c.IsSet(stuckTimeoutFlag) {
+					opts = append(opts, temporalite.WithStuckWorkflowDetection(c.Int(stuckAttemptsFlag), c.Duration(stuckTimeoutFlag)))
+				}
+				if c.Bool(retentionReportFlag) {
+					opts = append(opts, temporalite.WithRetentionReport())
+				}
+				if c.IsSet(memoryReportFlag) {
+					opts = append(opts, temporalite.WithMemoryReporting(c.Duration(memoryReportFlag)))
+				}
+				if c.IsSet(durabilityFlag) {
+					opts = append(opts, temporalite.WithDurability(c.String(durabilityFlag)))
+				}
+				if c.IsSet(checkpointFlag) {
+					opts = append(opts, temporalite.WithCheckpointInterval(c.Duration(checkpointFlag)))
+				}
+				for _, threshold := range c.StringSlice(dbSizeWarningFlag) {
+					size, err := parseByteSize(threshold)
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("bad value %q passed for flag %q: %v", threshold, dbSizeWarningFlag, err), exitConfigError)
+					}
+					opts = append(opts, temporalite.WithDatabaseSizeWarnings(int64(size)))
+				}
+				if c.Bool(readOnlyFlag) {
+					opts = append(opts, temporalite.WithReadOnly())
+				}
+				if c.IsSet(replicateFlag) {
+					opts = append(opts, temporalite.WithReplication(c.String(replicateFlag), c.Duration(replicateIntervalFlag)))
+				}
+				if c.IsSet(seedFlag) {
+					opts = append(opts, temporalite.WithSeedDatabase(c.String(seedFlag)))
+				}
+				if c.IsSet(recordDirFlag) {
+					opts = append(opts, temporalite.WithRequestRecording(c.String(recordDirFlag)))
+				}
+				if c.IsSet(idSeedFlag) {
+					opts = append(opts, temporalite.WithDeterministicIDs(c.Int64(idSeedFlag)))
+				}
+				levels, err := parseLogLevels(c.StringSlice(logLevelFlag))
+				if err != nil {
+					return cli.Exit(fmt.Sprintf("bad value passed for flag %q: %v", logLevelFlag, err), exitConfigError)
+				}
+				logger, err := newLogger(c.String(logFormatFlag), levels)
+				if err != nil {
+					return cli.Exit(fmt.Sprintf("ERROR: %v", err), exitConfigError)
+				}
+				if c.IsSet(cliLogFormatFlag) {
+					cliLogger, err := newLogger(c.String(cliLogFormatFlag), nil)
 					if err != nil {
-						return err
+						return cli.Exit(fmt.Sprintf("ERROR: %v", err), exitConfigError)
 					}
-					logger := log.NewZapLogger(l)
+					opts = append(opts, temporalite.WithLogger(cliLogger), temporalite.WithServerLogger(logger))
+				} else {
 					opts = append(opts, temporalite.WithLogger(logger))
 				}
 
-				s, err := temporalite.NewServer(opts...)
+				s, err := temporalite.NewServerWithContext(c.Context, opts...)
 				if err != nil {
-					return err
+					return serverExit(err.Error(), err, exitConfigError)
+				}
+				handleDiagnosticsSignal(s, c.String(dumpDirFlag))
+				if c.Bool(headlessFlag) {
+					uiPort = 0
+				}
+				go func() {
+					if err := awaitFrontend(c.Context, s); err != nil {
+						goLog.Printf("frontend not ready: %v", err)
+						return
+					}
+					if path := c.String(portsFileFlag); path != "" {
+						if err := writePortsFile(path, s, uiPort); err != nil {
+							goLog.Printf("unable to write ports file: %v", err)
+						}
+					}
+					if pidFile != "" {
+						if err := os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+							goLog.Printf("unable to write pid file: %v", err)
+						}
+					}
+					if err := sdNotify("READY=1"); err != nil {
+						goLog.Printf("unable to notify systemd: %v", err)
+					}
+				}()
+				if path := c.String(portsFileFlag); path != "" {
+					defer os.Remove(path)
+				}
+				if pidFile != "" {
+					defer os.Remove(pidFile)
 				}
 
-				if err := s.Start(); err != nil {
-					return cli.Exit(fmt.Sprintf("Unable to start server. Error: %v", err), 1)
+				if c.Bool(apiUsageFlag) {
+					defer printAPIUsage(os.Stderr, s)
 				}
-				return cli.Exit("All services are stopped.", 0)
+				return runServer(s)
 			},
 		},
+		stopCommand(),
+		statusCommand(),
+		debugCommand(),
+		inspectCommand(),
+		checkpointCommand(),
+		searchAttributesCommand(),
+		importNamespaceConfigCommand(),
+		replayRequestsCommand(),
+		benchCommand(),
+		demoCommand(),
+		learnCommand(),
+		initCommand(),
+		generateCommand(),
+		describeCommand(),
+		topCommand(),
+		taskQueueCommand(),
+		traceCommand(),
+		retentionCommand(),
+		pauseCommand(),
+		resumeCommand(),
+		replayCommand(),
+		workflowCommand(),
+		upgradeRestartCommand(),
 	}
 
 	return app
@@ -211,3 +952,35 @@ func getPragmaMap(input []string) (map[string]string, error) {
 	}
 	return result, nil
 }
+
+var byteSizeUnits = []struct {
+	suffix     string
+	multiplier uint64
+}{
+	{"KiB", 1 << 10},
+	{"MiB", 1 << 20},
+	{"GiB", 1 << 30},
+	{"KB", 1000},
+	{"MB", 1000 * 1000},
+	{"GB", 1000 * 1000 * 1000},
+	{"B", 1},
+}
+
+func parseByteSize(input string) (uint64, error) {
+	multiplier := uint64(1)
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(input, unit.suffix) {
+			input = strings.TrimSuffix(input, unit.suffix)
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(input), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxUint64/multiplier {
+		return 0, fmt.Errorf("size too large")
+	}
+	return n * multiplier, nil
+}
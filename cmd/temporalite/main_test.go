@@ -0,0 +1,43 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import "testing"
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    uint64
+		wantErr bool
+	}{
+		{input: "512", want: 512},
+		{input: "512B", want: 512},
+		{input: "4KiB", want: 4 << 10},
+		{input: "480MiB", want: 480 << 20},
+		{input: "1GiB", want: 1 << 30},
+		{input: "2KB", want: 2000},
+		{input: "3MB", want: 3000000},
+		{input: "1GB", want: 1000000000},
+		{input: " 1 GiB", want: 1 << 30},
+		{input: "17179869183GiB", want: 1<<64 - 1<<30},
+		{input: "17179869184GiB", wantErr: true},
+		{input: "18446744073709551615", want: 18446744073709551615},
+		{input: "18446744073709551616", wantErr: true},
+		{input: "-1MiB", wantErr: true},
+		{input: "1TiB", wantErr: true},
+		{input: "", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := parseByteSize(tc.input)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseByteSize(%q) error = %v, wantErr %v", tc.input, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("parseByteSize(%q) = %d, want %d", tc.input, got, tc.want)
+			}
+		})
+	}
+}
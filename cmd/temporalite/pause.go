@@ -0,0 +1,73 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/DataDog/temporalite/internal/liteconfig"
+)
+
+func postDebug(ctx context.Context, address, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+address+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("POST %s: %s", path, resp.Status)
+	}
+	return nil
+}
+
+func newDebugAddressFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:  debugAddressFlag,
+		Usage: "host:port of the server's pprof endpoint",
+		Value: fmt.Sprintf("127.0.0.1:%d", liteconfig.DefaultFrontendPort+201),
+	}
+}
+
+func pauseCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "pause",
+		Usage:     "Stop handing workflow and activity tasks to workers until resumed",
+		ArgsUsage: " ",
+		Description: "Holds worker polls so workflows can be inspected in an intermediate state. The server keeps " +
+			"accepting client requests, and timers keep firing.",
+		Flags: []cli.Flag{newDebugAddressFlag()},
+		Action: func(c *cli.Context) error {
+			if err := postDebug(c.Context, c.String(debugAddressFlag), "/debug/pause"); err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: unable to pause task processing; is --%s the server's pprof address? %v", debugAddressFlag, err), 1)
+			}
+			fmt.Println("Task processing paused; run resume to continue")
+			return nil
+		},
+	}
+}
+
+func resumeCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "resume",
+		Usage:     "Hand tasks to workers again after pause",
+		ArgsUsage: " ",
+		Flags:     []cli.Flag{newDebugAddressFlag()},
+		Action: func(c *cli.Context) error {
+			if err := postDebug(c.Context, c.String(debugAddressFlag), "/debug/resume"); err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: unable to resume task processing; is --%s the server's pprof address? %v", debugAddressFlag, err), 1)
+			}
+			fmt.Println("Task processing resumed")
+			return nil
+		},
+	}
+}
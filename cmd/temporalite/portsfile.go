@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/temporalite"
+)
+
+// portsFile is the content of the file written by --ports-file, letting tools
+// discover a server started with --dynamic-ports.
+type portsFile struct {
+	PID      int    `json:"pid"`
+	Frontend string `json:"frontend"`
+	UI       string `json:"ui,omitempty"`
+	Metrics  string `json:"metrics,omitempty"`
+	PProf    string `json:"pprof"`
+}
+
+// freePort returns a port on ip that is available at the time of the call.
+func freePort(ip string) (int, error) {
+	l, err := net.Listen("tcp", net.JoinHostPort(ip, "0"))
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// awaitFrontend blocks until the frontend of s accepts connections.
+func awaitFrontend(ctx context.Context, s *temporalite.Server) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	conn, err := s.Dial(ctx)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// writePortsFile writes the addresses of s to path. The file is replaced
+// atomically, so readers never see a partial write.
+func writePortsFile(path string, s *temporalite.Server, uiPort int) error {
+	ports := portsFile{
+		PID:      os.Getpid(),
+		Frontend: s.FrontendHostPort(),
+		Metrics:  s.MetricsHostPort(),
+		PProf:    s.PProfHostPort(),
+	}
+	if uiPort > 0 {
+		host, _, _ := net.SplitHostPort(s.FrontendHostPort())
+		ports.UI = net.JoinHostPort(host, strconv.Itoa(uiPort))
+	}
+	data, err := json.MarshalIndent(ports, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,250 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/urfave/cli/v2"
+	commonpb "go.temporal.io/api/common/v1"
+	historypb "go.temporal.io/api/history/v1"
+	"go.temporal.io/api/workflowservice/v1"
+)
+
+const (
+	packageFlag = "package"
+
+	sdkWorkflowPackage = "go.temporal.io/sdk/workflow"
+)
+
+// replayProgram is the source of the program built against the user's workflow
+// package to replay a history file with the SDK's WorkflowReplayer.
+var replayProgram = template.Must(template.New("replay").Parse(`// Code generated by temporalite replay. DO NOT EDIT.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"go.temporal.io/sdk/worker"
+
+	workflows {{ printf "%q" .ImportPath }}
+)
+
+func main() {
+	replayer := worker.NewWorkflowReplayer()
+{{- range .Workflows }}
+	replayer.RegisterWorkflow(workflows.{{ . }})
+{{- end }}
+	if err := replayer.ReplayWorkflowHistoryFromJSONFile(nil, os.Args[1]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+`))
+
+// workflowPackage describes a Go package containing workflow definitions.
+type workflowPackage struct {
+	ImportPath string
+	Dir        string
+	ModuleDir  string
+	Workflows  []string
+}
+
+// loadWorkflowPackage resolves pattern with the go command and finds the exported
+// functions in it whose first parameter is a workflow.Context.
+func loadWorkflowPackage(pattern string) (*workflowPackage, error) {
+	out, err := exec.Command("go", "list", "-f", "{{.ImportPath}}\n{{.Dir}}\n{{.Module.Dir}}\n{{join .GoFiles \"\\n\"}}", pattern).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("go list %s: %s", pattern, bytes.TrimSpace(exitErr.Stderr))
+		}
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) < 3 {
+		return nil, fmt.Errorf("unexpected go list output for %s", pattern)
+	}
+	pkg := &workflowPackage{ImportPath: lines[0], Dir: lines[1], ModuleDir: lines[2]}
+
+	fset := token.NewFileSet()
+	for _, name := range lines[3:] {
+		file, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		pkg.Workflows = append(pkg.Workflows, workflowFuncs(file)...)
+	}
+	sort.Strings(pkg.Workflows)
+	return pkg, nil
+}
+
+// workflowFuncs returns the exported top-level functions of file taking a
+// workflow.Context as their first parameter.
+func workflowFuncs(file *ast.File) []string {
+	alias := ""
+	for _, imp := range file.Imports {
+		if path, _ := strconv.Unquote(imp.Path.Value); path == sdkWorkflowPackage {
+			alias = "workflow"
+			if imp.Name != nil {
+				alias = imp.Name.Name
+			}
+		}
+	}
+	if alias == "" {
+		return nil
+	}
+
+	var names []string
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || !fn.Name.IsExported() || len(fn.Type.Params.List) == 0 {
+			continue
+		}
+		sel, ok := fn.Type.Params.List[0].Type.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "Context" {
+			continue
+		}
+		if x, ok := sel.X.(*ast.Ident); ok && x.Name == alias {
+			names = append(names, fn.Name.Name)
+		}
+	}
+	return names
+}
+
+func replayCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "replay",
+		Usage:     "Replay a workflow's history against local workflow code to check for nondeterminism",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			newAddressFlag(),
+			&cli.StringFlag{
+				Name:    namespaceFlag,
+				Aliases: []string{"n"},
+				Value:   "default",
+				Usage:   "namespace of the workflow",
+			},
+			&cli.StringFlag{
+				Name:     workflowIDFlag,
+				Aliases:  []string{"w"},
+				Usage:    "workflow ID",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:    runIDFlag,
+				Aliases: []string{"r"},
+				Usage:   "run ID, defaulting to the latest run",
+			},
+			&cli.StringFlag{
+				Name:  packageFlag,
+				Usage: "Go package `PATTERN` containing the workflow definitions, as passed to go build",
+				Value: ".",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			pkg, err := loadWorkflowPackage(c.String(packageFlag))
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: unable to load workflow package: %v", err), 1)
+			}
+			if len(pkg.Workflows) == 0 {
+				return cli.Exit(fmt.Sprintf("ERROR: no exported functions taking a workflow.Context found in %s", pkg.ImportPath), 1)
+			}
+
+			conn, err := dialFrontend(c)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			svc := workflowservice.NewWorkflowServiceClient(conn)
+
+			history := &historypb.History{}
+			var pageToken []byte
+			for {
+				resp, err := svc.GetWorkflowExecutionHistory(c.Context, &workflowservice.GetWorkflowExecutionHistoryRequest{
+					Namespace:     c.String(namespaceFlag),
+					Execution:     &commonpb.WorkflowExecution{WorkflowId: c.String(workflowIDFlag), RunId: c.String(runIDFlag)},
+					NextPageToken: pageToken,
+				})
+				if err != nil {
+					return cli.Exit(fmt.Sprintf("ERROR: unable to get workflow history: %v", err), 1)
+				}
+				history.Events = append(history.Events, resp.GetHistory().GetEvents()...)
+				if pageToken = resp.GetNextPageToken(); len(pageToken) == 0 {
+					break
+				}
+			}
+			if len(history.Events) > 0 {
+				typ := history.Events[0].GetWorkflowExecutionStartedEventAttributes().GetWorkflowType().GetName()
+				if !containsString(pkg.Workflows, typ) {
+					return cli.Exit(fmt.Sprintf("ERROR: workflow type %q not found in %s; found %s", typ, pkg.ImportPath, strings.Join(pkg.Workflows, ", ")), 1)
+				}
+			}
+
+			// The program must live inside the user's module to import their package
+			// and build with their SDK version.
+			dir, err := os.MkdirTemp(pkg.ModuleDir, ".temporalite-replay-")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(dir)
+
+			historyFile := filepath.Join(dir, "history.json")
+			f, err := os.Create(historyFile)
+			if err != nil {
+				return err
+			}
+			err = (&jsonpb.Marshaler{}).Marshal(f, history)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+
+			var src bytes.Buffer
+			if err := replayProgram.Execute(&src, pkg); err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(dir, "main.go"), src.Bytes(), 0644); err != nil {
+				return err
+			}
+
+			fmt.Printf("Replaying %d events against %s\n", len(history.Events), pkg.ImportPath)
+			cmd := exec.CommandContext(c.Context, "go", "run", "./"+filepath.Base(dir), historyFile)
+			cmd.Dir = pkg.ModuleDir
+			cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+			if err := cmd.Run(); err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: replay failed: %v", err), 1)
+			}
+			fmt.Println("Replay succeeded")
+			return nil
+		},
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
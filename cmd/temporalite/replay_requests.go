@@ -0,0 +1,67 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"google.golang.org/grpc"
+
+	// Register Temporal API message types for decoding recordings
+	_ "go.temporal.io/api/workflowservice/v1"
+
+	"github.com/DataDog/temporalite/internal/liteconfig"
+	"github.com/DataDog/temporalite/internal/recording"
+)
+
+const (
+	recordDirFlag    = "record-dir"
+	targetFlag       = "target"
+	includePollsFlag = "include-polls"
+	timeoutFlag      = "timeout"
+)
+
+func replayRequestsCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "replay-requests",
+		Usage:     "Re-issue frontend requests captured with --record-dir against a server",
+		ArgsUsage: "FILE",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  targetFlag,
+				Usage: "host:port of the temporal-frontend GRPC service to replay against",
+				Value: fmt.Sprintf("127.0.0.1:%d", liteconfig.DefaultFrontendPort),
+			},
+			&cli.BoolFlag{
+				Name:  includePollsFlag,
+				Usage: "also replay task queue long polls, which block until work is available",
+			},
+			&cli.DurationFlag{
+				Name:  timeoutFlag,
+				Usage: "timeout for each replayed request",
+				Value: 10 * time.Second,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() != 1 {
+				return cli.Exit("ERROR: replay-requests requires exactly one recording file argument", 1)
+			}
+
+			conn, err := grpc.Dial(c.String(targetFlag), grpc.WithInsecure())
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			return recording.Replay(c.Context, conn, c.Args().First(), recording.ReplayOptions{
+				IncludePolls: c.Bool(includePollsFlag),
+				Timeout:      c.Duration(timeoutFlag),
+			}, os.Stdout)
+		},
+	}
+}
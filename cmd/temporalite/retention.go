@@ -0,0 +1,152 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/DataDog/temporalite"
+	"github.com/DataDog/temporalite/internal/liteconfig"
+	"github.com/DataDog/temporalite/internal/sqlitedb"
+)
+
+func readRetentionReport(ctx context.Context, address string) (*temporalite.RetentionReport, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+address+"/debug/retention", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET /debug/retention: %s", resp.Status)
+	}
+	var report temporalite.RetentionReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func printRetainedRuns(title string, runs []temporalite.RetainedRun, deleted bool) {
+	fmt.Printf("\n%s: %d\n", title, len(runs))
+	if len(runs) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	if deleted {
+		fmt.Fprintln(w, "  DELETED\tNAMESPACE\tWORKFLOW ID\tRUN ID\tCLOSED")
+	} else {
+		fmt.Fprintln(w, "  DUE\tNAMESPACE\tWORKFLOW ID\tRUN ID\tCLOSED")
+	}
+	for _, run := range runs {
+		at := run.DueTime
+		if deleted && run.DeletedAt != nil {
+			at = *run.DeletedAt
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", at.Local().Format(time.RFC3339), run.Namespace, run.WorkflowID, run.RunID,
+			run.CloseTime.Local().Format(time.RFC3339))
+	}
+	_ = w.Flush()
+}
+
+func retentionCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "retention",
+		Usage:     "Show which closed workflows a running server deleted after their retention period, and which it deletes next",
+		ArgsUsage: " ",
+		Description: "Closed workflows are deleted once their namespace's retention period has passed since they closed. " +
+			"The period in effect when a workflow closed applies, so changing a namespace's retention does not affect " +
+			"workflows that already closed. Deletions scheduled while the server was stopped happen as soon as it starts.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  debugAddressFlag,
+				Usage: "host:port of the server's pprof endpoint",
+				Value: fmt.Sprintf("127.0.0.1:%d", liteconfig.DefaultFrontendPort+201),
+			},
+		},
+		Subcommands: []*cli.Command{
+			{
+				Name:      "run-now",
+				Usage:     "Delete the closed workflows of a namespace whose retention period has passed right away",
+				ArgsUsage: " ",
+				Description: "Deletes closed workflows older than the namespace's current retention period from a " +
+					"database file, including those that closed under a longer retention period. The server using the " +
+					"file must be stopped first. Deleted histories are not archived. SQLite reuses the freed space; to " +
+					"shrink the file, run VACUUM on it.",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    dbPathFlag,
+						Aliases: []string{"f"},
+						Value:   defaultCfg.DatabaseFilePath,
+						Usage:   "database file to delete expired workflows from",
+					},
+					&cli.StringFlag{
+						Name:    namespaceFlag,
+						Aliases: []string{"n"},
+						Value:   "default",
+						Usage:   "namespace to delete expired workflows from",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					deleted, err := sqlitedb.DeleteExpiredRuns(c.Context, c.String(dbPathFlag), c.String(namespaceFlag), time.Now())
+					if errors.Is(err, sqlitedb.ErrInUse) {
+						return cli.Exit(fmt.Sprintf("ERROR: %v; stop it and retry in 20 seconds", err), 1)
+					} else if err != nil {
+						return cli.Exit(fmt.Sprintf("ERROR: unable to delete expired workflows: %v", err), 1)
+					}
+					fmt.Printf("Deleted %d expired workflows from namespace %s\n", len(deleted), c.String(namespaceFlag))
+					if len(deleted) == 0 {
+						return nil
+					}
+					w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+					fmt.Fprintln(w, "  WORKFLOW ID\tRUN ID\tCLOSED")
+					for _, run := range deleted {
+						fmt.Fprintf(w, "  %s\t%s\t%s\n", run.WorkflowID, run.RunID, run.CloseTime.Local().Format(time.RFC3339))
+					}
+					_ = w.Flush()
+					return nil
+				},
+			},
+		},
+		Action: func(c *cli.Context) error {
+			report, err := readRetentionReport(c.Context, c.String(debugAddressFlag))
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: unable to read retention report; is --%s the server's pprof address? %v", debugAddressFlag, err), 1)
+			}
+			if report.LastScan.IsZero() {
+				fmt.Println("The server has not listed closed workflows yet; start it with --" + retentionReportFlag + ", or try again in a minute")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "NAMESPACE\tRETENTION\tDUE IN 1H\tOVERDUE")
+			for _, ns := range report.Namespaces {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%d\n", ns.Namespace, ns.Retention, ns.Due, ns.Overdue)
+			}
+			_ = w.Flush()
+
+			printRetainedRuns("Deleted since the server started", report.Deleted, true)
+			printRetainedRuns("Past retention but not deleted", report.Overdue, false)
+			printRetainedRuns("Next to be deleted", report.Upcoming, false)
+			if len(report.Overdue) > 0 {
+				fmt.Println("\nWARNING: workflows past retention are kept when they closed under a longer retention period than the namespace now has")
+			}
+			fmt.Printf("\nAs of %s\n", report.LastScan.Local().Format(time.RFC3339))
+			return nil
+		},
+	}
+}
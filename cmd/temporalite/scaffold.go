@@ -0,0 +1,260 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/DataDog/temporalite/internal/liteconfig"
+)
+
+const (
+	moduleFlag = "module"
+
+	sdkModule = "go.temporal.io/sdk"
+	// defaultSDKVersion is used when the SDK version temporalite was built
+	// with is not recorded in the binary.
+	defaultSDKVersion = "v1.11.1"
+)
+
+// scaffoldFiles are the templates of the files generated by temporalite init,
+// keyed by path relative to the project directory.
+var scaffoldFiles = map[string]*template.Template{
+	"go.mod": template.Must(template.New("go.mod").Parse(`module {{ .Module }}
+
+go 1.17
+
+require {{ .SDKModule }} {{ .SDKVersion }}
+`)),
+	"workflow.go": template.Must(template.New("workflow.go").Parse(`package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// TaskQueue is the task queue the worker polls and the starter starts workflows on.
+const TaskQueue = "greetings"
+
+// GreetingWorkflow greets name using the ComposeGreeting activity.
+func GreetingWorkflow(ctx workflow.Context, name string) (string, error) {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 10 * time.Second,
+	})
+	var greeting string
+	err := workflow.ExecuteActivity(ctx, ComposeGreeting, name).Get(ctx, &greeting)
+	return greeting, err
+}
+
+// ComposeGreeting returns a greeting for name. Activities are where workflows
+// call other services, and are retried when they fail.
+func ComposeGreeting(ctx context.Context, name string) (string, error) {
+	return fmt.Sprintf("Hello, %s!", name), nil
+}
+`)),
+	"worker/main.go": template.Must(template.New("worker").Parse(`// Command worker runs the workflows and activities of {{ .Module }}.
+package main
+
+import (
+	"log"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+
+	app {{ printf "%q" .Module }}
+)
+
+func main() {
+	c, err := client.NewClient(client.Options{
+		HostPort:  {{ printf "%q" .Address }},
+		Namespace: {{ printf "%q" .Namespace }},
+	})
+	if err != nil {
+		log.Fatalf("unable to connect to Temporal: %v", err)
+	}
+	defer c.Close()
+
+	w := worker.New(c, app.TaskQueue, worker.Options{})
+	w.RegisterWorkflow(app.GreetingWorkflow)
+	w.RegisterActivity(app.ComposeGreeting)
+	if err := w.Run(worker.InterruptCh()); err != nil {
+		log.Fatalf("worker stopped: %v", err)
+	}
+}
+`)),
+	"starter/main.go": template.Must(template.New("starter").Parse(`// Command starter starts a GreetingWorkflow and prints its result.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"go.temporal.io/sdk/client"
+
+	app {{ printf "%q" .Module }}
+)
+
+func main() {
+	c, err := client.NewClient(client.Options{
+		HostPort:  {{ printf "%q" .Address }},
+		Namespace: {{ printf "%q" .Namespace }},
+	})
+	if err != nil {
+		log.Fatalf("unable to connect to Temporal: %v", err)
+	}
+	defer c.Close()
+
+	name := "Temporal"
+	if len(os.Args) > 1 {
+		name = os.Args[1]
+	}
+	run, err := c.ExecuteWorkflow(context.Background(), client.StartWorkflowOptions{
+		ID:        "greeting-" + name,
+		TaskQueue: app.TaskQueue,
+	}, app.GreetingWorkflow, name)
+	if err != nil {
+		log.Fatalf("unable to start workflow: %v", err)
+	}
+	log.Printf("started workflow %s (run %s)", run.GetID(), run.GetRunID())
+
+	var greeting string
+	if err := run.Get(context.Background(), &greeting); err != nil {
+		log.Fatalf("workflow failed: %v", err)
+	}
+	log.Println(greeting)
+}
+`)),
+}
+
+// scaffoldData fills in scaffoldFiles.
+type scaffoldData struct {
+	Module     string
+	Address    string
+	Namespace  string
+	SDKModule  string
+	SDKVersion string
+}
+
+// sdkVersion returns the version of the Go SDK temporalite was built with,
+// which is known to work with this server.
+func sdkVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == sdkModule && strings.HasPrefix(dep.Version, "v") {
+				return dep.Version
+			}
+		}
+	}
+	return defaultSDKVersion
+}
+
+// writeScaffold generates the project in dir, which must not exist or be empty.
+func writeScaffold(dir string, data scaffoldData) error {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%s is not empty", dir)
+	}
+	names := make([]string, 0, len(scaffoldFiles))
+	for name := range scaffoldFiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var buf bytes.Buffer
+		if err := scaffoldFiles[name].Execute(&buf, data); err != nil {
+			return err
+		}
+		src := buf.Bytes()
+		if strings.HasSuffix(name, ".go") {
+			formatted, err := format.Source(src)
+			if err != nil {
+				return fmt.Errorf("unable to format %s: %w", name, err)
+			}
+			src = formatted
+		}
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, src, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func initCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "init",
+		Usage:     "Generate a Go project with a worker and a workflow starter for this server",
+		ArgsUsage: "DIR",
+		Description: "Writes a minimal Go module to DIR, which must not exist or be empty: a workflow and activity, " +
+			"a worker running them, and a program starting the workflow, all connecting to temporalite's default " +
+			"address and namespace.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        moduleFlag,
+				Usage:       "Go module path of the project",
+				DefaultText: "the base name of DIR",
+			},
+			&cli.StringFlag{
+				Name:  addressFlag,
+				Usage: "host:port of the Temporal frontend the worker and starter connect to",
+				Value: fmt.Sprintf("127.0.0.1:%d", liteconfig.DefaultFrontendPort),
+			},
+			&cli.StringFlag{
+				Name:    namespaceFlag,
+				Aliases: []string{"n"},
+				Usage:   "namespace the worker and starter use",
+				Value:   "default",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return cli.Exit("ERROR: init requires the project directory as its only argument", exitConfigError)
+			}
+			dir := c.Args().First()
+			module := c.String(moduleFlag)
+			if module == "" {
+				abs, err := filepath.Abs(dir)
+				if err != nil {
+					return err
+				}
+				module = path.Base(filepath.ToSlash(abs))
+			}
+			data := scaffoldData{
+				Module:     module,
+				Address:    c.String(addressFlag),
+				Namespace:  c.String(namespaceFlag),
+				SDKModule:  sdkModule,
+				SDKVersion: sdkVersion(),
+			}
+			if err := writeScaffold(dir, data); err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: unable to generate project: %v", err), 1)
+			}
+
+			fmt.Printf("Created %s in %s. To run it:\n\n", module, dir)
+			fmt.Printf("  cd %s && go mod tidy\n", dir)
+			fmt.Printf("  temporalite start --ephemeral --namespace %s  # in another terminal\n", data.Namespace)
+			fmt.Println("  go run ./worker  # in another terminal")
+			fmt.Println("  go run ./starter")
+			return nil
+		},
+	}
+}
@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+// runCommand runs cmd with args, returning what it wrote to the app's writer
+// and its error instead of exiting.
+func runCommand(cmd *cli.Command, args ...string) (string, error) {
+	var out bytes.Buffer
+	app := &cli.App{
+		Commands:       []*cli.Command{cmd},
+		Writer:         &out,
+		ExitErrHandler: func(*cli.Context, error) {},
+	}
+	err := app.Run(append([]string{"temporalite"}, args...))
+	return out.String(), err
+}
+
+func TestInitCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		existing string // file to create in the directory first
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name: "defaults",
+			want: []string{"module hello\n", `"127.0.0.1:7233"`, `Namespace: "default"`},
+		},
+		{
+			name: "flags",
+			args: []string{"--module", "example.com/hello", "--address", "10.0.0.1:7000", "--namespace", "orders"},
+			want: []string{"module example.com/hello\n", `app "example.com/hello"`, `"10.0.0.1:7000"`, `Namespace: "orders"`},
+		},
+		{
+			name:     "not empty",
+			existing: "README.md",
+			wantErr:  true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "hello")
+			if tc.existing != "" {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, tc.existing), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, err := runCommand(initCommand(), append(append([]string{"init"}, tc.args...), dir)...)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("init error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			var generated strings.Builder
+			for name := range scaffoldFiles {
+				src, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if strings.HasSuffix(name, ".go") {
+					if _, err := parser.ParseFile(token.NewFileSet(), name, src, 0); err != nil {
+						t.Errorf("generated %s does not parse: %v", name, err)
+					}
+				}
+				generated.Write(src)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(generated.String(), want) {
+					t.Errorf("generated project does not contain %q", want)
+				}
+			}
+		})
+	}
+}
+
+func TestInitCommandRequiresDir(t *testing.T) {
+	if _, err := runCommand(initCommand(), "init"); err == nil {
+		t.Error("init without a directory succeeded, want error")
+	}
+}
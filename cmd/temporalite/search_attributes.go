@@ -0,0 +1,98 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/server/api/adminservice/v1"
+)
+
+func searchAttributesCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "search-attributes",
+		Usage: "Manage custom search attributes of a running server",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "list",
+				Usage:     "List custom search attributes",
+				ArgsUsage: " ",
+				Flags:     []cli.Flag{newAddressFlag()},
+				Action: func(c *cli.Context) error {
+					conn, err := dialFrontend(c)
+					if err != nil {
+						return err
+					}
+					defer conn.Close()
+
+					resp, err := adminservice.NewAdminServiceClient(conn).GetSearchAttributes(c.Context, &adminservice.GetSearchAttributesRequest{})
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("ERROR: unable to get search attributes: %v", err), 1)
+					}
+					names := make([]string, 0, len(resp.GetCustomAttributes()))
+					for name := range resp.GetCustomAttributes() {
+						names = append(names, name)
+					}
+					sort.Strings(names)
+					for _, name := range names {
+						fmt.Printf("%s\t%s\n", name, resp.GetCustomAttributes()[name])
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "remove",
+				Usage:     "Remove custom search attributes",
+				ArgsUsage: "NAME...",
+				Flags:     []cli.Flag{newAddressFlag()},
+				Action: func(c *cli.Context) error {
+					if c.Args().Len() == 0 {
+						return cli.Exit("ERROR: search-attributes remove requires at least one search attribute name", 1)
+					}
+					conn, err := dialFrontend(c)
+					if err != nil {
+						return err
+					}
+					defer conn.Close()
+
+					_, err = adminservice.NewAdminServiceClient(conn).RemoveSearchAttributes(c.Context, &adminservice.RemoveSearchAttributesRequest{
+						SearchAttributes: c.Args().Slice(),
+					})
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("ERROR: unable to remove search attributes: %v", err), 1)
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// parseSearchAttributes parses NAME=TYPE pairs, where TYPE is a search attribute
+// type such as Keyword or Int, matched case-insensitively.
+func parseSearchAttributes(input []string) (map[string]enumspb.IndexedValueType, error) {
+	result := make(map[string]enumspb.IndexedValueType)
+	for _, attr := range input {
+		vals := strings.SplitN(attr, "=", 2)
+		if len(vals) != 2 || vals[0] == "" {
+			return nil, fmt.Errorf("search attributes must be in NAME=TYPE format, got %q", attr)
+		}
+		typ := enumspb.INDEXED_VALUE_TYPE_UNSPECIFIED
+		for name, value := range enumspb.IndexedValueType_value {
+			if strings.EqualFold(name, vals[1]) {
+				typ = enumspb.IndexedValueType(value)
+			}
+		}
+		if typ == enumspb.INDEXED_VALUE_TYPE_UNSPECIFIED {
+			return nil, fmt.Errorf("unknown search attribute type %q", vals[1])
+		}
+		result[vals[0]] = typ
+	}
+	return result, nil
+}
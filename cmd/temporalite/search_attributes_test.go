@@ -0,0 +1,55 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	enumspb "go.temporal.io/api/enums/v1"
+)
+
+func TestParseSearchAttributes(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []string
+		want    map[string]enumspb.IndexedValueType
+		wantErr bool
+	}{
+		{
+			name: "none",
+			want: map[string]enumspb.IndexedValueType{},
+		},
+		{
+			name:  "types ignore case",
+			input: []string{"CustomerId=keyword", "Total=Double", "ShippedAt=DATETIME"},
+			want: map[string]enumspb.IndexedValueType{
+				"CustomerId": enumspb.INDEXED_VALUE_TYPE_KEYWORD,
+				"Total":      enumspb.INDEXED_VALUE_TYPE_DOUBLE,
+				"ShippedAt":  enumspb.INDEXED_VALUE_TYPE_DATETIME,
+			},
+		},
+		{
+			name:  "last type wins",
+			input: []string{"Total=Int", "Total=Double"},
+			want:  map[string]enumspb.IndexedValueType{"Total": enumspb.INDEXED_VALUE_TYPE_DOUBLE},
+		},
+		{name: "missing type", input: []string{"CustomerId"}, wantErr: true},
+		{name: "missing name", input: []string{"=Keyword"}, wantErr: true},
+		{name: "unknown type", input: []string{"CustomerId=String"}, wantErr: true},
+		{name: "unspecified type", input: []string{"CustomerId=Unspecified"}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseSearchAttributes(tc.input)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseSearchAttributes() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("parseSearchAttributes() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
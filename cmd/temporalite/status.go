@@ -0,0 +1,180 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"go.temporal.io/api/workflowservice/v1"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// statusTimeout bounds each request made by the status command.
+const statusTimeout = 5 * time.Second
+
+// runningServer is an entry of the temporalite.servers variable in /debug/vars.
+type runningServer struct {
+	Frontend      string `json:"frontend"`
+	Version       string `json:"version"`
+	Ephemeral     bool   `json:"ephemeral"`
+	Database      string `json:"database"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+}
+
+// readDebugVars decodes the /debug/vars page served on a server's pprof address into v.
+func readDebugVars(ctx context.Context, address string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+address+"/debug/vars", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// pprofAddress returns the default pprof address of a server with the given frontend address.
+func pprofAddress(frontend string) string {
+	host, port, err := net.SplitHostPort(frontend)
+	if err != nil {
+		return ""
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return ""
+	}
+	return net.JoinHostPort(host, strconv.Itoa(p+201))
+}
+
+// countNamespaces returns the number of namespaces registered on the server.
+func countNamespaces(ctx context.Context, svc workflowservice.WorkflowServiceClient) (int, error) {
+	var (
+		count int
+		token []byte
+	)
+	for {
+		resp, err := svc.ListNamespaces(ctx, &workflowservice.ListNamespacesRequest{PageSize: 100, NextPageToken: token})
+		if err != nil {
+			return 0, err
+		}
+		count += len(resp.GetNamespaces())
+		if token = resp.GetNextPageToken(); len(token) == 0 {
+			return count, nil
+		}
+	}
+}
+
+func statusCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "status",
+		Usage:     "Report whether a server is running and how it is configured",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			newAddressFlag(),
+			&cli.StringFlag{
+				Name:        debugAddressFlag,
+				Usage:       "host:port of the server's pprof endpoint",
+				DefaultText: "--address port + 201",
+			},
+			&cli.StringFlag{
+				Name:  portsFileFlag,
+				Usage: "read the server's addresses from a ports file written by start --ports-file",
+			},
+			&cli.StringFlag{
+				Name:  pidFileFlag,
+				Usage: "pid file written by the server",
+				Value: defaultPIDFile,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			var (
+				address      = c.String(addressFlag)
+				debugAddress = c.String(debugAddressFlag)
+				pid          int
+			)
+			if path := c.String(portsFileFlag); path != "" {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return cli.Exit(fmt.Sprintf("ERROR: unable to read ports file: %v", err), 1)
+				}
+				var ports portsFile
+				if err := json.Unmarshal(data, &ports); err != nil {
+					return cli.Exit(fmt.Sprintf("ERROR: invalid ports file: %v", err), 1)
+				}
+				address, debugAddress, pid = ports.Frontend, ports.PProf, ports.PID
+			} else if p, err := readPIDFile(c.String(pidFileFlag)); err == nil && processRunning(p) {
+				pid = p
+			}
+			if debugAddress == "" {
+				debugAddress = pprofAddress(address)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			defer w.Flush()
+			fmt.Fprintf(w, "Frontend:\t%s\n", address)
+			if pid > 0 {
+				fmt.Fprintf(w, "PID:\t%d\n", pid)
+			}
+
+			conn, err := grpc.DialContext(c.Context, address, grpc.WithInsecure())
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(c.Context, statusTimeout)
+			defer cancel()
+			health, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{
+				Service: "temporal.api.workflowservice.v1.WorkflowService",
+			})
+			if err != nil {
+				fmt.Fprintf(w, "Health:\tunreachable (%v)\n", err)
+				w.Flush()
+				return cli.Exit("", 1)
+			}
+			fmt.Fprintf(w, "Health:\t%s\n", health.GetStatus())
+
+			if count, err := countNamespaces(ctx, workflowservice.NewWorkflowServiceClient(conn)); err != nil {
+				fmt.Fprintf(w, "Namespaces:\tunavailable (%v)\n", err)
+			} else {
+				fmt.Fprintf(w, "Namespaces:\t%d\n", count)
+			}
+
+			var vars struct {
+				Servers []runningServer `json:"temporalite.servers"`
+			}
+			if err := readDebugVars(ctx, debugAddress, &vars); err != nil || len(vars.Servers) == 0 {
+				fmt.Fprintf(w, "Server details:\tunavailable from %s; is --%s the server's pprof address?\n", debugAddress, debugAddressFlag)
+				return nil
+			}
+			server := vars.Servers[0]
+			for _, s := range vars.Servers {
+				if s.Frontend == address {
+					server = s
+				}
+			}
+			database := server.Database
+			if server.Ephemeral {
+				database = "in memory"
+			}
+			fmt.Fprintf(w, "Version:\t%s\n", server.Version)
+			fmt.Fprintf(w, "Uptime:\t%s\n", time.Duration(server.UptimeSeconds)*time.Second)
+			fmt.Fprintf(w, "Database:\t%s\n", database)
+			return nil
+		},
+	}
+}
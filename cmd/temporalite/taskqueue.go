@@ -0,0 +1,85 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"go.temporal.io/api/workflowservice/v1"
+
+	"github.com/DataDog/temporalite"
+)
+
+func taskQueueCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "taskqueue",
+		Usage: "Inspect task queues of a running server",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "describe",
+				Usage:     "Print the backlog depth and pollers of a task queue",
+				ArgsUsage: "NAME",
+				Flags: []cli.Flag{
+					newAddressFlag(),
+					&cli.StringFlag{
+						Name:    namespaceFlag,
+						Aliases: []string{"n"},
+						Value:   "default",
+						Usage:   "namespace of the task queue",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if c.Args().Len() != 1 {
+						return cli.Exit("ERROR: taskqueue describe requires exactly one task queue name", 1)
+					}
+					conn, err := dialFrontend(c)
+					if err != nil {
+						return err
+					}
+					defer conn.Close()
+
+					backlog, err := temporalite.DescribeTaskQueueBacklog(c.Context, workflowservice.NewWorkflowServiceClient(conn), c.String(namespaceFlag), c.Args().First())
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("ERROR: unable to describe task queue: %v", err), 1)
+					}
+
+					w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+					fmt.Fprintf(w, "Task queue:\t%s\n", backlog.Name)
+					fmt.Fprintf(w, "Workflow backlog:\t%d\n", backlog.WorkflowBacklog)
+					fmt.Fprintf(w, "Activity backlog:\t%d\n", backlog.ActivityBacklog)
+					_ = w.Flush()
+
+					for _, group := range []struct {
+						kind    string
+						pollers []temporalite.Poller
+					}{
+						{"Workflow", backlog.WorkflowPollers},
+						{"Activity", backlog.ActivityPollers},
+					} {
+						fmt.Printf("\n%s pollers: %d\n", group.kind, len(group.pollers))
+						if len(group.pollers) == 0 {
+							continue
+						}
+						w = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+						fmt.Fprintln(w, "  IDENTITY\tLAST POLL")
+						for _, p := range group.pollers {
+							fmt.Fprintf(w, "  %s\t%s ago\n", p.Identity, time.Since(p.LastAccessTime).Round(time.Second))
+						}
+						_ = w.Flush()
+					}
+
+					if backlog.Stalled() {
+						fmt.Println("\nWARNING: tasks are waiting but no worker is polling; check that a worker is running for this task queue and namespace")
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
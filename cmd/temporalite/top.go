@@ -0,0 +1,233 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"go.temporal.io/api/workflowservice/v1"
+
+	"github.com/DataDog/temporalite"
+	"github.com/DataDog/temporalite/internal/liteconfig"
+)
+
+const (
+	debugAddressFlag = "debug-address"
+	intervalFlag     = "interval"
+
+	// maxOpenWorkflows bounds how many open workflows top lists per refresh.
+	maxOpenWorkflows = 10000
+)
+
+// rateMethods are the frontend methods whose request rates top reports.
+var rateMethods = []struct {
+	label  string
+	method string
+}{
+	{"workflows started", workflowServiceMethod("StartWorkflowExecution")},
+	{"workflow tasks completed", workflowServiceMethod("RespondWorkflowTaskCompleted")},
+	{"workflow tasks failed", workflowServiceMethod("RespondWorkflowTaskFailed")},
+	{"activities completed", workflowServiceMethod("RespondActivityTaskCompleted")},
+	{"activities failed", workflowServiceMethod("RespondActivityTaskFailed")},
+}
+
+func workflowServiceMethod(name string) string {
+	return "/temporal.api.workflowservice.v1.WorkflowService/" + name
+}
+
+// topSnapshot is the state shown in one refresh of the dashboard.
+type topSnapshot struct {
+	taken      time.Time
+	openByType map[string]int
+	open       int
+	truncated  bool
+	queues     []*temporalite.TaskQueueBacklog
+	requests   map[string]float64
+}
+
+// frontendRequestCounts reads temporalite's per-method request counters from /debug/vars.
+func frontendRequestCounts(ctx context.Context, address string) (map[string]float64, error) {
+	var vars struct {
+		Requests map[string]float64 `json:"temporalite.frontend.requests"`
+	}
+	if err := readDebugVars(ctx, address, &vars); err != nil {
+		return nil, err
+	}
+	return vars.Requests, nil
+}
+
+func takeSnapshot(ctx context.Context, svc workflowservice.WorkflowServiceClient, namespace, debugAddress string) (topSnapshot, error) {
+	snap := topSnapshot{taken: time.Now(), openByType: make(map[string]int)}
+	queueNames := make(map[string]struct{})
+
+	var pageToken []byte
+	for {
+		resp, err := svc.ListOpenWorkflowExecutions(ctx, &workflowservice.ListOpenWorkflowExecutionsRequest{
+			Namespace:       namespace,
+			MaximumPageSize: 1000,
+			NextPageToken:   pageToken,
+		})
+		if err != nil {
+			return snap, err
+		}
+		for _, info := range resp.GetExecutions() {
+			snap.open++
+			snap.openByType[info.GetType().GetName()]++
+			queueNames[info.GetTaskQueue()] = struct{}{}
+		}
+		pageToken = resp.GetNextPageToken()
+		if len(pageToken) == 0 {
+			break
+		}
+		if snap.open >= maxOpenWorkflows {
+			snap.truncated = true
+			break
+		}
+	}
+
+	for name := range queueNames {
+		backlog, err := temporalite.DescribeTaskQueueBacklog(ctx, svc, namespace, name)
+		if err != nil {
+			return snap, err
+		}
+		snap.queues = append(snap.queues, backlog)
+	}
+	sort.Slice(snap.queues, func(i, j int) bool { return snap.queues[i].Name < snap.queues[j].Name })
+
+	// Rates are optional: the debug endpoint is only reachable on the local machine
+	// and is on an unpredictable port when dynamic ports are enabled.
+	snap.requests, _ = frontendRequestCounts(ctx, debugAddress)
+	return snap, nil
+}
+
+func renderTop(namespace string, prev, cur topSnapshot) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "temporalite top - namespace %s - %s\n\n", namespace, cur.taken.Format("15:04:05"))
+
+	open := fmt.Sprintf("%d", cur.open)
+	if cur.truncated {
+		open += "+"
+	}
+	fmt.Fprintf(&b, "Open workflows: %s\n", open)
+	types := make([]string, 0, len(cur.openByType))
+	for t := range cur.openByType {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return cur.openByType[types[i]] > cur.openByType[types[j]] })
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	for _, t := range types {
+		fmt.Fprintf(w, "  %s\t%d\n", t, cur.openByType[t])
+	}
+	_ = w.Flush()
+
+	fmt.Fprintf(&b, "\nTask queues:\n")
+	w = tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "  NAME\tWORKFLOW BACKLOG\tACTIVITY BACKLOG\tWORKFLOW POLLERS\tACTIVITY POLLERS\tLAST POLL")
+	for _, q := range cur.queues {
+		var lastPollTime time.Time
+		for _, p := range append(q.WorkflowPollers, q.ActivityPollers...) {
+			if p.LastAccessTime.After(lastPollTime) {
+				lastPollTime = p.LastAccessTime
+			}
+		}
+		lastPoll := "never"
+		if !lastPollTime.IsZero() {
+			lastPoll = fmt.Sprintf("%s ago", time.Since(lastPollTime).Round(time.Second))
+		}
+		warn := ""
+		if q.Stalled() {
+			warn = "  <- no pollers"
+		}
+		fmt.Fprintf(w, "  %s\t%d\t%d\t%d\t%d\t%s%s\n", q.Name, q.WorkflowBacklog, q.ActivityBacklog, len(q.WorkflowPollers), len(q.ActivityPollers), lastPoll, warn)
+	}
+	_ = w.Flush()
+
+	fmt.Fprintf(&b, "\nRates (per second):\n")
+	if cur.requests == nil || prev.requests == nil {
+		fmt.Fprintf(&b, "  unavailable; is --%s the server's pprof address (--port + 201)?\n", debugAddressFlag)
+		return b.String()
+	}
+	elapsed := cur.taken.Sub(prev.taken).Seconds()
+	w = tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	for _, m := range rateMethods {
+		fmt.Fprintf(w, "  %s\t%.1f\n", m.label, (cur.requests[m.method]-prev.requests[m.method])/elapsed)
+	}
+	_ = w.Flush()
+	return b.String()
+}
+
+func topCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "top",
+		Usage:     "Show a live view of open workflows, task queue backlogs, and task rates",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			newAddressFlag(),
+			&cli.StringFlag{
+				Name:    namespaceFlag,
+				Aliases: []string{"n"},
+				Value:   "default",
+				Usage:   "namespace to show",
+			},
+			&cli.StringFlag{
+				Name:  debugAddressFlag,
+				Usage: "host:port of the server's pprof endpoint, used for task rates",
+				Value: fmt.Sprintf("127.0.0.1:%d", liteconfig.DefaultFrontendPort+201),
+			},
+			&cli.DurationFlag{
+				Name:  intervalFlag,
+				Usage: "refresh interval",
+				Value: 2 * time.Second,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			ctx, stop := signal.NotifyContext(c.Context, os.Interrupt)
+			defer stop()
+
+			conn, err := dialFrontend(c)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			svc := workflowservice.NewWorkflowServiceClient(conn)
+
+			namespace := c.String(namespaceFlag)
+			prev, err := takeSnapshot(ctx, svc, namespace, c.String(debugAddressFlag))
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: %v", err), 1)
+			}
+			ticker := time.NewTicker(c.Duration(intervalFlag))
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-ticker.C:
+				}
+				cur, err := takeSnapshot(ctx, svc, namespace, c.String(debugAddressFlag))
+				if ctx.Err() != nil {
+					return nil
+				}
+				// Clear the screen and move the cursor home before each frame.
+				fmt.Print("\033[H\033[2J")
+				if err != nil {
+					fmt.Printf("ERROR: %v\n", strings.TrimSpace(err.Error()))
+					continue
+				}
+				fmt.Print(renderTop(namespace, prev, cur))
+				prev = cur
+			}
+		},
+	}
+}
@@ -0,0 +1,122 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/DataDog/temporalite"
+	"github.com/DataDog/temporalite/internal/liteconfig"
+)
+
+// routingTraceRequest calls the /debug/routingtrace endpoint of the server at
+// address, decoding the response into v when it is not nil.
+func routingTraceRequest(ctx context.Context, method, address, namespace, workflowID string, v interface{}) error {
+	query := url.Values{"namespace": {namespace}, "workflow_id": {workflowID}}
+	req, err := http.NewRequestWithContext(ctx, method, "http://"+address+"/debug/routingtrace?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s /debug/routingtrace: %s", method, resp.Status)
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func formatRoutingEvent(e temporalite.RoutingEvent) string {
+	line := fmt.Sprintf("%s  %-25s %s", e.Time.Format("15:04:05.000"), e.Stage, e.Detail)
+	if e.Latency > 0 {
+		line += fmt.Sprintf(" (+%s)", e.Latency.Round(time.Millisecond))
+	}
+	return line
+}
+
+func traceCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "trace",
+		Usage:     "Follow a workflow's tasks through the server as they are dispatched and completed",
+		ArgsUsage: "WORKFLOW-ID",
+		Description: "Turns on routing tracing for the workflow ID on a running server and prints each workflow and " +
+			"activity task as it is delivered to a poller and completed, with the time it spent waiting. " +
+			"Tracing is turned off again on exit.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    namespaceFlag,
+				Aliases: []string{"n"},
+				Value:   "default",
+				Usage:   "namespace of the workflow",
+			},
+			&cli.StringFlag{
+				Name:  debugAddressFlag,
+				Usage: "host:port of the server's pprof endpoint",
+				Value: fmt.Sprintf("127.0.0.1:%d", liteconfig.DefaultFrontendPort+201),
+			},
+			&cli.DurationFlag{
+				Name:  intervalFlag,
+				Usage: "how often to check for new events",
+				Value: time.Second,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() != 1 {
+				return cli.Exit("ERROR: trace requires exactly one workflow ID", 1)
+			}
+			ctx, stop := signal.NotifyContext(c.Context, os.Interrupt)
+			defer stop()
+
+			address, namespace, workflowID := c.String(debugAddressFlag), c.String(namespaceFlag), c.Args().First()
+			if err := routingTraceRequest(ctx, http.MethodPost, address, namespace, workflowID, nil); err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: unable to start tracing; is --%s the server's pprof address? %v", debugAddressFlag, err), 1)
+			}
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				_ = routingTraceRequest(ctx, http.MethodDelete, address, namespace, workflowID, nil)
+			}()
+			fmt.Printf("Tracing workflow %s in namespace %s; press Ctrl-C to stop\n", workflowID, namespace)
+
+			ticker := time.NewTicker(c.Duration(intervalFlag))
+			defer ticker.Stop()
+			var last time.Time
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-ticker.C:
+				}
+				var events []temporalite.RoutingEvent
+				if err := routingTraceRequest(ctx, http.MethodGet, address, namespace, workflowID, &events); err != nil {
+					if ctx.Err() != nil {
+						return nil
+					}
+					return cli.Exit(fmt.Sprintf("ERROR: %v", err), 1)
+				}
+				for _, e := range events {
+					if e.Time.After(last) {
+						fmt.Println(formatRoutingEvent(e))
+						last = e.Time
+					}
+				}
+			}
+		},
+	}
+}
@@ -0,0 +1,234 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	uiserver "github.com/temporalio/ui-server/server"
+	uiconfig "github.com/temporalio/ui-server/server/config"
+	uiroutes "github.com/temporalio/ui-server/server/routes"
+	uirpc "github.com/temporalio/ui-server/server/rpc"
+	uiserveroptions "github.com/temporalio/ui-server/server/server_options"
+	"google.golang.org/grpc"
+)
+
+var forwardHeaders = []string{"X-Forwarded-For", "X-Forwarded-Host", "X-Forwarded-Proto", "X-Real-Ip"}
+
+type uiFrontendOptions struct {
+	// AssetPath is a directory holding a custom UI build to serve instead of
+	// the embedded UI, if any.
+	AssetPath string
+	// PublicPath is the path prefix the UI is served under, such as /temporal.
+	PublicPath string
+	// TrustedProxies are the networks whose forward headers are passed on to the UI server.
+	TrustedProxies []*net.IPNet
+}
+
+// uiFrontend serves the embedded UI or a custom UI build along with the
+// ui-server's HTTP API, adding features the ui-server lacks: a public path
+// prefix and filtering of untrusted forward headers.
+type uiFrontend struct {
+	// api serves the ui-server's API and auth routes in-process for a custom
+	// UI build.
+	api  *echo.Echo
+	conn *grpc.ClientConn
+	// embedded serves the embedded UI on a loopback port, as its assets are
+	// only served by a ui-server listening on its own, and requests are
+	// forwarded to it through proxy.
+	embedded *uiserver.Server
+	proxy    *httputil.ReverseProxy
+	opts     uiFrontendOptions
+	listener net.Listener
+	http     *http.Server
+}
+
+// newUIFrontend returns a UI server for cfg, the configuration the embedded UI
+// would otherwise have been served with, listening on cfg's address.
+func newUIFrontend(cfg uiconfig.Config, opts uiFrontendOptions) (*uiFrontend, error) {
+	if opts.AssetPath != "" {
+		if info, err := os.Stat(filepath.Join(opts.AssetPath, "index.html")); err != nil || info.IsDir() {
+			return nil, fmt.Errorf("%s does not contain a UI build: index.html not found", opts.AssetPath)
+		}
+	}
+	opts.PublicPath = strings.TrimSuffix(opts.PublicPath, "/")
+
+	l, err := net.Listen("tcp", net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)))
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.AssetPath == "" {
+		port, err := freeLoopbackPort()
+		if err != nil {
+			_ = l.Close()
+			return nil, err
+		}
+		embeddedCfg := cfg
+		embeddedCfg.Host, embeddedCfg.Port = "127.0.0.1", port
+		s := &uiFrontend{
+			embedded: uiserver.NewServer(uiserveroptions.WithConfig(&embeddedCfg)),
+			proxy:    httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: net.JoinHostPort(embeddedCfg.Host, strconv.Itoa(port))}),
+			opts:     opts,
+			listener: l,
+		}
+		s.http = &http.Server{Handler: s}
+		return s, nil
+	}
+
+	// Set up the routes as the ui-server does, minus the embedded UI.
+	api := echo.New()
+	api.Use(middleware.Recover())
+	api.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins: cfg.CORS.AllowOrigins,
+		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept},
+	}))
+	api.Use(session.Middleware(sessions.NewCookieStore(
+		securecookie.GenerateRandomKey(32),
+		securecookie.GenerateRandomKey(32),
+	)))
+	conn := uirpc.CreateFrontendGRPCConnection(cfg.TemporalGRPCAddress)
+	_ = uiroutes.SetAPIRoutes(api, &cfg, conn)
+	uiroutes.SetAuthRoutes(api, &cfg.Auth)
+
+	s := &uiFrontend{
+		api:      api,
+		conn:     conn,
+		opts:     opts,
+		listener: l,
+	}
+	s.http = &http.Server{Handler: s}
+	return s, nil
+}
+
+func (s *uiFrontend) trusted(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	for _, network := range s.opts.TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *uiFrontend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.trusted(r.RemoteAddr) {
+		for _, h := range forwardHeaders {
+			r.Header.Del(h)
+		}
+	}
+
+	if prefix := s.opts.PublicPath; prefix != "" {
+		if r.URL.Path == prefix {
+			http.Redirect(w, r, prefix+"/", http.StatusMovedPermanently)
+			return
+		}
+		switch {
+		case strings.HasPrefix(r.URL.Path, prefix+"/"):
+			r.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+			r.URL.RawPath = ""
+		case s.embedded == nil:
+			http.NotFound(w, r)
+			return
+		}
+		// The embedded UI requests its assets and API from the root, so
+		// those requests are served as is.
+	}
+
+	if s.embedded != nil {
+		s.proxy.ServeHTTP(w, r)
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, "/api/") || strings.HasPrefix(r.URL.Path, "/auth/") {
+		s.api.ServeHTTP(w, r)
+		return
+	}
+	s.serveAsset(w, r)
+}
+
+// serveAsset serves the requested file, falling back to index.html so the UI's
+// client-side routes resolve.
+func (s *uiFrontend) serveAsset(w http.ResponseWriter, r *http.Request) {
+	name := filepath.Join(s.opts.AssetPath, filepath.FromSlash(path.Clean("/"+r.URL.Path)))
+	if info, err := os.Stat(name); err != nil || info.IsDir() {
+		name = filepath.Join(s.opts.AssetPath, "index.html")
+	}
+	http.ServeFile(w, r, name)
+}
+
+func (s *uiFrontend) Start() error {
+	if s.embedded != nil {
+		// The ui-server exits the process when it stops serving, so it is
+		// never stopped and keeps its loopback port until the process exits.
+		go func() { _ = s.embedded.Start() }()
+	}
+	if err := s.http.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *uiFrontend) Stop() {
+	_ = s.http.Close()
+	_ = s.listener.Close()
+	if s.conn != nil {
+		_ = s.conn.Close()
+	}
+}
+
+// freeLoopbackPort returns a port on the loopback interface that is free to
+// listen on.
+func freeLoopbackPort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// parseTrustedProxies parses IP addresses and CIDR ranges.
+func parseTrustedProxies(input []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, s := range input {
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", s)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
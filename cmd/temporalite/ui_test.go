@@ -0,0 +1,183 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	uiconfig "github.com/temporalio/ui-server/server/config"
+	"github.com/urfave/cli/v2"
+)
+
+func TestUIFrontend(t *testing.T) {
+	assets := t.TempDir()
+	if err := os.WriteFile(filepath.Join(assets, "index.html"), []byte("index"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(assets, "app.js"), []byte("app"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := uiconfig.Config{Host: "127.0.0.1", TemporalGRPCAddress: "127.0.0.1:7233"}
+	ui, err := newUIFrontend(cfg, uiFrontendOptions{AssetPath: assets, PublicPath: "/temporal/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	errCh := make(chan error, 1)
+	go func() { errCh <- ui.Start() }()
+	defer func() {
+		ui.Stop()
+		if err := <-errCh; err != nil {
+			t.Errorf("Start() = %v", err)
+		}
+	}()
+	addr := ui.listener.Addr().(*net.TCPAddr)
+
+	tests := []struct {
+		path string
+		want string
+		code int
+	}{
+		{path: "/temporal/", want: "index", code: http.StatusOK},
+		{path: "/temporal/app.js", want: "app", code: http.StatusOK},
+		{path: "/temporal/namespaces/default", want: "index", code: http.StatusOK},
+		{path: "/app.js", code: http.StatusNotFound},
+		{path: "/temporal/api/v1/settings", code: http.StatusOK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			resp, err := http.Get("http://" + addr.String() + tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tc.code {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tc.code)
+			}
+			if tc.want != "" && string(body) != tc.want {
+				t.Errorf("body = %q, want %q", body, tc.want)
+			}
+		})
+	}
+
+	cfg.Port = addr.Port
+	if _, err := newUIFrontend(cfg, uiFrontendOptions{AssetPath: assets}); !errors.Is(err, syscall.EADDRINUSE) {
+		t.Errorf("newUIFrontend() on a port in use = %v, want EADDRINUSE", err)
+	}
+}
+
+func TestUIFrontendEmbedded(t *testing.T) {
+	cfg := uiconfig.Config{Host: "127.0.0.1", TemporalGRPCAddress: "127.0.0.1:7233", EnableUI: true}
+	ui, err := newUIFrontend(cfg, uiFrontendOptions{PublicPath: "/temporal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	errCh := make(chan error, 1)
+	go func() { errCh <- ui.Start() }()
+	defer func() {
+		ui.Stop()
+		if err := <-errCh; err != nil {
+			t.Errorf("Start() = %v", err)
+		}
+	}()
+	base := "http://" + ui.listener.Addr().String()
+
+	// The embedded ui-server starts listening in the background.
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		resp, err := http.Get(base + "/temporal/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			if !strings.Contains(string(body), "<html") {
+				t.Errorf("/temporal/ served %q, want the embedded UI", body)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("/temporal/ status = %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// The embedded UI requests its assets from the root.
+	resp, err := http.Get(base + "/favicon.ico")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("/favicon.ico status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestUIFlagsWithUIDisabled(t *testing.T) {
+	var start *cli.Command
+	for _, cmd := range buildCLI().Commands {
+		if cmd.Name == "start" {
+			start = cmd
+		}
+	}
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "API keys", args: []string{"--api-key-file", "keys.txt", "--ui-trusted-proxy", "127.0.0.1"}},
+		{name: "TLS", args: []string{"--tls-cert", "cert.pem", "--tls-key", "key.pem", "--tls-client-ca", "ca.pem", "--ui-public-path", "/temporal"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := runCommand(start, append([]string{"start"}, tc.args...)...)
+			var exit cli.ExitCoder
+			if !errors.As(err, &exit) || exit.ExitCode() != exitConfigError || !strings.Contains(err.Error(), "disables the web UI") {
+				t.Errorf("start error = %v, want a config error for the UI flag", err)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "IPv4 address", input: []string{"10.0.0.1"}, want: []string{"10.0.0.1/32"}},
+		{name: "IPv6 address", input: []string{"::1"}, want: []string{"::1/128"}},
+		{name: "CIDR", input: []string{"10.0.0.7/8", "fd00::/8"}, want: []string{"10.0.0.0/8", "fd00::/8"}},
+		{name: "invalid address", input: []string{"proxy.local"}, wantErr: true},
+		{name: "invalid CIDR", input: []string{"10.0.0.0/33"}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			networks, err := parseTrustedProxies(tc.input)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseTrustedProxies() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			var got []string
+			for _, network := range networks {
+				got = append(got, network.String())
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("parseTrustedProxies() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
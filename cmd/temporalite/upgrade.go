@@ -0,0 +1,161 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/DataDog/temporalite/internal/sqlitedb"
+	"github.com/DataDog/temporalite/temporalitecmd"
+)
+
+const binaryFlag = "binary"
+
+// flagValue returns the value of the flag with the given long or short name in
+// command line args, accepting both "--name value" and "--name=value" forms.
+func flagValue(args []string, long, short string) (string, bool) {
+	for i, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)
+		if parts[0] != long && parts[0] != short {
+			continue
+		}
+		if len(parts) == 2 {
+			return parts[1], true
+		}
+		if i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+// verifyUpgrade starts binary against a copy of the database at dbPath,
+// returning an error with the server's output if it fails to start.
+func verifyUpgrade(ctx context.Context, binary, dbPath string, timeout time.Duration) error {
+	dir, err := os.MkdirTemp("", "temporalite-upgrade-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	dbCopy, err := copyDatabase(dbPath, dir)
+	if err != nil {
+		return err
+	}
+
+	var output bytes.Buffer
+	s, err := temporalitecmd.Start(ctx,
+		temporalitecmd.WithBinary(binary),
+		temporalitecmd.WithArgs("--filename", dbCopy),
+		temporalitecmd.WithOutput(&output, &output),
+		temporalitecmd.WithStartTimeout(timeout),
+	)
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, output.String())
+	}
+	s.Stop()
+	return nil
+}
+
+func upgradeRestartCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "upgrade-restart",
+		Usage:     "Replace a running server with a new temporalite binary, keeping its database",
+		ArgsUsage: "[-- START-ARGS...]",
+		Description: "Checks that the new binary starts against a copy of the database, stops the running server, " +
+			"checkpoints and backs up the database, and then runs the new binary in place of this command " +
+			"with the running server's arguments. On Linux the arguments are read from the running process; " +
+			"elsewhere pass them after --.",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:     pidFlag,
+				Usage:    "process ID of the running server",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     binaryFlag,
+				Usage:    "path to the new temporalite binary",
+				Required: true,
+			},
+			&cli.DurationFlag{
+				Name:  timeoutFlag,
+				Usage: "how long to wait for servers to start and stop",
+				Value: time.Minute,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			pid := c.Int(pidFlag)
+			args := c.Args().Slice()
+			dir, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				var cmdline []string
+				if cmdline, dir, err = processCommandLine(pid); err != nil {
+					return cli.Exit(fmt.Sprintf("ERROR: unable to read arguments of process %d, pass them after --: %v", pid, err), 1)
+				}
+				args = cmdline[1:]
+			}
+			if len(args) == 0 || args[0] != "start" {
+				return cli.Exit("ERROR: upgrade-restart only supports servers run with temporalite start", 1)
+			}
+			if containsString(args, "--"+ephemeralFlag) {
+				return cli.Exit("ERROR: ephemeral servers have no state to preserve; restart them with the new binary directly", 1)
+			}
+			dbPath, ok := flagValue(args, dbPathFlag, "f")
+			if !ok {
+				dbPath = defaultCfg.DatabaseFilePath
+			}
+			if !filepath.IsAbs(dbPath) {
+				dbPath = filepath.Join(dir, dbPath)
+			}
+
+			version, err := exec.Command(c.String(binaryFlag), "--version").Output()
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: unable to run %s: %v", c.String(binaryFlag), err), 1)
+			}
+			fmt.Printf("Upgrading to %s", version)
+
+			// Fold the write-ahead log into the database so the copy is small and current.
+			if _, err := sqlitedb.Checkpoint(c.Context, dbPath); err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: unable to checkpoint database: %v", err), 1)
+			}
+			fmt.Println("Starting new binary against a copy of the database")
+			if err := verifyUpgrade(c.Context, c.String(binaryFlag), dbPath, c.Duration(timeoutFlag)); err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: new binary failed to start; the running server was left untouched: %v", err), 1)
+			}
+
+			fmt.Printf("Stopping server %d\n", pid)
+			if err := stopProcess(pid, c.Duration(timeoutFlag)); err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: unable to stop server: %v", err), 1)
+			}
+			if _, err := sqlitedb.Checkpoint(c.Context, dbPath); err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: unable to checkpoint database: %v", err), 1)
+			}
+			backup := dbPath + ".pre-upgrade"
+			if err := copyFile(dbPath, backup); err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: unable to back up database: %v", err), 1)
+			}
+			fmt.Printf("Backed up database to %s\n", backup)
+
+			if err := execBinary(c.String(binaryFlag), dir, args); err != nil {
+				return cli.Exit(fmt.Sprintf("ERROR: unable to run new binary: %v", err), 1)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,33 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import "testing"
+
+func TestFlagValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		want   string
+		wantOK bool
+	}{
+		{name: "long with space", args: []string{"start", "--filename", "a.db"}, want: "a.db", wantOK: true},
+		{name: "long with equals", args: []string{"start", "--filename=a.db"}, want: "a.db", wantOK: true},
+		{name: "short", args: []string{"start", "-f", "a.db"}, want: "a.db", wantOK: true},
+		{name: "short with equals", args: []string{"start", "-f=a.db"}, want: "a.db", wantOK: true},
+		{name: "first wins", args: []string{"--filename", "a.db", "--filename", "b.db"}, want: "a.db", wantOK: true},
+		{name: "other flag", args: []string{"start", "--filename-prefix", "a"}},
+		{name: "value not a flag", args: []string{"start", "filename", "a.db"}},
+		{name: "missing value", args: []string{"start", "--filename"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := flagValue(tc.args, "filename", "f")
+			if got != tc.want || ok != tc.wantOK {
+				t.Errorf("flagValue() = %q, %v, want %q, %v", got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
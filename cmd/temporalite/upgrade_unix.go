@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// processCommandLine returns the command line and working directory of a
+// process. It is only available on systems with a /proc filesystem.
+func processCommandLine(pid int) ([]string, string, error) {
+	proc := "/proc/" + strconv.Itoa(pid)
+	data, err := os.ReadFile(proc + "/cmdline")
+	if err != nil {
+		return nil, "", err
+	}
+	dir, err := os.Readlink(proc + "/cwd")
+	if err != nil {
+		return nil, "", err
+	}
+	var args []string
+	for _, arg := range bytes.Split(bytes.TrimRight(data, "\x00"), []byte{0}) {
+		args = append(args, string(arg))
+	}
+	return args, dir, nil
+}
+
+// stopProcess interrupts a process and waits for it to exit.
+func stopProcess(pid int, timeout time.Duration) error {
+	if err := syscall.Kill(pid, syscall.SIGINT); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if err := syscall.Kill(pid, 0); err == syscall.ESRCH {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("process %d still running after %s", pid, timeout)
+}
+
+// execBinary replaces the current process with binary run in dir, keeping the environment.
+func execBinary(binary, dir string, args []string) error {
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return err
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return err
+	}
+	if err := os.Chdir(dir); err != nil {
+		return err
+	}
+	return syscall.Exec(path, append([]string{path}, args...), os.Environ())
+}
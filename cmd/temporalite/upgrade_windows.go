@@ -0,0 +1,27 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+var errUnsupported = errors.New("not supported on windows")
+
+func processCommandLine(int) ([]string, string, error) {
+	return nil, "", errUnsupported
+}
+
+func stopProcess(int, time.Duration) error {
+	return errUnsupported
+}
+
+func execBinary(string, string, []string) error {
+	return errUnsupported
+}
@@ -0,0 +1,31 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/DataDog/temporalite"
+)
+
+const apiUsageFlag = "api-usage-summary"
+
+// printAPIUsage writes a table of the frontend methods called on s.
+func printAPIUsage(w io.Writer, s *temporalite.Server) {
+	usage := s.APIUsage()
+	if len(usage) == 0 {
+		fmt.Fprintln(w, "No frontend API calls were made")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	defer tw.Flush()
+	fmt.Fprintln(tw, "CALLS\tERRORS\t METHOD")
+	for _, u := range usage {
+		fmt.Fprintf(tw, "%d\t%d\t %s\n", u.Calls, u.Errors, strings.TrimPrefix(u.Method, workflowServiceMethod("")))
+	}
+}
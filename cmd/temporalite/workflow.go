@@ -0,0 +1,196 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/converter"
+)
+
+const (
+	queryFlag       = "query"
+	reasonFlag      = "reason"
+	yesFlag         = "yes"
+	signalNameFlag  = "name"
+	signalInputFlag = "input"
+)
+
+// listRunningWorkflows returns the running workflows matching a visibility query.
+//
+// SQLite visibility supports filtering on one of WorkflowId, WorkflowType, or
+// ExecutionStatus, optionally combined with a StartTime range.
+func listRunningWorkflows(ctx context.Context, svc workflowservice.WorkflowServiceClient, namespace, query string) ([]*commonpb.WorkflowExecution, error) {
+	var (
+		executions []*commonpb.WorkflowExecution
+		token      []byte
+	)
+	for {
+		resp, err := svc.ListWorkflowExecutions(ctx, &workflowservice.ListWorkflowExecutionsRequest{
+			Namespace:     namespace,
+			PageSize:      1000,
+			NextPageToken: token,
+			Query:         query,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range resp.GetExecutions() {
+			if info.GetStatus() == enumspb.WORKFLOW_EXECUTION_STATUS_RUNNING {
+				executions = append(executions, info.GetExecution())
+			}
+		}
+		if token = resp.GetNextPageToken(); len(token) == 0 {
+			return executions, nil
+		}
+	}
+}
+
+// confirm asks the user to confirm an action on stdin unless --yes was passed.
+func confirm(c *cli.Context, prompt string) bool {
+	if c.Bool(yesFlag) {
+		return true
+	}
+	fmt.Printf("%s [y/N] ", prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// forEachRunningWorkflow lists the running workflows matching the query flag,
+// asks for confirmation, and applies fn to each, reporting failures as it goes.
+func forEachRunningWorkflow(c *cli.Context, verb string, fn func(svc workflowservice.WorkflowServiceClient, execution *commonpb.WorkflowExecution) error) error {
+	conn, err := dialFrontend(c)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	svc := workflowservice.NewWorkflowServiceClient(conn)
+
+	executions, err := listRunningWorkflows(c.Context, svc, c.String(namespaceFlag), c.String(queryFlag))
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("ERROR: unable to list workflows: %v", err), 1)
+	}
+	if len(executions) == 0 {
+		fmt.Println("No running workflows match")
+		return nil
+	}
+	if !confirm(c, fmt.Sprintf("%s %d running workflows in namespace %q?", verb, len(executions), c.String(namespaceFlag))) {
+		return cli.Exit("Aborted", 1)
+	}
+
+	var failed int
+	for _, execution := range executions {
+		if err := fn(svc, execution); err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "%s: %v\n", execution.GetWorkflowId(), err)
+		}
+	}
+	fmt.Printf("Done with %d of %d workflows\n", len(executions)-failed, len(executions))
+	if failed > 0 {
+		return cli.Exit(fmt.Sprintf("ERROR: %d workflows failed", failed), 1)
+	}
+	return nil
+}
+
+func workflowCommand() *cli.Command {
+	batchFlags := func(flags ...cli.Flag) []cli.Flag {
+		return append([]cli.Flag{
+			newAddressFlag(),
+			&cli.StringFlag{
+				Name:    namespaceFlag,
+				Aliases: []string{"n"},
+				Value:   "default",
+				Usage:   "namespace of the workflows",
+			},
+			&cli.StringFlag{
+				Name:    queryFlag,
+				Aliases: []string{"q"},
+				Usage:   "visibility query selecting workflows, eg. \"WorkflowType='MyWorkflow'\"; all running workflows when unset",
+			},
+			&cli.BoolFlag{
+				Name:    yesFlag,
+				Aliases: []string{"y"},
+				Usage:   "don't ask for confirmation",
+			},
+		}, flags...)
+	}
+
+	return &cli.Command{
+		Name:  "workflow",
+		Usage: "Operate on workflows of a running server in bulk",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "terminate-all",
+				Usage:     "Terminate all running workflows matching a query",
+				ArgsUsage: " ",
+				Flags: batchFlags(&cli.StringFlag{
+					Name:  reasonFlag,
+					Usage: "termination reason recorded in each workflow's history",
+					Value: "terminated by temporalite workflow terminate-all",
+				}),
+				Action: func(c *cli.Context) error {
+					return forEachRunningWorkflow(c, "Terminate", func(svc workflowservice.WorkflowServiceClient, execution *commonpb.WorkflowExecution) error {
+						_, err := svc.TerminateWorkflowExecution(c.Context, &workflowservice.TerminateWorkflowExecutionRequest{
+							Namespace:         c.String(namespaceFlag),
+							WorkflowExecution: execution,
+							Reason:            c.String(reasonFlag),
+							Identity:          "temporalite-cli",
+						})
+						return err
+					})
+				},
+			},
+			{
+				Name:      "signal-all",
+				Usage:     "Signal all running workflows matching a query",
+				ArgsUsage: " ",
+				Flags: batchFlags(
+					&cli.StringFlag{
+						Name:     signalNameFlag,
+						Usage:    "signal name",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  signalInputFlag,
+						Usage: "signal input as `JSON`",
+					},
+				),
+				Action: func(c *cli.Context) error {
+					var input *commonpb.Payloads
+					if c.IsSet(signalInputFlag) {
+						var value interface{}
+						if err := json.Unmarshal([]byte(c.String(signalInputFlag)), &value); err != nil {
+							return cli.Exit(fmt.Sprintf("bad value %q passed for flag %q: %v", c.String(signalInputFlag), signalInputFlag, err), 1)
+						}
+						var err error
+						if input, err = converter.GetDefaultDataConverter().ToPayloads(value); err != nil {
+							return err
+						}
+					}
+					return forEachRunningWorkflow(c, "Signal", func(svc workflowservice.WorkflowServiceClient, execution *commonpb.WorkflowExecution) error {
+						_, err := svc.SignalWorkflowExecution(c.Context, &workflowservice.SignalWorkflowExecutionRequest{
+							Namespace:         c.String(namespaceFlag),
+							WorkflowExecution: execution,
+							SignalName:        c.String(signalNameFlag),
+							Input:             input,
+							Identity:          "temporalite-cli",
+						})
+						return err
+					})
+				},
+			},
+		},
+	}
+}
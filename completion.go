@@ -0,0 +1,159 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"time"
+
+	filterpb "go.temporal.io/api/filter/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/common"
+
+	"github.com/DataDog/temporalite/internal/liteconfig"
+)
+
+const completionPollInterval = 250 * time.Millisecond
+
+// WorkflowClosedEvent describes a workflow execution that has closed for any
+// reason, including completion, failure, cancellation, termination, timeout,
+// and continue-as-new.
+type WorkflowClosedEvent = liteconfig.WorkflowClosedEvent
+
+// watchCompletions polls visibility for closed workflows in every user namespace
+// and invokes each listener once per closed run until ctx is done.
+func (s *Server) watchCompletions(ctx context.Context, listeners []func(WorkflowClosedEvent)) {
+	var (
+		// Visibility records are written asynchronously, so each poll overlaps the
+		// last to pick up records with close times slightly in the past.
+		overlap   = 5 * time.Second
+		watermark = time.Now()
+		seen      = make(map[string]time.Time)
+		ticker    = time.NewTicker(completionPollInterval)
+	)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		svc, err := s.workflowService()
+		if err != nil {
+			continue
+		}
+		pollStart := time.Now()
+		events, err := listClosedSince(ctx, svc, watermark.Add(-overlap))
+		if err != nil {
+			continue
+		}
+		for _, ev := range events {
+			if _, ok := seen[ev.RunID]; ok {
+				continue
+			}
+			seen[ev.RunID] = ev.CloseTime
+			for _, listener := range listeners {
+				listener(ev)
+			}
+		}
+
+		watermark = pollStart
+		for runID, closeTime := range seen {
+			if closeTime.Before(watermark.Add(-2 * overlap)) {
+				delete(seen, runID)
+			}
+		}
+	}
+}
+
+func listClosedSince(ctx context.Context, svc workflowservice.WorkflowServiceClient, since time.Time) ([]WorkflowClosedEvent, error) {
+	namespaces, err := listNamespaces(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []WorkflowClosedEvent
+	for _, ns := range namespaces {
+		closed, err := listClosedInNamespace(ctx, svc, ns, since, time.Now().Add(time.Minute))
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, closed...)
+	}
+	return events, nil
+}
+
+// listClosedInNamespace returns the runs in namespace that closed between since
+// and until.
+func listClosedInNamespace(ctx context.Context, svc workflowservice.WorkflowServiceClient, namespace string, since, until time.Time) ([]WorkflowClosedEvent, error) {
+	var (
+		events []WorkflowClosedEvent
+		token  []byte
+	)
+	for {
+		resp, err := svc.ListClosedWorkflowExecutions(ctx, &workflowservice.ListClosedWorkflowExecutionsRequest{
+			Namespace:       namespace,
+			MaximumPageSize: 1000,
+			NextPageToken:   token,
+			StartTimeFilter: &filterpb.StartTimeFilter{
+				EarliestTime: &since,
+				LatestTime:   &until,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range resp.GetExecutions() {
+			events = append(events, WorkflowClosedEvent{
+				Namespace:     namespace,
+				WorkflowID:    info.GetExecution().GetWorkflowId(),
+				RunID:         info.GetExecution().GetRunId(),
+				WorkflowType:  info.GetType().GetName(),
+				Status:        info.GetStatus(),
+				StartTime:     timeValue(info.GetStartTime()),
+				CloseTime:     timeValue(info.GetCloseTime()),
+				HistoryLength: info.GetHistoryLength(),
+			})
+		}
+		if token = resp.GetNextPageToken(); len(token) == 0 {
+			return events, nil
+		}
+	}
+}
+
+// listNamespaces returns the names of all namespaces except Temporal's system namespace.
+func listNamespaces(ctx context.Context, svc workflowservice.WorkflowServiceClient) ([]string, error) {
+	var (
+		names []string
+		token []byte
+	)
+	for {
+		resp, err := svc.ListNamespaces(ctx, &workflowservice.ListNamespacesRequest{PageSize: 100, NextPageToken: token})
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range resp.GetNamespaces() {
+			if name := ns.GetNamespaceInfo().GetName(); name != common.SystemLocalNamespace {
+				names = append(names, name)
+			}
+		}
+		if token = resp.GetNextPageToken(); len(token) == 0 {
+			return names, nil
+		}
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func timeValue(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
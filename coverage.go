@@ -0,0 +1,138 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+
+	"go.temporal.io/api/workflowservice/v1"
+	"google.golang.org/grpc"
+)
+
+// CoverageReport lists the workflow and activity types executed by workers.
+type CoverageReport struct {
+	Workflows  []CoveredType `json:"workflows"`
+	Activities []CoveredType `json:"activities"`
+}
+
+// CoveredType is a workflow or activity type executed in a namespace, with
+// the number of workflow runs or activity tasks dispatched to workers.
+type CoveredType struct {
+	Namespace string `json:"namespace"`
+	Type      string `json:"type"`
+	Count     int    `json:"count"`
+}
+
+type coverageKey struct {
+	namespace string
+	typeName  string
+}
+
+// coverageRecorder records the types of tasks handed to workers.
+type coverageRecorder struct {
+	path string
+
+	mu         sync.Mutex
+	workflows  map[coverageKey]map[string]struct{}
+	activities map[coverageKey]int
+}
+
+// coverageFileMu serializes updates to coverage reports by servers in one process.
+var coverageFileMu sync.Mutex
+
+func newCoverageRecorder(path string) *coverageRecorder {
+	return &coverageRecorder{
+		path:       path,
+		workflows:  make(map[coverageKey]map[string]struct{}),
+		activities: make(map[coverageKey]int),
+	}
+}
+
+func (r *coverageRecorder) Intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	switch resp := resp.(type) {
+	case *workflowservice.PollWorkflowTaskQueueResponse:
+		if name := resp.GetWorkflowType().GetName(); name != "" {
+			key := coverageKey{req.(*workflowservice.PollWorkflowTaskQueueRequest).GetNamespace(), name}
+			r.mu.Lock()
+			if r.workflows[key] == nil {
+				r.workflows[key] = make(map[string]struct{})
+			}
+			r.workflows[key][resp.GetWorkflowExecution().GetRunId()] = struct{}{}
+			r.mu.Unlock()
+		}
+	case *workflowservice.PollActivityTaskQueueResponse:
+		if name := resp.GetActivityType().GetName(); name != "" {
+			key := coverageKey{req.(*workflowservice.PollActivityTaskQueueRequest).GetNamespace(), name}
+			r.mu.Lock()
+			r.activities[key]++
+			r.mu.Unlock()
+		}
+	}
+	return resp, nil
+}
+
+// Write adds the recorded types to the report file, keeping the counts of
+// servers that wrote it before.
+func (r *coverageRecorder) Write() error {
+	coverageFileMu.Lock()
+	defer coverageFileMu.Unlock()
+
+	workflows := make(map[coverageKey]int)
+	activities := make(map[coverageKey]int)
+	data, err := os.ReadFile(r.path)
+	if err == nil {
+		var existing CoverageReport
+		if err := json.Unmarshal(data, &existing); err != nil {
+			return err
+		}
+		for _, t := range existing.Workflows {
+			workflows[coverageKey{t.Namespace, t.Type}] += t.Count
+		}
+		for _, t := range existing.Activities {
+			activities[coverageKey{t.Namespace, t.Type}] += t.Count
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	r.mu.Lock()
+	for key, runs := range r.workflows {
+		workflows[key] += len(runs)
+	}
+	for key, count := range r.activities {
+		activities[key] += count
+	}
+	r.mu.Unlock()
+
+	report := CoverageReport{Workflows: coveredTypes(workflows), Activities: coveredTypes(activities)}
+	if data, err = json.MarshalIndent(report, "", "  "); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(data, '\n'), 0644)
+}
+
+func coveredTypes(counts map[coverageKey]int) []CoveredType {
+	types := make([]CoveredType, 0, len(counts))
+	for key, count := range counts {
+		types = append(types, CoveredType{Namespace: key.namespace, Type: key.typeName, Count: count})
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if types[i].Namespace != types[j].Namespace {
+			return types[i].Namespace < types[j].Namespace
+		}
+		return types[i].Type < types[j].Type
+	})
+	return types
+}
@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.temporal.io/server/common/log/tag"
+
+	"github.com/DataDog/temporalite/internal/sqlitedb"
+)
+
+const (
+	databaseSizeCheckInterval = time.Minute
+	// largestNamespacesReported is how many namespaces a size warning lists.
+	largestNamespacesReported = 3
+	// largestNamespacesTimeout bounds the scan of every history node in the
+	// database that finds the largest namespaces. Warnings are logged without
+	// them if it takes longer.
+	largestNamespacesTimeout = 10 * time.Second
+	// databaseBytesGauge is the metric reporting the size of the database file.
+	databaseBytesGauge = "temporalite_database_bytes"
+)
+
+// watchDatabaseSize publishes the size of the database file every minute, on
+// /debug/vars and as a gauge with the server's metrics, and logs a warning, listing the largest namespaces, the first time the size
+// passes each of thresholds. A threshold is warned about again if the size
+// drops back below it, for example after old workflows are deleted.
+func (s *Server) watchDatabaseSize(ctx context.Context, thresholds []int64) {
+	path := s.config.DatabaseFilePath
+	thresholds = append([]int64(nil), thresholds...)
+	sort.Slice(thresholds, func(i, j int) bool { return thresholds[i] < thresholds[j] })
+	defer databaseBytes.Delete(path)
+
+	ticker := time.NewTicker(databaseSizeCheckInterval)
+	defer ticker.Stop()
+
+	// passed is the number of thresholds the size was last seen to exceed.
+	var passed int
+	for {
+		size, err := sqlitedb.FileSize(path)
+		if err == nil {
+			v := new(expvar.Int)
+			v.Set(size)
+			databaseBytes.Set(path, v)
+			if s.metrics != nil {
+				s.metrics.UserScope().UpdateGauge(databaseBytesGauge, float64(size))
+			}
+			exceeded := sort.Search(len(thresholds), func(i int) bool { return thresholds[i] >= size })
+			if exceeded > passed {
+				s.warnDatabaseSize(ctx, size, thresholds[exceeded-1])
+			}
+			passed = exceeded
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) warnDatabaseSize(ctx context.Context, size, threshold int64) {
+	tags := []tag.Tag{
+		tag.NewStringTag("database", s.config.DatabaseFilePath),
+		tag.NewInt64("database-bytes", size),
+		tag.NewInt64("threshold-bytes", threshold),
+	}
+	ctx, cancel := context.WithTimeout(ctx, largestNamespacesTimeout)
+	defer cancel()
+	if largest, err := sqlitedb.LargestNamespaces(ctx, s.config.DatabaseFilePath, largestNamespacesReported); err == nil {
+		namespaces := make([]string, 0, len(largest))
+		for _, ns := range largest {
+			namespaces = append(namespaces, fmt.Sprintf("%s=%d", ns.Namespace, ns.HistoryBytes))
+		}
+		tags = append(tags, tag.NewStringTag("largest-namespaces-history-bytes", strings.Join(namespaces, ",")))
+	}
+	s.logger.Warn("Database has grown beyond size threshold; delete or terminate unneeded workflows, "+
+		"or shorten namespace retention", tags...)
+}
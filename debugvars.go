@@ -0,0 +1,120 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.temporal.io/server/common/headers"
+)
+
+// Importing expvar registers /debug/vars on http.DefaultServeMux, which upstream
+// serves on the pprof port alongside /debug/pprof. Temporalite's own debug
+// handlers are served by DebugHandler instead.
+var (
+	// databaseBytes is the size of each running server's database file, by path.
+	databaseBytes = expvar.NewMap("temporalite.database.bytes")
+
+	runningServersMu sync.Mutex
+	runningServers   = map[*Server]time.Time{}
+)
+
+func init() {
+	// Request counts are summed from the running servers' APIUsage.
+	expvar.Publish("temporalite.frontend.requests", expvar.Func(func() interface{} {
+		return frontendCounts(func(u APIUsage) int64 { return u.Calls })
+	}))
+	expvar.Publish("temporalite.frontend.errors", expvar.Func(func() interface{} {
+		return frontendCounts(func(u APIUsage) int64 { return u.Errors })
+	}))
+	expvar.Publish("temporalite.servers", expvar.Func(func() interface{} {
+		runningServersMu.Lock()
+		defer runningServersMu.Unlock()
+
+		servers := make([]map[string]interface{}, 0, len(runningServers))
+		for s, started := range runningServers {
+			servers = append(servers, map[string]interface{}{
+				"frontend":       s.frontendHostPort,
+				"version":        headers.ServerVersion,
+				"ephemeral":      s.config.Ephemeral,
+				"database":       s.config.DatabaseFilePath,
+				"uptime_seconds": int64(time.Since(started).Seconds()),
+			})
+		}
+		return servers
+	}))
+}
+
+// DebugHandler serves temporalite's debug endpoints, such as /debug/taskqueue
+// and /debug/pause, for the servers running in the process.
+//
+// Some of them change how servers run, so unlike /debug/vars they are not
+// registered on http.DefaultServeMux. The temporalite command serves them on
+// the pprof port with:
+//
+//	http.Handle("/debug/", temporalite.DebugHandler())
+func DebugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/taskqueue", serveTaskQueue)
+	mux.HandleFunc("/debug/startconflict", serveStartConflict)
+	mux.HandleFunc("/debug/routingtrace", serveRoutingTrace)
+	mux.HandleFunc("/debug/retention", serveRetention)
+	mux.HandleFunc("/debug/summary", serveSummary)
+	mux.HandleFunc("/debug/pause", servePause)
+	mux.HandleFunc("/debug/resume", servePause)
+	return mux
+}
+
+func trackRunningServer(s *Server, running bool) {
+	runningServersMu.Lock()
+	defer runningServersMu.Unlock()
+	if running {
+		runningServers[s] = time.Now()
+	} else {
+		delete(runningServers, s)
+	}
+}
+
+// debugServer returns the running server a debug request is for. When several
+// servers run in one process, frontend selects one by its host:port.
+func debugServer(frontend string) (*Server, error) {
+	runningServersMu.Lock()
+	defer runningServersMu.Unlock()
+	var candidates []*Server
+	for s := range runningServers {
+		if frontend == "" || frontend == s.frontendHostPort {
+			candidates = append(candidates, s)
+		}
+	}
+	if len(candidates) != 1 {
+		return nil, fmt.Errorf("%d servers match; pass the frontend parameter to select one", len(candidates))
+	}
+	return candidates[0], nil
+}
+
+// frontendCounts sums count over the frontend methods called on every running
+// server, by method, omitting methods it is zero for.
+func frontendCounts(count func(APIUsage) int64) map[string]int64 {
+	runningServersMu.Lock()
+	servers := make([]*Server, 0, len(runningServers))
+	for s := range runningServers {
+		servers = append(servers, s)
+	}
+	runningServersMu.Unlock()
+
+	counts := make(map[string]int64)
+	for _, s := range servers {
+		for _, u := range s.APIUsage() {
+			if n := count(u); n > 0 {
+				counts[u.Method] += n
+			}
+		}
+	}
+	return counts
+}
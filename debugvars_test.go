@@ -0,0 +1,48 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDebugHandler(t *testing.T) {
+	s := &Server{frontendHostPort: "127.0.0.1:7233"}
+	trackRunningServer(s, true)
+	defer trackRunningServer(s, false)
+	defer s.ResumeTaskProcessing()
+
+	tests := []struct {
+		method string
+		target string
+		want   int
+	}{
+		{method: http.MethodGet, target: "/debug/pause", want: http.StatusMethodNotAllowed},
+		{method: http.MethodPost, target: "/debug/pause?frontend=127.0.0.1:7234", want: http.StatusBadRequest},
+		{method: http.MethodPost, target: "/debug/pause", want: http.StatusNoContent},
+		{method: http.MethodPost, target: "/debug/resume", want: http.StatusNoContent},
+		{method: http.MethodGet, target: "/debug/routingtrace", want: http.StatusBadRequest},
+		{method: http.MethodGet, target: "/debug/unknown", want: http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.method+" "+tc.target, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			DebugHandler().ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, nil))
+			if rec.Code != tc.want {
+				t.Errorf("status = %d, want %d", rec.Code, tc.want)
+			}
+		})
+	}
+}
+
+func TestDebugHandlersNotOnDefaultServeMux(t *testing.T) {
+	for _, path := range []string{"/debug/pause", "/debug/resume", "/debug/retention", "/debug/taskqueue"} {
+		if _, pattern := http.DefaultServeMux.Handler(httptest.NewRequest(http.MethodPost, path, nil)); pattern != "" {
+			t.Errorf("%s is served by http.DefaultServeMux as %q", path, pattern)
+		}
+	}
+}
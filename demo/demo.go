@@ -0,0 +1,104 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+// Package demo holds small workflows illustrating Temporal concepts: activity
+// retries, child workflows, and signals.
+//
+// The temporalite demo command runs them against an in-memory server, so
+// newcomers can watch them in the web UI without writing any code.
+package demo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+// TaskQueue is the task queue demo workflows and activities run on.
+const TaskQueue = "temporalite-demo"
+
+// ApprovalSignal is the name of the signal ApprovalWorkflow waits for.
+const ApprovalSignal = "approve"
+
+// flakyAttempts is the number of attempts FlakyActivity fails before succeeding.
+const flakyAttempts = 2
+
+var activityOptions = workflow.ActivityOptions{
+	StartToCloseTimeout: 10 * time.Second,
+	RetryPolicy: &temporal.RetryPolicy{
+		InitialInterval:    time.Second,
+		BackoffCoefficient: 2,
+		MaximumAttempts:    5,
+	},
+}
+
+// RetryWorkflow executes FlakyActivity, which the server retries with
+// increasing backoff until it succeeds on its third attempt.
+func RetryWorkflow(ctx workflow.Context) (string, error) {
+	ctx = workflow.WithActivityOptions(ctx, activityOptions)
+	var result string
+	err := workflow.ExecuteActivity(ctx, FlakyActivity).Get(ctx, &result)
+	return result, err
+}
+
+// FlakyActivity fails its first attempts, as a call to an unreliable service might.
+func FlakyActivity(ctx context.Context) (string, error) {
+	attempt := activity.GetInfo(ctx).Attempt
+	if attempt <= flakyAttempts {
+		return "", fmt.Errorf("simulated failure on attempt %d", attempt)
+	}
+	return fmt.Sprintf("succeeded on attempt %d", attempt), nil
+}
+
+// ParentWorkflow starts a ChildWorkflow for each name in parallel and returns
+// their greetings once all have completed.
+func ParentWorkflow(ctx workflow.Context, names []string) ([]string, error) {
+	children := make([]workflow.ChildWorkflowFuture, len(names))
+	for i, name := range names {
+		children[i] = workflow.ExecuteChildWorkflow(ctx, ChildWorkflow, name)
+	}
+	greetings := make([]string, len(names))
+	for i, child := range children {
+		if err := child.Get(ctx, &greetings[i]); err != nil {
+			return nil, err
+		}
+	}
+	return greetings, nil
+}
+
+// ChildWorkflow greets name with GreetActivity.
+func ChildWorkflow(ctx workflow.Context, name string) (string, error) {
+	ctx = workflow.WithActivityOptions(ctx, activityOptions)
+	var greeting string
+	err := workflow.ExecuteActivity(ctx, GreetActivity, name).Get(ctx, &greeting)
+	return greeting, err
+}
+
+// GreetActivity returns a greeting for name.
+func GreetActivity(ctx context.Context, name string) (string, error) {
+	return fmt.Sprintf("Hello, %s!", name), nil
+}
+
+// ApprovalWorkflow blocks until it receives an ApprovalSignal carrying the
+// approver's name, however long that takes.
+func ApprovalWorkflow(ctx workflow.Context) (string, error) {
+	var approver string
+	workflow.GetSignalChannel(ctx, ApprovalSignal).Receive(ctx, &approver)
+	return fmt.Sprintf("approved by %s", approver), nil
+}
+
+// Register registers the demo workflows and activities.
+func Register(r worker.Registry) {
+	r.RegisterWorkflow(RetryWorkflow)
+	r.RegisterWorkflow(ParentWorkflow)
+	r.RegisterWorkflow(ChildWorkflow)
+	r.RegisterWorkflow(ApprovalWorkflow)
+	r.RegisterActivity(FlakyActivity)
+	r.RegisterActivity(GreetActivity)
+}
@@ -0,0 +1,64 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package demo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/client"
+
+	"github.com/DataDog/temporalite/demo"
+	"github.com/DataDog/temporalite/temporaltest"
+)
+
+func TestWorkflows(t *testing.T) {
+	ts := temporaltest.NewServer(temporaltest.WithT(t))
+	ts.Worker(demo.TaskQueue, demo.Register)
+	c := ts.Client()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	opts := client.StartWorkflowOptions{TaskQueue: demo.TaskQueue}
+
+	run, err := c.ExecuteWorkflow(ctx, opts, demo.RetryWorkflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result string
+	if err := run.Get(ctx, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result != "succeeded on attempt 3" {
+		t.Errorf("unexpected retry result %q", result)
+	}
+
+	run, err = c.ExecuteWorkflow(ctx, opts, demo.ParentWorkflow, []string{"Alice", "Bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var greetings []string
+	if err := run.Get(ctx, &greetings); err != nil {
+		t.Fatal(err)
+	}
+	if len(greetings) != 2 || greetings[0] != "Hello, Alice!" || greetings[1] != "Hello, Bob!" {
+		t.Errorf("unexpected greetings %q", greetings)
+	}
+
+	run, err = c.ExecuteWorkflow(ctx, opts, demo.ApprovalWorkflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SignalWorkflow(ctx, run.GetID(), "", demo.ApprovalSignal, "Carol"); err != nil {
+		t.Fatal(err)
+	}
+	if err := run.Get(ctx, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result != "approved by Carol" {
+		t.Errorf("unexpected approval result %q", result)
+	}
+}
@@ -0,0 +1,127 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"go.temporal.io/server/common/config"
+	"gopkg.in/yaml.v3"
+)
+
+// redactedMask replaces redacted configuration values in diagnostics dumps.
+const redactedMask = "<redacted>"
+
+// redactedConfigKeys are the keys of upstream configuration values that hold
+// credentials, point at them, or reveal where data is archived. Dumps are
+// attached to bug reports, so these are masked wherever they appear.
+var redactedConfigKeys = map[string]bool{
+	"user":              true,
+	"password":          true,
+	"connectAttributes": true,
+	"certFile":          true,
+	"keyFile":           true,
+	"certData":          true,
+	"keyData":           true,
+	"clientCaFiles":     true,
+	"clientCaData":      true,
+	"rootCaFiles":       true,
+	"rootCaData":        true,
+	"credentialsPath":   true,
+	"endpoint":          true,
+	"URI":               true,
+	"keySourceURIs":     true,
+}
+
+// DumpDiagnostics writes goroutine stacks, the active server configuration, and
+// basic persistence statistics to w.
+//
+// This is intended for debugging hung servers, for example when SQLite is under
+// heavy lock contention. Sensitive configuration values, such as credentials
+// and TLS key paths, are redacted.
+func (s *Server) DumpDiagnostics(w io.Writer) error {
+	fmt.Fprintf(w, "temporalite diagnostics dump (pid %d) at %s\n\n", os.Getpid(), time.Now().Format(time.RFC3339))
+
+	fmt.Fprintln(w, "== persistence ==")
+	if s.config.Ephemeral {
+		fmt.Fprintln(w, "mode: ephemeral (in-memory)")
+	} else {
+		fmt.Fprintf(w, "mode: file\npath: %s\n", s.config.DatabaseFilePath)
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			path := s.config.DatabaseFilePath + suffix
+			if info, err := os.Stat(path); err == nil {
+				fmt.Fprintf(w, "size %s: %d bytes\n", path, info.Size())
+			}
+		}
+	}
+	fmt.Fprintf(w, "pragmas: %v\n\n", s.config.SQLitePragmas)
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	fmt.Fprintln(w, "== runtime ==")
+	fmt.Fprintf(w, "goroutines: %d\nheap alloc: %d bytes\nsys: %d bytes\nnum gc: %d\n\n", runtime.NumGoroutine(), mem.HeapAlloc, mem.Sys, mem.NumGC)
+
+	fmt.Fprintln(w, "== config ==")
+	fmt.Fprintln(w, redactConfig(s.upstreamConfig))
+
+	fmt.Fprintln(w, "== goroutines ==")
+	return pprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// redactConfig returns cfg as YAML with the values of redactedConfigKeys masked.
+func redactConfig(cfg *config.Config) string {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Sprintf("unable to encode config: %v", err)
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Sprintf("unable to encode config: %v", err)
+	}
+	redactValues(doc)
+	if data, err = yaml.Marshal(doc); err != nil {
+		return fmt.Sprintf("unable to encode config: %v", err)
+	}
+	return string(data)
+}
+
+// redactValues masks the set values of redactedConfigKeys in a decoded YAML document.
+func redactValues(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if redactedConfigKeys[key] && !isEmptyValue(value) {
+				v[key] = redactedMask
+				continue
+			}
+			redactValues(value)
+		}
+	case []interface{}:
+		for _, value := range v {
+			redactValues(value)
+		}
+	}
+}
+
+// isEmptyValue reports whether a decoded YAML value is unset, so that dumps
+// still show which sensitive settings are configured.
+func isEmptyValue(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
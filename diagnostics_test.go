@@ -0,0 +1,73 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.temporal.io/server/common/config"
+
+	"github.com/DataDog/temporalite/internal/liteconfig"
+)
+
+func TestDumpDiagnosticsRedactsConfig(t *testing.T) {
+	endpoint := "https://archive.internal:9000"
+	cfg := &config.Config{
+		Global: config.Global{
+			TLS: config.RootTLS{
+				Frontend: config.GroupTLS{
+					Server: config.ServerTLS{CertFile: "/secrets/frontend.pem", KeyFile: "/secrets/frontend-key.pem"},
+					Client: config.ClientTLS{RootCAFiles: []string{"/secrets/ca.pem"}},
+				},
+			},
+		},
+		Persistence: config.Persistence{
+			DefaultStore: "sqlite-default",
+			DataStores: map[string]config.DataStore{
+				"sqlite-default": {SQL: &config.SQL{
+					User:              "temporal",
+					Password:          "hunter2",
+					PluginName:        "sqlite",
+					ConnectAttributes: map[string]string{"_auth_pass": "hunter3"},
+				}},
+			},
+		},
+		Archival: config.Archival{
+			History: config.HistoryArchival{
+				Provider: &config.HistoryArchiverProvider{
+					S3store:  &config.S3Archiver{Region: "us-east-1", Endpoint: &endpoint},
+					Gstorage: &config.GstorageArchiver{CredentialsPath: "/secrets/gcs.json"},
+				},
+			},
+		},
+		NamespaceDefaults: config.NamespaceDefaults{
+			Archival: config.ArchivalNamespaceDefaults{
+				History: config.HistoryArchivalNamespaceDefaults{State: "enabled", URI: "s3://private-bucket/history"},
+			},
+		},
+	}
+	s := &Server{config: &liteconfig.Config{Ephemeral: true}, upstreamConfig: cfg}
+
+	var buf bytes.Buffer
+	if err := s.DumpDiagnostics(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dump := buf.String()
+	for _, secret := range []string{
+		"/secrets/frontend.pem", "/secrets/frontend-key.pem", "/secrets/ca.pem", "hunter2", "hunter3",
+		"user: temporal", "archive.internal", "/secrets/gcs.json", "private-bucket",
+	} {
+		if strings.Contains(dump, secret) {
+			t.Errorf("dump contains %q", secret)
+		}
+	}
+	for _, kept := range []string{"pluginName: sqlite", "region: us-east-1", "state: enabled", redactedMask} {
+		if !strings.Contains(dump, kept) {
+			t.Errorf("dump does not contain %q", kept)
+		}
+	}
+}
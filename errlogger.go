@@ -0,0 +1,126 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/log/tag"
+)
+
+// errChanLogger turns Fatal messages logged by internal Temporal services into
+// errors, rather than letting the wrapped logger exit the process that embeds
+// the server.
+//
+// Upstream code assumes Fatal does not return, for example using the nil
+// listener it failed to create, so Fatal never returns into it. A Fatal logged
+// while NewServer or Start run upstream code under guardFatal unwinds to them,
+// and they return its error. A Fatal logged later by a service goroutine is
+// reported on errCh, stops the server, and ends that goroutine.
+type errChanLogger struct {
+	log.Logger
+	errCh chan<- error
+	// stop stops the server after a Fatal logged by a service goroutine.
+	stop func()
+}
+
+func (l *errChanLogger) Fatal(msg string, tags ...tag.Tag) {
+	var b strings.Builder
+	b.WriteString(msg)
+	for _, t := range tags {
+		fmt.Fprintf(&b, " %s=%v", t.Key(), t.Value())
+	}
+	fatal := &fatalError{msg: "temporal service fatal error: " + b.String()}
+	for _, t := range tags {
+		if zt, ok := t.(tag.ZapTag); ok {
+			if cause, ok := zt.Field().Interface.(error); ok {
+				fatal.err = cause
+			}
+		}
+	}
+	err := classifyStartError(fatal)
+	l.Logger.Error(msg, tags...)
+
+	if guardingFatal() {
+		panic(fatalPanic{err})
+	}
+	reportErr(l.errCh, err)
+	if l.stop != nil {
+		go l.stop()
+	}
+	runtime.Goexit()
+}
+
+// fatalError is a Fatal message logged by an internal Temporal service, wrapping
+// the error logged with it, if any, so that it can be classified.
+type fatalError struct {
+	msg string
+	err error
+}
+
+func (e *fatalError) Error() string {
+	return e.msg
+}
+
+func (e *fatalError) Unwrap() error {
+	return e.err
+}
+
+// fatalPanic carries the error of a Fatal from upstream code to guardFatal.
+type fatalPanic struct {
+	err error
+}
+
+// guardFatal runs f, which calls into upstream code, returning the error of a
+// Fatal logged by that code on the calling goroutine instead of f's.
+func guardFatal(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			p, ok := r.(fatalPanic)
+			if !ok {
+				panic(r)
+			}
+			err = p.err
+		}
+	}()
+	return f()
+}
+
+var guardFatalName = runtime.FuncForPC(reflect.ValueOf(guardFatal).Pointer()).Name()
+
+// guardingFatal reports whether the calling goroutine is running under guardFatal.
+func guardingFatal() bool {
+	pcs := make([]uintptr, 128)
+	for {
+		n := runtime.Callers(2, pcs)
+		if n < len(pcs) {
+			pcs = pcs[:n]
+			break
+		}
+		pcs = make([]uintptr, 2*len(pcs))
+	}
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if frame.Function == guardFatalName {
+			return true
+		}
+		if !more {
+			return false
+		}
+	}
+}
+
+// reportErr sends err without blocking, dropping it if nobody is consuming the channel.
+func reportErr(errCh chan<- error, err error) {
+	select {
+	case errCh <- err:
+	default:
+	}
+}
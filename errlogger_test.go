@@ -0,0 +1,142 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.temporal.io/server/common"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/log/tag"
+
+	"github.com/DataDog/temporalite/internal/liteconfig"
+)
+
+type errorRecorder struct {
+	log.Logger
+	errors []string
+}
+
+func (r *errorRecorder) Error(msg string, _ ...tag.Tag) {
+	r.errors = append(r.errors, msg)
+}
+
+func (r *errorRecorder) Fatal(msg string, _ ...tag.Tag) {
+	panic("Fatal called: " + msg)
+}
+
+// fatalInGoroutine logs a Fatal with l on a new goroutine, as a service
+// goroutine would, and reports whether Fatal returned.
+func fatalInGoroutine(l *errChanLogger, msg string, tags ...tag.Tag) (returned bool) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Fatal(msg, tags...)
+		returned = true
+	}()
+	<-done
+	return returned
+}
+
+func TestErrChanLoggerFatal(t *testing.T) {
+	errCh := make(chan error, 1)
+	stopped := make(chan struct{})
+	recorder := &errorRecorder{Logger: log.NewNoopLogger()}
+	l := &errChanLogger{Logger: recorder, errCh: errCh, stop: func() { close(stopped) }}
+
+	if fatalInGoroutine(l, "Failed to start gRPC listener", tag.Address("127.0.0.1:7233")) {
+		t.Error("Fatal returned")
+	}
+
+	select {
+	case err := <-errCh:
+		if !strings.Contains(err.Error(), "Failed to start gRPC listener") || !strings.Contains(err.Error(), "127.0.0.1:7233") {
+			t.Errorf("reported error %q, want the message and its tags", err)
+		}
+	default:
+		t.Error("no error reported")
+	}
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Error("server not stopped")
+	}
+	if len(recorder.errors) != 1 {
+		t.Errorf("logged %d errors, want 1", len(recorder.errors))
+	}
+}
+
+func TestErrChanLoggerFatalGuarded(t *testing.T) {
+	errCh := make(chan error, 1)
+	l := &errChanLogger{Logger: &errorRecorder{Logger: log.NewNoopLogger()}, errCh: errCh, stop: func() { t.Error("server stopped") }}
+
+	err := guardFatal(func() error {
+		l.Fatal("Failed to start gRPC listener")
+		t.Error("Fatal returned")
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "Failed to start gRPC listener") {
+		t.Errorf("guardFatal() = %v, want the fatal error", err)
+	}
+	select {
+	case err := <-errCh:
+		t.Errorf("reported %v on errCh, want it returned only", err)
+	default:
+	}
+}
+
+func TestErrChanLoggerFatalPortInUse(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	_, bindErr := net.Listen("tcp", l.Addr().String())
+	if bindErr == nil {
+		t.Fatal("second listener bound a port in use")
+	}
+
+	logger := &errChanLogger{Logger: &errorRecorder{Logger: log.NewNoopLogger()}, errCh: make(chan error, 1)}
+	err = guardFatal(func() error {
+		logger.Fatal("Failed to start gRPC listener", tag.Error(bindErr), tag.Address(l.Addr().String()))
+		return nil
+	})
+	if !errors.Is(err, ErrPortInUse) {
+		t.Errorf("fatal error %q does not match ErrPortInUse", err)
+	}
+}
+
+func TestServersOnSamePort(t *testing.T) {
+	port, err := liteconfig.FindAvailablePort("127.0.0.1", 20000+rand.Intn(20000), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := []ServerOption{WithPersistenceDisabled(), WithFrontendIP("127.0.0.1"), WithFrontendPort(port), WithLogger(log.NewNoopLogger())}
+	s, err := NewServer(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewServer(opts...)
+	if !errors.Is(err, ErrPortInUse) {
+		t.Fatalf("NewServer() on a port in use = %v, want ErrPortInUse", err)
+	}
+	// The first server is unaffected.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := s.AwaitNamespace(ctx, common.SystemLocalNamespace); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var (
+	// ErrPortInUse is returned when a port the server listens on is already bound,
+	// such as by another running server. Use WithPortRetries or WithDynamicPorts
+	// to pick another port.
+	ErrPortInUse = errors.New("port in use")
+	// ErrSchemaMismatch is returned when the database file is not a SQLite database
+	// or does not contain the Temporal schema this version of temporalite expects.
+	ErrSchemaMismatch = errors.New("database schema mismatch")
+	// ErrDatabaseLocked is returned when another process holds a lock on the
+	// database file for longer than temporalite waits.
+	ErrDatabaseLocked = errors.New("database locked")
+)
+
+// serverError associates an underlying error with one of the exported error
+// variables, so both errors.Is and the original message are preserved.
+type serverError struct {
+	kind error
+	err  error
+}
+
+func (e *serverError) Error() string {
+	return e.err.Error()
+}
+
+func (e *serverError) Unwrap() error {
+	return e.err
+}
+
+func (e *serverError) Is(target error) bool {
+	return target == e.kind
+}
+
+// classifyDatabaseError marks SQLite lock contention and invalid database files.
+func classifyDatabaseError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code {
+		case sqlite3.ErrBusy, sqlite3.ErrLocked:
+			return &serverError{kind: ErrDatabaseLocked, err: err}
+		case sqlite3.ErrNotADB, sqlite3.ErrCorrupt:
+			return &serverError{kind: ErrSchemaMismatch, err: err}
+		}
+	}
+	// Upstream persistence code does not always wrap driver errors.
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "database is locked"), strings.Contains(msg, "database table is locked"):
+		return &serverError{kind: ErrDatabaseLocked, err: err}
+	case strings.Contains(msg, "file is not a database"):
+		return &serverError{kind: ErrSchemaMismatch, err: err}
+	}
+	return err
+}
+
+// classifyStartError marks failures to bind a listener to a port in use.
+func classifyStartError(err error) error {
+	if errors.Is(err, syscall.EADDRINUSE) {
+		return &serverError{kind: ErrPortInUse, err: err}
+	}
+	return classifyDatabaseError(err)
+}
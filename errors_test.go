@@ -0,0 +1,51 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestClassifyStartError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{
+			name: "port in use",
+			err:  fmt.Errorf("unable to listen: %w", &net.OpError{Op: "listen", Net: "tcp", Err: os.NewSyscallError("bind", syscall.EADDRINUSE)}),
+			want: ErrPortInUse,
+		},
+		{
+			name: "address not available",
+			err:  fmt.Errorf("unable to listen: %w", &net.OpError{Op: "listen", Net: "tcp", Err: os.NewSyscallError("bind", syscall.EADDRNOTAVAIL)}),
+		},
+		{
+			name: "message mentioning the address",
+			err:  errors.New("address already in use"),
+		},
+		{
+			name: "database locked",
+			err:  errors.New("database is locked"),
+			want: ErrDatabaseLocked,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := classifyStartError(tc.err)
+			for _, kind := range []error{ErrPortInUse, ErrSchemaMismatch, ErrDatabaseLocked} {
+				if errors.Is(got, kind) != (kind == tc.want) {
+					t.Errorf("errors.Is(%v, %v) = %v", got, kind, !(kind == tc.want))
+				}
+			}
+		})
+	}
+}
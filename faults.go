@@ -0,0 +1,153 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	failurepb "go.temporal.io/api/failure/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"google.golang.org/grpc"
+)
+
+// A Fault is a failure forced on the next workflow task or activity of a workflow
+// with InjectFault.
+type Fault int
+
+const (
+	// FailWorkflowTask fails the next workflow task as if the worker had
+	// panicked, so the server retries it.
+	FailWorkflowTask Fault = iota + 1
+	// TimeoutWorkflowTask withholds the next workflow task from workers until
+	// its start-to-close timeout expires.
+	TimeoutWorkflowTask
+	// FailActivity fails the next activity task with an application error,
+	// subject to the activity's retry policy.
+	FailActivity
+	// TimeoutActivity withholds the next activity task from workers until its
+	// start-to-close timeout expires.
+	TimeoutActivity
+)
+
+const (
+	faultIdentity = "temporalite-fault-injector"
+	faultMessage  = "failure injected by temporalite"
+)
+
+func (f Fault) workflowTask() bool {
+	return f == FailWorkflowTask || f == TimeoutWorkflowTask
+}
+
+// InjectFault forces the next workflow task or activity task dispatched for
+// workflowID to fail or time out, for testing retry policies and compensation
+// logic. Each call applies to a single task; faults injected repeatedly for the
+// same workflow apply to successive tasks in order.
+//
+// The task is intercepted when a worker polls for it, so it is not seen by
+// worker interceptors or counted as an attempt by the worker.
+//
+// The server must have been created with WithFaultInjection.
+func (s *Server) InjectFault(workflowID string, fault Fault) error {
+	if s.faults == nil {
+		return errors.New("fault injection requires WithFaultInjection")
+	}
+	s.faults.add(workflowID, fault)
+	return nil
+}
+
+// faultInjector intercepts task queue polls to apply faults added with InjectFault.
+type faultInjector struct {
+	workflowService func() (workflowservice.WorkflowServiceClient, error)
+
+	mu      sync.Mutex
+	pending map[string][]Fault
+}
+
+func (f *faultInjector) add(workflowID string, fault Fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.pending == nil {
+		f.pending = make(map[string][]Fault)
+	}
+	f.pending[workflowID] = append(f.pending[workflowID], fault)
+}
+
+// take removes and returns the first pending fault for workflowID that applies
+// to a workflow task or an activity task, or zero.
+func (f *faultInjector) take(workflowID string, workflowTask bool) Fault {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	faults := f.pending[workflowID]
+	for i, fault := range faults {
+		if fault.workflowTask() == workflowTask {
+			f.pending[workflowID] = append(faults[:i:i], faults[i+1:]...)
+			if len(f.pending[workflowID]) == 0 {
+				delete(f.pending, workflowID)
+			}
+			return fault
+		}
+	}
+	return 0
+}
+
+func (f *faultInjector) Intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	switch r := resp.(type) {
+	case *workflowservice.PollWorkflowTaskQueueResponse:
+		fault := f.take(r.GetWorkflowExecution().GetWorkflowId(), true)
+		if fault == FailWorkflowTask {
+			// If failing the task doesn't succeed, it times out instead.
+			_ = f.respond(ctx, func(svc workflowservice.WorkflowServiceClient) error {
+				_, err := svc.RespondWorkflowTaskFailed(ctx, &workflowservice.RespondWorkflowTaskFailedRequest{
+					Namespace: req.(*workflowservice.PollWorkflowTaskQueueRequest).GetNamespace(),
+					TaskToken: r.GetTaskToken(),
+					Cause:     enumspb.WORKFLOW_TASK_FAILED_CAUSE_WORKFLOW_WORKER_UNHANDLED_FAILURE,
+					Failure:   &failurepb.Failure{Message: faultMessage},
+					Identity:  faultIdentity,
+				})
+				return err
+			})
+		}
+		if fault != 0 {
+			// An empty response looks to the worker like a poll that timed out.
+			return &workflowservice.PollWorkflowTaskQueueResponse{}, nil
+		}
+	case *workflowservice.PollActivityTaskQueueResponse:
+		fault := f.take(r.GetWorkflowExecution().GetWorkflowId(), false)
+		if fault == FailActivity {
+			// If failing the task doesn't succeed, it times out instead.
+			_ = f.respond(ctx, func(svc workflowservice.WorkflowServiceClient) error {
+				_, err := svc.RespondActivityTaskFailed(ctx, &workflowservice.RespondActivityTaskFailedRequest{
+					Namespace: req.(*workflowservice.PollActivityTaskQueueRequest).GetNamespace(),
+					TaskToken: r.GetTaskToken(),
+					Failure: &failurepb.Failure{
+						Message:     faultMessage,
+						FailureInfo: &failurepb.Failure_ApplicationFailureInfo{ApplicationFailureInfo: &failurepb.ApplicationFailureInfo{Type: "InjectedFault"}},
+					},
+					Identity: faultIdentity,
+				})
+				return err
+			})
+		}
+		if fault != 0 {
+			return &workflowservice.PollActivityTaskQueueResponse{}, nil
+		}
+	}
+	return resp, nil
+}
+
+func (f *faultInjector) respond(ctx context.Context, call func(workflowservice.WorkflowServiceClient) error) error {
+	svc, err := f.workflowService()
+	if err != nil {
+		return err
+	}
+	return call(svc)
+}
@@ -0,0 +1,185 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"testing"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"google.golang.org/grpc"
+)
+
+// fakeTaskResponder records the tasks failed by the fault injector.
+type fakeTaskResponder struct {
+	workflowservice.WorkflowServiceClient
+	failedWorkflowTasks int
+	failedActivities    int
+}
+
+func (f *fakeTaskResponder) RespondWorkflowTaskFailed(context.Context, *workflowservice.RespondWorkflowTaskFailedRequest, ...grpc.CallOption) (*workflowservice.RespondWorkflowTaskFailedResponse, error) {
+	f.failedWorkflowTasks++
+	return &workflowservice.RespondWorkflowTaskFailedResponse{}, nil
+}
+
+func (f *fakeTaskResponder) RespondActivityTaskFailed(context.Context, *workflowservice.RespondActivityTaskFailedRequest, ...grpc.CallOption) (*workflowservice.RespondActivityTaskFailedResponse, error) {
+	f.failedActivities++
+	return &workflowservice.RespondActivityTaskFailedResponse{}, nil
+}
+
+func TestFaultInjectorTake(t *testing.T) {
+	tests := []struct {
+		name         string
+		faults       []Fault
+		workflowTask bool
+		want         []Fault
+	}{
+		{
+			name:         "none pending",
+			workflowTask: true,
+			want:         []Fault{0},
+		},
+		{
+			name:         "in order",
+			faults:       []Fault{FailWorkflowTask, TimeoutWorkflowTask},
+			workflowTask: true,
+			want:         []Fault{FailWorkflowTask, TimeoutWorkflowTask, 0},
+		},
+		{
+			name:         "skips activity faults",
+			faults:       []Fault{FailActivity, TimeoutWorkflowTask},
+			workflowTask: true,
+			want:         []Fault{TimeoutWorkflowTask, 0},
+		},
+		{
+			name:   "skips workflow task faults",
+			faults: []Fault{FailWorkflowTask, TimeoutActivity, FailActivity},
+			want:   []Fault{TimeoutActivity, FailActivity, 0},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var f faultInjector
+			for _, fault := range tc.faults {
+				f.add("order-1", fault)
+			}
+			if got := f.take("order-2", tc.workflowTask); got != 0 {
+				t.Errorf("take() for another workflow = %v, want none", got)
+			}
+			for i, want := range tc.want {
+				if got := f.take("order-1", tc.workflowTask); got != want {
+					t.Errorf("take() #%d = %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFaultInjectorIntercept(t *testing.T) {
+	execution := &commonpb.WorkflowExecution{WorkflowId: "order-1"}
+	workflowTask := &workflowservice.PollWorkflowTaskQueueResponse{TaskToken: []byte("token"), WorkflowExecution: execution}
+	activityTask := &workflowservice.PollActivityTaskQueueResponse{TaskToken: []byte("token"), WorkflowExecution: execution}
+
+	tests := []struct {
+		name                    string
+		fault                   Fault
+		req                     interface{}
+		resp                    interface{}
+		wantWithheld            bool
+		wantFailedWorkflowTasks int
+		wantFailedActivities    int
+	}{
+		{
+			name: "no fault",
+			req:  &workflowservice.PollWorkflowTaskQueueRequest{Namespace: "default"},
+			resp: workflowTask,
+		},
+		{
+			name:                    "fail workflow task",
+			fault:                   FailWorkflowTask,
+			req:                     &workflowservice.PollWorkflowTaskQueueRequest{Namespace: "default"},
+			resp:                    workflowTask,
+			wantWithheld:            true,
+			wantFailedWorkflowTasks: 1,
+		},
+		{
+			name:         "time out workflow task",
+			fault:        TimeoutWorkflowTask,
+			req:          &workflowservice.PollWorkflowTaskQueueRequest{Namespace: "default"},
+			resp:         workflowTask,
+			wantWithheld: true,
+		},
+		{
+			name:                 "fail activity",
+			fault:                FailActivity,
+			req:                  &workflowservice.PollActivityTaskQueueRequest{Namespace: "default"},
+			resp:                 activityTask,
+			wantWithheld:         true,
+			wantFailedActivities: 1,
+		},
+		{
+			name:         "time out activity",
+			fault:        TimeoutActivity,
+			req:          &workflowservice.PollActivityTaskQueueRequest{Namespace: "default"},
+			resp:         activityTask,
+			wantWithheld: true,
+		},
+		{
+			name:  "activity fault on workflow task",
+			fault: FailActivity,
+			req:   &workflowservice.PollWorkflowTaskQueueRequest{Namespace: "default"},
+			resp:  workflowTask,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := &fakeTaskResponder{}
+			f := &faultInjector{workflowService: func() (workflowservice.WorkflowServiceClient, error) { return svc, nil }}
+			if tc.fault != 0 {
+				f.add("order-1", tc.fault)
+			}
+			handler := func(context.Context, interface{}) (interface{}, error) { return tc.resp, nil }
+			resp, err := f.Intercept(context.Background(), tc.req, &grpc.UnaryServerInfo{}, handler)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if withheld := resp != tc.resp; withheld != tc.wantWithheld {
+				t.Errorf("task withheld = %v, want %v", withheld, tc.wantWithheld)
+			}
+			if svc.failedWorkflowTasks != tc.wantFailedWorkflowTasks {
+				t.Errorf("failed %d workflow tasks, want %d", svc.failedWorkflowTasks, tc.wantFailedWorkflowTasks)
+			}
+			if svc.failedActivities != tc.wantFailedActivities {
+				t.Errorf("failed %d activities, want %d", svc.failedActivities, tc.wantFailedActivities)
+			}
+		})
+	}
+}
+
+func TestInjectFaultRequiresOption(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []ServerOption
+		wantErr bool
+	}{
+		{name: "enabled", opts: []ServerOption{WithFaultInjection()}},
+		{name: "not enabled", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := NewServer(append([]ServerOption{WithPersistenceDisabled(), WithDynamicPorts()}, tc.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.InjectFault("order-1", FailActivity); (err != nil) != tc.wantErr {
+				t.Errorf("InjectFault() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if installed := s.faults != nil; installed == tc.wantErr {
+				t.Errorf("fault injector installed = %v, want %v", installed, !tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"time"
+
+	"go.temporal.io/api/workflowservice/v1"
+	"google.golang.org/grpc"
+)
+
+// Heartbeat timeouts of WithTightHeartbeatTimeouts.
+const (
+	tightActivityHeartbeatTimeout     = time.Second
+	tightWorkflowTaskHeartbeatTimeout = 10 * time.Second
+)
+
+// heartbeatLimiter lowers the heartbeat timeouts of activities scheduled by
+// workflows to max. Activities without a heartbeat timeout are left alone, as
+// they don't heartbeat.
+type heartbeatLimiter struct {
+	max time.Duration
+}
+
+func (l *heartbeatLimiter) Intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if r, ok := req.(*workflowservice.RespondWorkflowTaskCompletedRequest); ok {
+		for _, cmd := range r.GetCommands() {
+			attrs := cmd.GetScheduleActivityTaskCommandAttributes()
+			if attrs == nil || attrs.HeartbeatTimeout == nil || *attrs.HeartbeatTimeout <= l.max {
+				continue
+			}
+			limit := l.max
+			attrs.HeartbeatTimeout = &limit
+		}
+	}
+	return handler(ctx, req)
+}
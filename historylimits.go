@@ -0,0 +1,91 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/common"
+	"go.temporal.io/server/common/dynamicconfig"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/log/tag"
+	"google.golang.org/grpc"
+)
+
+// Upstream defaults for the history limits, used when they are not configured.
+const (
+	defaultHistoryCountLimit = 50 * 1024
+	defaultHistorySizeLimit  = 50 * 1024 * 1024
+)
+
+// maxExplainedRuns bounds the runs historyLimitExplainer remembers having
+// logged; once reached, it starts over.
+const maxExplainedRuns = 1000
+
+// historyLimitExplainer logs a warning explaining which limit was hit and how
+// to avoid it the first time a workflow terminated for exceeding a history
+// limit is read, as the recorded reason doesn't say. It is only installed when
+// the limits are configured with WithHistoryLimits.
+type historyLimitExplainer struct {
+	dynamicConfig *dynamicconfig.MutableEphemeralClient
+	logger        log.Logger
+
+	mu     sync.Mutex
+	logged map[string]bool
+}
+
+// configuresHistoryLimits reports whether the dynamic config values set either
+// history limit.
+func configuresHistoryLimits(values map[dynamicconfig.Key]interface{}) bool {
+	_, count := values[dynamicconfig.HistoryCountLimitError]
+	_, size := values[dynamicconfig.HistorySizeLimitError]
+	return count || size
+}
+
+func (e *historyLimitExplainer) explanation(namespace string) string {
+	filter := map[dynamicconfig.Filter]interface{}{dynamicconfig.Namespace: namespace}
+	count, _ := e.dynamicConfig.GetIntValue(dynamicconfig.HistoryCountLimitError, filter, defaultHistoryCountLimit)
+	size, _ := e.dynamicConfig.GetIntValue(dynamicconfig.HistorySizeLimitError, filter, defaultHistorySizeLimit)
+	return fmt.Sprintf("Histories are limited to %d events and %d bytes (configured with WithHistoryLimits or "+
+		"--max-history-events and --max-history-size). Long-running loops should use continue-as-new to start a fresh history.",
+		count, size)
+}
+
+func (e *historyLimitExplainer) Intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	r, ok := resp.(*workflowservice.GetWorkflowExecutionHistoryResponse)
+	if err != nil || !ok || len(r.GetHistory().GetEvents()) == 0 {
+		return resp, err
+	}
+	events := r.GetHistory().GetEvents()
+	last := events[len(events)-1]
+	attrs := last.GetWorkflowExecutionTerminatedEventAttributes()
+	if last.GetEventType() != enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_TERMINATED || attrs.GetReason() != common.FailureReasonSizeExceedsLimit {
+		return resp, nil
+	}
+
+	request := req.(*workflowservice.GetWorkflowExecutionHistoryRequest)
+	runID := request.GetExecution().GetRunId()
+	e.mu.Lock()
+	if e.logged == nil || len(e.logged) >= maxExplainedRuns {
+		e.logged = make(map[string]bool)
+	}
+	seen := e.logged[runID]
+	e.logged[runID] = true
+	e.mu.Unlock()
+	if !seen {
+		e.logger.Warn("Workflow terminated for exceeding history limit",
+			tag.WorkflowNamespace(request.GetNamespace()),
+			tag.WorkflowID(request.GetExecution().GetWorkflowId()),
+			tag.WorkflowRunID(runID),
+			tag.WorkflowEventCount(int(last.GetEventId())),
+			tag.NewStringTag("explanation", e.explanation(request.GetNamespace())))
+	}
+	return resp, nil
+}
@@ -0,0 +1,70 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	historypb "go.temporal.io/api/history/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/common"
+	"go.temporal.io/server/common/dynamicconfig"
+	"go.temporal.io/server/common/log"
+	"google.golang.org/grpc"
+)
+
+func TestHistoryLimitExplainer(t *testing.T) {
+	terminated := &workflowservice.GetWorkflowExecutionHistoryResponse{History: &historypb.History{Events: []*historypb.HistoryEvent{{
+		EventId:   3,
+		EventType: enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_TERMINATED,
+		Attributes: &historypb.HistoryEvent_WorkflowExecutionTerminatedEventAttributes{
+			WorkflowExecutionTerminatedEventAttributes: &historypb.WorkflowExecutionTerminatedEventAttributes{Reason: common.FailureReasonSizeExceedsLimit},
+		},
+	}}}}
+	e := &historyLimitExplainer{dynamicConfig: dynamicconfig.NewMutableEphemeralClient(), logger: log.NewNoopLogger()}
+
+	for i := 0; i < maxExplainedRuns+1; i++ {
+		req := &workflowservice.GetWorkflowExecutionHistoryRequest{
+			Namespace: "default",
+			Execution: &commonpb.WorkflowExecution{WorkflowId: "loop", RunId: fmt.Sprintf("run-%d", i)},
+		}
+		resp, err := e.Intercept(context.Background(), req, &grpc.UnaryServerInfo{}, func(context.Context, interface{}) (interface{}, error) {
+			return terminated, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp != terminated {
+			t.Fatal("explainer modified the history")
+		}
+	}
+	if len(e.logged) > maxExplainedRuns {
+		t.Errorf("remembered %d runs, want at most %d", len(e.logged), maxExplainedRuns)
+	}
+}
+
+func TestConfiguresHistoryLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[dynamicconfig.Key]interface{}
+		want   bool
+	}{
+		{name: "none"},
+		{name: "count", values: map[dynamicconfig.Key]interface{}{dynamicconfig.HistoryCountLimitError: 100}, want: true},
+		{name: "size", values: map[dynamicconfig.Key]interface{}{dynamicconfig.HistorySizeLimitError: 1024}, want: true},
+		{name: "warning only", values: map[dynamicconfig.Key]interface{}{dynamicconfig.HistoryCountLimitWarn: 100}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := configuresHistoryLimits(tc.values); got != tc.want {
+				t.Errorf("configuresHistoryLimits() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
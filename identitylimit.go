@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/server/common/quotas"
+	"google.golang.org/grpc"
+)
+
+// identityIdleTimeout is how long an identity's limiter is kept after its last
+// request. Workers get a new identity each time they restart, so limiters of
+// identities that stopped calling are dropped.
+const identityIdleTimeout = 10 * time.Minute
+
+// identityLimiter rejects frontend requests from a caller identity exceeding
+// its rate limit, so that one misbehaving worker fleet can't starve others of
+// the frontend's namespace-wide limit.
+type identityLimiter struct {
+	// rps is the limit of identities without an override, or zero if they
+	// are unlimited.
+	rps       float64
+	overrides map[string]float64
+
+	mu       sync.Mutex
+	limiters map[identityKey]*identityLimit
+	swept    time.Time
+}
+
+type identityKey struct {
+	namespace string
+	identity  string
+}
+
+type identityLimit struct {
+	limiter  quotas.RateLimiter
+	lastUsed time.Time
+}
+
+// rate returns the limit of identity, or zero if it is unlimited.
+func (l *identityLimiter) rate(identity string) float64 {
+	if rps, ok := l.overrides[identity]; ok {
+		return rps
+	}
+	return l.rps
+}
+
+// allow reports whether a request from key is within its limit of rps.
+func (l *identityLimiter) allow(key identityKey, rps float64, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limiters == nil {
+		l.limiters = make(map[identityKey]*identityLimit)
+	}
+	if now.Sub(l.swept) > identityIdleTimeout {
+		for k, limit := range l.limiters {
+			if now.Sub(limit.lastUsed) > identityIdleTimeout {
+				delete(l.limiters, k)
+			}
+		}
+		l.swept = now
+	}
+	limit, ok := l.limiters[key]
+	if !ok {
+		limit = &identityLimit{limiter: quotas.NewDefaultIncomingRateLimiter(func() float64 { return rps })}
+		l.limiters[key] = limit
+	}
+	limit.lastUsed = now
+	return limit.limiter.Allow()
+}
+
+func (l *identityLimiter) Intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	r, ok := req.(interface {
+		GetNamespace() string
+		GetIdentity() string
+	})
+	if !ok || r.GetIdentity() == "" {
+		return handler(ctx, req)
+	}
+	rps := l.rate(r.GetIdentity())
+	if rps <= 0 {
+		return handler(ctx, req)
+	}
+	key := identityKey{namespace: r.GetNamespace(), identity: r.GetIdentity()}
+	if !l.allow(key, rps, time.Now()) {
+		return nil, serviceerror.NewResourceExhausted(fmt.Sprintf("identity %q exceeded its limit of %v requests per second in namespace %q",
+			key.identity, rps, key.namespace))
+	}
+	return handler(ctx, req)
+}
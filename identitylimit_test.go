@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.temporal.io/api/workflowservice/v1"
+	"google.golang.org/grpc"
+)
+
+func TestIdentityLimiter(t *testing.T) {
+	tests := []struct {
+		name         string
+		rps          float64
+		overrides    map[string]float64
+		identity     string
+		wantRejected bool
+	}{
+		{name: "limited", rps: 1, identity: "worker-1", wantRejected: true},
+		{name: "override", rps: 1, overrides: map[string]float64{"ci": 1000}, identity: "ci"},
+		{name: "exempt", rps: 1, overrides: map[string]float64{"ci": 0}, identity: "ci"},
+		{name: "override only", overrides: map[string]float64{"ci": 1}, identity: "ci", wantRejected: true},
+		{name: "unlimited", overrides: map[string]float64{"ci": 1}, identity: "worker-1"},
+		{name: "no identity", rps: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			l := &identityLimiter{rps: tc.rps, overrides: tc.overrides}
+			req := &workflowservice.PollActivityTaskQueueRequest{Namespace: "default", Identity: tc.identity}
+			rejected := false
+			for i := 0; i < 20; i++ {
+				if _, err := l.Intercept(context.Background(), req, &grpc.UnaryServerInfo{}, func(context.Context, interface{}) (interface{}, error) {
+					return nil, nil
+				}); err != nil {
+					rejected = true
+				}
+			}
+			if rejected != tc.wantRejected {
+				t.Errorf("rejected = %v, want %v", rejected, tc.wantRejected)
+			}
+		})
+	}
+}
+
+func TestIdentityLimiterEvictsIdle(t *testing.T) {
+	l := &identityLimiter{rps: 1}
+	start := time.Now()
+	idle := identityKey{namespace: "default", identity: "stopped-worker"}
+	l.allow(idle, 1, start)
+	l.allow(identityKey{namespace: "default", identity: "worker"}, 1, start.Add(identityIdleTimeout+time.Minute))
+	if _, ok := l.limiters[idle]; ok {
+		t.Error("limiter of idle identity was kept")
+	}
+	if len(l.limiters) != 1 {
+		t.Errorf("kept %d limiters, want 1", len(l.limiters))
+	}
+}
@@ -0,0 +1,55 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// seededReader is a concurrency-safe source of pseudo-random bytes. Both UUID
+// packages used upstream read from the google/uuid source, which SetRand replaces.
+type seededReader struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func (r *seededReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.Read(p)
+}
+
+var (
+	seedMu sync.Mutex
+	// activeSeed is the source installed by seedIDs, or nil while the system
+	// source is in use.
+	activeSeed *seededReader
+)
+
+// seedIDs makes generated UUIDs deterministic, returning a func that restores
+// the system source. The UUID source is global, so only one server per process
+// may seed it at a time.
+func seedIDs(seed int64) (func(), error) {
+	seedMu.Lock()
+	defer seedMu.Unlock()
+	if activeSeed != nil {
+		return nil, errors.New("deterministic IDs are already in use by another server in this process")
+	}
+	r := &seededReader{rng: rand.New(rand.NewSource(seed))}
+	activeSeed = r
+	uuid.SetRand(r)
+	return func() {
+		seedMu.Lock()
+		defer seedMu.Unlock()
+		if activeSeed == r {
+			activeSeed = nil
+			uuid.SetRand(nil)
+		}
+	}, nil
+}
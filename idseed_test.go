@@ -0,0 +1,84 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSeedIDs(t *testing.T) {
+	generate := func(seed int64) []string {
+		restore, err := seedIDs(seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer restore()
+		return []string{uuid.NewString(), uuid.NewString()}
+	}
+	first, second := generate(42), generate(42)
+	if first[0] != second[0] || first[1] != second[1] {
+		t.Errorf("same seed generated %v and %v", first, second)
+	}
+	if other := generate(43); other[0] == first[0] {
+		t.Errorf("different seeds generated the same ID %s", other[0])
+	}
+
+	restore, err := seedIDs(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := seedIDs(2); err == nil {
+		t.Error("second seedIDs succeeded while the first was active")
+	}
+	restore()
+	restore()
+	if activeSeed != nil {
+		t.Error("restore left the seeded source installed")
+	}
+}
+
+func TestDeterministicIDsRestoredOnError(t *testing.T) {
+	_, err := NewServer(
+		WithPersistenceDisabled(),
+		WithDynamicPorts(),
+		WithDeterministicIDs(1),
+		WithAuditLog(filepath.Join(t.TempDir(), "missing", "audit.log")),
+	)
+	if err == nil {
+		t.Fatal("NewServer() succeeded, want error")
+	}
+	if activeSeed != nil {
+		t.Error("failed NewServer left the seeded source installed")
+	}
+}
+
+func TestDeterministicIDsRestoredOnStop(t *testing.T) {
+	newSeeded := func() (*Server, error) {
+		return NewServer(WithPersistenceDisabled(), WithDynamicPorts(), WithDeterministicIDs(1))
+	}
+	s, err := newSeeded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newSeeded(); err == nil {
+		t.Error("second seeded NewServer succeeded while the first was running")
+	}
+	if activeSeed == nil {
+		t.Error("failed second NewServer removed the first server's seeded source")
+	}
+	s.Stop()
+	if activeSeed != nil {
+		t.Fatal("Stop left the seeded source installed")
+	}
+
+	s, err = newSeeded()
+	if err != nil {
+		t.Fatalf("seeded NewServer after Stop: %v", err)
+	}
+	s.Stop()
+}
@@ -0,0 +1,94 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package liteconfig
+
+import (
+	"fmt"
+	"net/url"
+
+	"go.temporal.io/server/common/config"
+)
+
+// DefaultArchivalS3Region is the S3 region used for archival when none is configured.
+const DefaultArchivalS3Region = "us-east-1"
+
+// ValidateArchivalURI checks that uri names a supported archival store:
+// file:///path, s3://bucket/path, or gs://bucket/path.
+func ValidateArchivalURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return fmt.Errorf("invalid archival URI %q: %w", uri, err)
+	}
+	switch u.Scheme {
+	case "file":
+		if u.Path == "" {
+			return fmt.Errorf("invalid archival URI %q: missing path", uri)
+		}
+	case "s3", "gs":
+		if u.Host == "" {
+			return fmt.Errorf("invalid archival URI %q: missing bucket", uri)
+		}
+	default:
+		return fmt.Errorf("unsupported archival URI scheme %q, expected file, s3, or gs", u.Scheme)
+	}
+	return nil
+}
+
+func (o *Config) s3Archiver() *config.S3Archiver {
+	region := o.ArchivalS3Region
+	if region == "" {
+		region = DefaultArchivalS3Region
+	}
+	s3 := &config.S3Archiver{Region: region}
+	if o.ArchivalS3Endpoint != "" {
+		// S3-compatible stores such as MinIO serve buckets by path rather
+		// than by subdomain.
+		endpoint := o.ArchivalS3Endpoint
+		s3.Endpoint = &endpoint
+		s3.S3ForcePathStyle = true
+	}
+	return s3
+}
+
+// historyArchival enables history archival with the archiver for the scheme
+// of HistoryArchivalURI, if set.
+func (o *Config) historyArchival() (config.HistoryArchival, config.HistoryArchivalNamespaceDefaults) {
+	if o.HistoryArchivalURI == "" {
+		return config.HistoryArchival{State: "disabled"}, config.HistoryArchivalNamespaceDefaults{State: "disabled"}
+	}
+	provider := &config.HistoryArchiverProvider{}
+	u, _ := url.Parse(o.HistoryArchivalURI)
+	switch u.Scheme {
+	case "file":
+		provider.Filestore = &config.FilestoreArchiver{FileMode: "0666", DirMode: "0766"}
+	case "s3":
+		provider.S3store = o.s3Archiver()
+	case "gs":
+		// Credentials are read from the environment.
+		provider.Gstorage = &config.GstorageArchiver{}
+	}
+	return config.HistoryArchival{State: "enabled", EnableRead: true, Provider: provider},
+		config.HistoryArchivalNamespaceDefaults{State: "enabled", URI: o.HistoryArchivalURI}
+}
+
+// visibilityArchival enables visibility archival with the archiver for the
+// scheme of VisibilityArchivalURI, if set.
+func (o *Config) visibilityArchival() (config.VisibilityArchival, config.VisibilityArchivalNamespaceDefaults) {
+	if o.VisibilityArchivalURI == "" {
+		return config.VisibilityArchival{State: "disabled"}, config.VisibilityArchivalNamespaceDefaults{State: "disabled"}
+	}
+	provider := &config.VisibilityArchiverProvider{}
+	u, _ := url.Parse(o.VisibilityArchivalURI)
+	switch u.Scheme {
+	case "file":
+		provider.Filestore = &config.FilestoreArchiver{FileMode: "0666", DirMode: "0766"}
+	case "s3":
+		provider.S3store = o.s3Archiver()
+	case "gs":
+		provider.Gstorage = &config.GstorageArchiver{}
+	}
+	return config.VisibilityArchival{State: "enabled", EnableRead: true, Provider: provider},
+		config.VisibilityArchivalNamespaceDefaults{State: "enabled", URI: o.VisibilityArchivalURI}
+}
@@ -7,22 +7,33 @@ package liteconfig
 import (
 	"fmt"
 	"math/rand"
+	"net"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/uber-go/tally/v4/m3"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/server/common/authorization"
 	"go.temporal.io/server/common/cluster"
 	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/dynamicconfig"
 	"go.temporal.io/server/common/log"
 	"go.temporal.io/server/common/metrics"
+	persistenceclient "go.temporal.io/server/common/persistence/client"
 	"go.temporal.io/server/common/persistence/sql/sqlplugin/sqlite"
 	"go.temporal.io/server/temporal"
+	"google.golang.org/grpc"
 )
 
 const (
-	broadcastAddress     = "127.0.0.1"
+	localhostIPv4        = "127.0.0.1"
+	localhostIPv6        = "::1"
 	PersistenceStoreName = "sqlite-default"
+	customStoreName      = "custom-default"
 	DefaultFrontendPort  = 7233
 )
 
@@ -36,6 +47,31 @@ type UIServer interface {
 	Stop()
 }
 
+// WorkflowClosedEvent describes a workflow execution that has closed.
+type WorkflowClosedEvent struct {
+	Namespace     string
+	WorkflowID    string
+	RunID         string
+	WorkflowType  string
+	Status        enumspb.WorkflowExecutionStatus
+	StartTime     time.Time
+	CloseTime     time.Time
+	HistoryLength int64
+}
+
+// LifecycleEventType identifies a state transition of a server.
+type LifecycleEventType string
+
+// LifecycleEvent describes a state transition of a server.
+type LifecycleEvent struct {
+	Type LifecycleEventType `json:"type"`
+	Time time.Time          `json:"time"`
+	// Namespace is set for namespace events.
+	Namespace string `json:"namespace,omitempty"`
+	// FrontendAddress is set for the started event.
+	FrontendAddress string `json:"frontend_address,omitempty"`
+}
+
 type noopUIServer struct{}
 
 func (noopUIServer) Start() error {
@@ -45,17 +81,86 @@ func (noopUIServer) Start() error {
 func (noopUIServer) Stop() {}
 
 type Config struct {
-	Ephemeral        bool
-	DatabaseFilePath string
-	FrontendPort     int
-	DynamicPorts     bool
-	Namespaces       []string
-	SQLitePragmas    map[string]string
-	Logger           log.Logger
-	UpstreamOptions  []temporal.ServerOption
-	portProvider     *portProvider
-	FrontendIP       string
-	UIServer         UIServer
+	Ephemeral             bool
+	DatabaseFilePath      string
+	SeedDatabase          string
+	FrontendPort          int
+	PortRetries           int
+	DynamicPorts          bool
+	Namespaces            []string
+	NamespaceWait         *bool
+	SQLitePragmas         map[string]string
+	SQLiteUnlockNotify    bool
+	Logger                log.Logger
+	ServerLogger          log.Logger
+	UpstreamOptions       []temporal.ServerOption
+	UpstreamModifiers     []func([]temporal.ServerOption) []temporal.ServerOption
+	FrontendInterceptors  []grpc.UnaryServerInterceptor
+	RecordDir             string
+	CompletionListeners   []func(WorkflowClosedEvent)
+	LifecycleListeners    []func(LifecycleEvent)
+	DynamicConfig         map[dynamicconfig.Key]interface{}
+	ReplicateTo           string
+	ReplicateInterval     time.Duration
+	ReadOnly              bool
+	CheckpointInterval    time.Duration
+	DatabaseSizeWarnings  []int64
+	Durability            string
+	ResourceProfile       string
+	ConcurrencyProfile    string
+	MemoryReportInterval  time.Duration
+	MaxMemory             uint64
+	CacheShrinkThreshold  uint64
+	MetricsExporter       string
+	MetricsEndpoint       string
+	MetricsFlushInterval  time.Duration
+	MetricsTags           map[string]string
+	MetricsPrefix         string
+	AuditLogPath          string
+	APIKeyFile            string
+	Authorizer            authorization.Authorizer
+	ClaimMapper           authorization.ClaimMapper
+	TLSCertFile           string
+	TLSKeyFile            string
+	TLSClientCAFile       string
+	CertClaimsFile        string
+	SearchAttributes      map[string]enumspb.IndexedValueType
+	NamespaceRetention    time.Duration
+	ReconcileNamespaces   bool
+	DataStoreFactory      persistenceclient.AbstractDataStoreFactory
+	MaxOpenWorkflows      int
+	IdentityRPS           float64
+	IdentityRPSOverrides  map[string]float64
+	TimeScale             float64
+	MaxHeartbeatTimeout   time.Duration
+	StuckTaskAttempts     int32
+	StuckTaskTimeout      time.Duration
+	RetentionReport       bool
+	ParentPID             int
+	FrontendListener      net.Listener
+	LocalFrontendAddress  string
+	MirrorAddress         string
+	MirrorMethods         []string
+	MirrorForward         bool
+	MirrorTLS             bool
+	IDSeed                *int64
+	CoverageReport        string
+	FaultInjection        bool
+	HistoryArchivalURI    string
+	VisibilityArchivalURI string
+	ArchivalS3Region      string
+	ArchivalS3Endpoint    string
+	portProvider          *portProvider
+	FrontendIP            string
+	BroadcastAddress      string
+	UIServer              UIServer
+}
+
+// DurabilityLevels maps each supported durability level to its SQLite synchronous pragma.
+var DurabilityLevels = map[string]string{
+	"full":   "FULL",
+	"normal": "NORMAL",
+	"off":    "OFF",
 }
 
 var SupportedPragmas = map[string]struct{}{
@@ -96,6 +201,102 @@ func NewDefaultConfig() (*Config, error) {
 	}, nil
 }
 
+// loopbackAddress returns the loopback address in the same family as ip,
+// defaulting to IPv4 when ip is empty or invalid.
+func loopbackAddress(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		return localhostIPv6
+	}
+	return localhostIPv4
+}
+
+// frontendClientAddress returns an address clients can use to reach the frontend service.
+func (o *Config) frontendClientAddress() string {
+	if ip := net.ParseIP(o.FrontendIP); ip != nil && !ip.IsUnspecified() {
+		return o.FrontendIP
+	}
+	return loopbackAddress(o.FrontendIP)
+}
+
+func hostPort(host string, port int) string {
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// tlsConfig requires clients of the frontend service to present a certificate
+// signed by TLSClientCAFile when TLS is configured. The system worker connects
+// over loopback with the frontend's own certificate.
+func (o *Config) tlsConfig() config.RootTLS {
+	if o.TLSCertFile == "" {
+		return config.RootTLS{}
+	}
+	client := config.ClientTLS{
+		RootCAFiles:             []string{o.TLSClientCAFile},
+		DisableHostVerification: true,
+	}
+	return config.RootTLS{
+		Frontend: config.GroupTLS{
+			Server: config.ServerTLS{
+				CertFile:          o.TLSCertFile,
+				KeyFile:           o.TLSKeyFile,
+				ClientCAFiles:     []string{o.TLSClientCAFile},
+				RequireClientAuth: true,
+			},
+			Client: client,
+		},
+		SystemWorker: config.WorkerTLS{
+			CertFile: o.TLSCertFile,
+			KeyFile:  o.TLSKeyFile,
+			Client:   client,
+		},
+	}
+}
+
+// MetricsExporters are the supported values for Config.MetricsExporter.
+var MetricsExporters = []string{"prometheus", "statsd", "m3"}
+
+// metricsConfig returns the metrics configuration for the configured exporter,
+// serving Prometheus metrics on prometheusAddress by default.
+func (o *Config) metricsConfig(prometheusAddress string) *metrics.Config {
+	clientConfig := metrics.ClientConfig{Tags: o.MetricsTags}
+	switch o.MetricsExporter {
+	case "statsd":
+		prefix := o.MetricsPrefix
+		if prefix == "" {
+			prefix = "temporal"
+		}
+		return &metrics.Config{
+			ClientConfig: clientConfig,
+			Statsd: &metrics.StatsdConfig{
+				HostPort:      o.MetricsEndpoint,
+				Prefix:        prefix,
+				FlushInterval: o.MetricsFlushInterval,
+			},
+		}
+	case "m3":
+		service := o.MetricsPrefix
+		if service == "" {
+			service = "temporalite"
+		}
+		return &metrics.Config{
+			ClientConfig: clientConfig,
+			M3: &m3.Configuration{
+				HostPort: o.MetricsEndpoint,
+				Service:  service,
+				Env:      "local",
+			},
+		}
+	default:
+		clientConfig.Prefix = o.MetricsPrefix
+		return &metrics.Config{
+			ClientConfig: clientConfig,
+			Prometheus: &metrics.PrometheusConfig{
+				ListenAddress: prometheusAddress,
+				HandlerPath:   "/metrics",
+			},
+		}
+	}
+}
+
 func Convert(cfg *Config) *config.Config {
 	defer func() {
 		if err := cfg.portProvider.close(); err != nil {
@@ -103,6 +304,9 @@ func Convert(cfg *Config) *config.Config {
 		}
 	}()
 
+	historyArchival, historyArchivalDefaults := cfg.historyArchival()
+	visibilityArchival, visibilityArchivalDefaults := cfg.visibilityArchival()
+
 	sqliteConfig := config.SQL{
 		PluginName:        sqlite.PluginName,
 		ConnectAttributes: make(map[string]string),
@@ -114,12 +318,34 @@ func Convert(cfg *Config) *config.Config {
 		sqliteConfig.DatabaseName = fmt.Sprintf("%d", rand.Intn(9999999))
 	} else {
 		sqliteConfig.ConnectAttributes["mode"] = "rwc"
+		// WAL lets visibility queries read while workers write.
+		sqliteConfig.ConnectAttributes["_journal_mode"] = "WAL"
 	}
 
+	durability := cfg.Durability
+	if durability == "" {
+		durability = "full"
+		if cfg.Ephemeral {
+			durability = "off"
+		}
+	}
+	sqliteConfig.ConnectAttributes["_synchronous"] = DurabilityLevels[durability]
+
 	for k, v := range cfg.SQLitePragmas {
+		if strings.EqualFold(k, "journal_mode") {
+			delete(sqliteConfig.ConnectAttributes, "_journal_mode")
+		}
+		if strings.EqualFold(k, "synchronous") {
+			delete(sqliteConfig.ConnectAttributes, "_synchronous")
+		}
 		sqliteConfig.ConnectAttributes["_"+k] = v
 	}
 
+	broadcastAddress := loopbackAddress(cfg.FrontendIP)
+	if cfg.BroadcastAddress != "" {
+		broadcastAddress = cfg.BroadcastAddress
+	}
+
 	var metricsPort, pprofPort int
 	if cfg.DynamicPorts {
 		if cfg.FrontendPort == 0 {
@@ -141,22 +367,11 @@ func Convert(cfg *Config) *config.Config {
 				MaxJoinDuration:  30 * time.Second,
 				BroadcastAddress: broadcastAddress,
 			},
-			Metrics: &metrics.Config{
-				Prometheus: &metrics.PrometheusConfig{
-					ListenAddress: fmt.Sprintf("%s:%d", broadcastAddress, metricsPort),
-					HandlerPath:   "/metrics",
-				},
-			},
-			PProf: config.PProf{Port: pprofPort},
-		},
-		Persistence: config.Persistence{
-			DefaultStore:     PersistenceStoreName,
-			VisibilityStore:  PersistenceStoreName,
-			NumHistoryShards: 1,
-			DataStores: map[string]config.DataStore{
-				PersistenceStoreName: {SQL: &sqliteConfig},
-			},
+			Metrics: cfg.metricsConfig(hostPort(loopbackAddress(cfg.FrontendIP), metricsPort)),
+			PProf:   config.PProf{Port: pprofPort},
+			TLS:     cfg.tlsConfig(),
 		},
+		Persistence: cfg.persistenceConfig(&sqliteConfig),
 		ClusterMetadata: &cluster.Config{
 			EnableGlobalNamespace:    false,
 			FailoverVersionIncrement: 10,
@@ -166,7 +381,7 @@ func Convert(cfg *Config) *config.Config {
 				"active": {
 					Enabled:                true,
 					InitialFailoverVersion: 1,
-					RPCAddress:             fmt.Sprintf("%s:%d", broadcastAddress, cfg.FrontendPort),
+					RPCAddress:             hostPort(cfg.frontendClientAddress(), cfg.FrontendPort),
 				},
 			},
 		},
@@ -180,33 +395,42 @@ func Convert(cfg *Config) *config.Config {
 			"worker":   cfg.mustGetService(3),
 		},
 		Archival: config.Archival{
-			History: config.HistoryArchival{
-				State:      "disabled",
-				EnableRead: false,
-				Provider:   nil,
-			},
-			Visibility: config.VisibilityArchival{
-				State:      "disabled",
-				EnableRead: false,
-				Provider:   nil,
-			},
+			History:    historyArchival,
+			Visibility: visibilityArchival,
 		},
 		PublicClient: config.PublicClient{
-			HostPort: fmt.Sprintf("%s:%d", broadcastAddress, cfg.FrontendPort),
+			HostPort: hostPort(cfg.frontendClientAddress(), cfg.FrontendPort),
 		},
 		NamespaceDefaults: config.NamespaceDefaults{
 			Archival: config.ArchivalNamespaceDefaults{
-				History: config.HistoryArchivalNamespaceDefaults{
-					State: "disabled",
-				},
-				Visibility: config.VisibilityArchivalNamespaceDefaults{
-					State: "disabled",
-				},
+				History:    historyArchivalDefaults,
+				Visibility: visibilityArchivalDefaults,
 			},
 		},
 	}
 }
 
+// persistenceConfig stores everything in SQLite, apart from the default store
+// when a custom data store factory is set. Upstream does not support custom
+// visibility stores, so visibility always uses SQLite.
+func (o *Config) persistenceConfig(sqliteConfig *config.SQL) config.Persistence {
+	p := config.Persistence{
+		DefaultStore:     PersistenceStoreName,
+		VisibilityStore:  PersistenceStoreName,
+		NumHistoryShards: 1,
+		DataStores: map[string]config.DataStore{
+			PersistenceStoreName: {SQL: sqliteConfig},
+		},
+	}
+	if o.DataStoreFactory != nil {
+		p.DefaultStore = customStoreName
+		p.DataStores[customStoreName] = config.DataStore{
+			CustomDataStoreConfig: &config.CustomDatastoreConfig{Name: customStoreName},
+		}
+	}
+	return p
+}
+
 func (o *Config) mustGetService(frontendPortOffset int) config.Service {
 	svc := config.Service{
 		RPC: config.RPC{
@@ -225,10 +449,15 @@ func (o *Config) mustGetService(frontendPortOffset int) config.Service {
 		svc.RPC.MembershipPort = o.portProvider.mustGetFreePort()
 	}
 
-	// Optionally bind frontend to IPv4 address
-	if frontendPortOffset == 0 && o.FrontendIP != "" {
+	// Optionally bind frontend to a specific address. When a broadcast address is
+	// set, all services must be reachable on it so they share the frontend address.
+	if (frontendPortOffset == 0 || o.BroadcastAddress != "") && o.FrontendIP != "" {
 		svc.RPC.BindOnLocalHost = false
 		svc.RPC.BindOnIP = o.FrontendIP
+	} else if loopbackAddress(o.FrontendIP) == localhostIPv6 {
+		// Upstream's BindOnLocalHost is IPv4-only
+		svc.RPC.BindOnLocalHost = false
+		svc.RPC.BindOnIP = localhostIPv6
 	}
 
 	return svc
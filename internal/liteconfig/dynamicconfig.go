@@ -0,0 +1,138 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package liteconfig
+
+import (
+	"go.temporal.io/server/common/dynamicconfig"
+)
+
+// ephemeralDynamicConfigDefaults are applied in ephemeral mode unless overridden,
+// favoring the latency of a single-node test server over multi-node throughput.
+var ephemeralDynamicConfigDefaults = map[dynamicconfig.Key]interface{}{
+	dynamicconfig.MatchingNumTaskqueueReadPartitions:  1,
+	dynamicconfig.MatchingNumTaskqueueWritePartitions: 1,
+}
+
+// unlockNotifyDynamicConfigDefaults are added to the ephemeral defaults when the
+// SQLite driver waits for table locks. Upstream reads each history task queue
+// at most 20 times a second, which adds up to 50ms to the dispatch of every
+// workflow and activity task. Without unlock notify, reading faster makes
+// matching's writes fail on table locks often enough that tasks are lost until
+// the next full read a minute later.
+var unlockNotifyDynamicConfigDefaults = map[dynamicconfig.Key]interface{}{
+	dynamicconfig.TransferProcessorMaxPollRPS:   1000,
+	dynamicconfig.TimerProcessorMaxPollRPS:      1000,
+	dynamicconfig.VisibilityProcessorMaxPollRPS: 1000,
+}
+
+// ResourceProfiles size history caches and task processors. Upstream defaults
+// target clusters serving many users; "large" keeps them unchanged.
+var ResourceProfiles = map[string]map[dynamicconfig.Key]interface{}{
+	"small": {
+		dynamicconfig.HistoryCacheInitialSize:   16,
+		dynamicconfig.HistoryCacheMaxSize:       64,
+		dynamicconfig.EventsCacheInitialSize:    16,
+		dynamicconfig.EventsCacheMaxSize:        64,
+		dynamicconfig.TimerTaskWorkerCount:      2,
+		dynamicconfig.TransferTaskWorkerCount:   2,
+		dynamicconfig.VisibilityTaskWorkerCount: 2,
+		dynamicconfig.ReplicatorTaskWorkerCount: 1,
+	},
+	"medium": {
+		dynamicconfig.HistoryCacheInitialSize:   64,
+		dynamicconfig.HistoryCacheMaxSize:       256,
+		dynamicconfig.EventsCacheInitialSize:    64,
+		dynamicconfig.EventsCacheMaxSize:        256,
+		dynamicconfig.TimerTaskWorkerCount:      4,
+		dynamicconfig.TransferTaskWorkerCount:   4,
+		dynamicconfig.VisibilityTaskWorkerCount: 4,
+		dynamicconfig.ReplicatorTaskWorkerCount: 1,
+	},
+	"large": {},
+}
+
+// DefaultResourceProfile is used when no resource profile is configured.
+const DefaultResourceProfile = "medium"
+
+// ConcurrencyProfiles raise the rate limits, task processor pools, and matching
+// batch sizes that bound how fast work is dispatched, for load testing. "default"
+// keeps upstream's values. A profile's worker counts take precedence over the
+// resource profile's.
+var ConcurrencyProfiles = map[string]map[dynamicconfig.Key]interface{}{
+	"default": {},
+	"high": {
+		dynamicconfig.FrontendRPS:                             10000,
+		dynamicconfig.FrontendMaxNamespaceRPSPerInstance:      10000,
+		dynamicconfig.MatchingRPS:                             5000,
+		dynamicconfig.HistoryRPS:                              12000,
+		dynamicconfig.FrontendPersistenceMaxQPS:               8000,
+		dynamicconfig.MatchingPersistenceMaxQPS:               12000,
+		dynamicconfig.HistoryPersistenceMaxQPS:                36000,
+		dynamicconfig.TransferTaskWorkerCount:                 16,
+		dynamicconfig.TimerTaskWorkerCount:                    16,
+		dynamicconfig.TransferTaskBatchSize:                   200,
+		dynamicconfig.TimerTaskBatchSize:                      200,
+		dynamicconfig.TransferProcessorMaxPollRPS:             100,
+		dynamicconfig.TimerProcessorMaxPollRPS:                100,
+		dynamicconfig.MatchingGetTasksBatchSize:               200,
+		dynamicconfig.MatchingMaxTaskBatchSize:                200,
+		dynamicconfig.MatchingOutstandingTaskAppendsThreshold: 1000,
+	},
+	"max": {
+		dynamicconfig.FrontendRPS:                             100000,
+		dynamicconfig.FrontendMaxNamespaceRPSPerInstance:      100000,
+		dynamicconfig.MatchingRPS:                             100000,
+		dynamicconfig.HistoryRPS:                              100000,
+		dynamicconfig.FrontendPersistenceMaxQPS:               100000,
+		dynamicconfig.MatchingPersistenceMaxQPS:               100000,
+		dynamicconfig.HistoryPersistenceMaxQPS:                100000,
+		dynamicconfig.TransferTaskWorkerCount:                 32,
+		dynamicconfig.TimerTaskWorkerCount:                    32,
+		dynamicconfig.TransferTaskBatchSize:                   500,
+		dynamicconfig.TimerTaskBatchSize:                      500,
+		dynamicconfig.TransferProcessorMaxPollRPS:             1000,
+		dynamicconfig.TimerProcessorMaxPollRPS:                1000,
+		dynamicconfig.MatchingGetTasksBatchSize:               500,
+		dynamicconfig.MatchingMaxTaskBatchSize:                500,
+		dynamicconfig.MatchingOutstandingTaskAppendsThreshold: 5000,
+	},
+}
+
+// NewDynamicConfigClient returns a dynamic config client holding the configured
+// values, which may be changed while the server is running.
+func NewDynamicConfigClient(cfg *Config) *dynamicconfig.MutableEphemeralClient {
+	defaults := map[dynamicconfig.Key]interface{}{}
+	profile := cfg.ResourceProfile
+	if profile == "" {
+		profile = DefaultResourceProfile
+	}
+	for k, v := range ResourceProfiles[profile] {
+		defaults[k] = v
+	}
+	for k, v := range ConcurrencyProfiles[cfg.ConcurrencyProfile] {
+		defaults[k] = v
+	}
+	if cfg.Ephemeral {
+		for k, v := range ephemeralDynamicConfigDefaults {
+			defaults[k] = v
+		}
+		if cfg.SQLiteUnlockNotify {
+			for k, v := range unlockNotifyDynamicConfigDefaults {
+				defaults[k] = v
+			}
+		}
+	}
+
+	var mutations []dynamicconfig.Mutation
+	for k, v := range defaults {
+		if _, ok := cfg.DynamicConfig[k]; !ok {
+			mutations = append(mutations, dynamicconfig.Set(k, v))
+		}
+	}
+	for k, v := range cfg.DynamicConfig {
+		mutations = append(mutations, dynamicconfig.Set(k, v))
+	}
+	return dynamicconfig.NewMutableEphemeralClient(mutations...)
+}
@@ -0,0 +1,43 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package liteconfig
+
+import (
+	"testing"
+
+	"go.temporal.io/server/common/dynamicconfig"
+)
+
+func TestNewDynamicConfigClientPollRate(t *testing.T) {
+	const upstreamDefault = 20
+	tests := []struct {
+		name string
+		cfg  Config
+		want int
+	}{
+		{name: "file", cfg: Config{SQLiteUnlockNotify: true}, want: upstreamDefault},
+		{name: "ephemeral", cfg: Config{Ephemeral: true}, want: upstreamDefault},
+		{name: "ephemeral with unlock notify", cfg: Config{Ephemeral: true, SQLiteUnlockNotify: true}, want: 1000},
+		{
+			name: "overridden",
+			cfg: Config{
+				Ephemeral:          true,
+				SQLiteUnlockNotify: true,
+				DynamicConfig:      map[dynamicconfig.Key]interface{}{dynamicconfig.TransferProcessorMaxPollRPS: 50},
+			},
+			want: 50,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewDynamicConfigClient(&tc.cfg)
+			// Unset keys return the default along with an error.
+			got, _ := c.GetIntValue(dynamicconfig.TransferProcessorMaxPollRPS, nil, upstreamDefault)
+			if got != tc.want {
+				t.Errorf("TransferProcessorMaxPollRPS = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
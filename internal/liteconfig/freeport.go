@@ -5,8 +5,10 @@
 package liteconfig
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"syscall"
 )
 
 // Modified from https://github.com/phayes/freeport/blob/95f893ade6f232a5f1511d61735d89b1ae2df543/freeport.go
@@ -17,18 +19,14 @@ type portProvider struct {
 
 // getFreePort asks the kernel for a free open port that is ready to use.
 func (p *portProvider) getFreePort() (int, error) {
-	addr, err := net.ResolveTCPAddr("tcp", "127.0.0.1:0")
+	l, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.ParseIP(localhostIPv4)})
 	if err != nil {
-		if addr, err = net.ResolveTCPAddr("tcp6", "[::1]:0"); err != nil {
-			panic(fmt.Sprintf("temporalite: failed to get free port: %v", err))
+		// Fall back to IPv6 when IPv4 loopback is unavailable
+		if l, err = net.ListenTCP("tcp", &net.TCPAddr{IP: net.ParseIP(localhostIPv6)}); err != nil {
+			return 0, err
 		}
 	}
 
-	l, err := net.ListenTCP("tcp", addr)
-	if err != nil {
-		return 0, err
-	}
-
 	p.listeners = append(p.listeners, l)
 
 	return l.Addr().(*net.TCPAddr).Port, nil
@@ -50,3 +48,48 @@ func (p *portProvider) close() error {
 	}
 	return nil
 }
+
+// servicePortOffsets lists the offsets from the frontend port of every port
+// bound when dynamic ports are disabled.
+var servicePortOffsets = []int{0, 1, 2, 3, 100, 101, 102, 103, 200, 201}
+
+// FindAvailablePort returns the first frontend port starting at port for which
+// all derived service ports can be bound, trying up to retries successive ports.
+// Ports are only skipped when they are in use; other failures to bind, such as
+// an address missing from every interface, are returned right away. With no
+// retries, the error binding the first unavailable port is returned.
+func FindAvailablePort(ip string, port int, retries int) (int, error) {
+	if ip == "" {
+		ip = loopbackAddress(ip)
+	}
+	var err error
+	for candidate := port; candidate <= port+retries; candidate++ {
+		if err = bindPorts(ip, candidate); err == nil {
+			return candidate, nil
+		}
+		if !errors.Is(err, syscall.EADDRINUSE) {
+			return 0, err
+		}
+	}
+	if retries == 0 {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no available port found in range %d-%d: %w", port, port+retries, err)
+}
+
+// bindPorts returns the error binding the first of the service ports derived
+// from frontendPort that cannot be bound.
+func bindPorts(ip string, frontendPort int) error {
+	for _, offset := range servicePortOffsets {
+		host := loopbackAddress(ip)
+		if offset == 0 {
+			host = ip
+		}
+		l, err := net.Listen("tcp", hostPort(host, frontendPort+offset))
+		if err != nil {
+			return err
+		}
+		_ = l.Close()
+	}
+	return nil
+}
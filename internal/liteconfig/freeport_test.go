@@ -0,0 +1,72 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package liteconfig
+
+import (
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+)
+
+// freeBasePort returns a frontend port for which it and the next frontend port
+// have all their service ports free.
+func freeBasePort(t *testing.T) int {
+	t.Helper()
+	for i := 0; i < 20; i++ {
+		var p portProvider
+		port := p.mustGetFreePort()
+		_ = p.close()
+		if bindPorts(localhostIPv4, port) == nil && bindPorts(localhostIPv4, port+1) == nil {
+			return port
+		}
+	}
+	t.Fatal("no free ports found")
+	return 0
+}
+
+func TestFindAvailablePort(t *testing.T) {
+	tests := []struct {
+		name       string
+		ip         string
+		retries    int
+		occupied   []int // offsets from the base port to hold
+		wantOffset int
+		wantInUse  bool
+		wantErr    bool
+	}{
+		{name: "free", ip: localhostIPv4},
+		{name: "default address"},
+		{name: "frontend in use", ip: localhostIPv4, occupied: []int{0}, wantErr: true, wantInUse: true},
+		{name: "frontend in use with retries", ip: localhostIPv4, retries: 1, occupied: []int{0}, wantOffset: 1},
+		{name: "service port in use with retries", ip: localhostIPv4, retries: 1, occupied: []int{100}, wantOffset: 1},
+		{name: "all retries in use", ip: localhostIPv4, retries: 1, occupied: []int{0, 1}, wantErr: true, wantInUse: true},
+		// 192.0.2.0/24 is reserved for documentation, so no interface has it.
+		{name: "address not available", ip: "192.0.2.1", retries: 5, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			base := freeBasePort(t)
+			for _, offset := range tc.occupied {
+				l, err := net.Listen("tcp", hostPort(localhostIPv4, base+offset))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer l.Close()
+			}
+
+			got, err := FindAvailablePort(tc.ip, base, tc.retries)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("FindAvailablePort() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if inUse := errors.Is(err, syscall.EADDRINUSE); inUse != tc.wantInUse {
+				t.Errorf("FindAvailablePort() error = %v, want in use %v", err, tc.wantInUse)
+			}
+			if !tc.wantErr && got != base+tc.wantOffset {
+				t.Errorf("FindAvailablePort() = %d, want %d", got, base+tc.wantOffset)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+// Package recording captures frontend API calls to disk and re-issues them
+// against another server.
+package recording
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/gogo/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Entry is a single recorded API call.
+type Entry struct {
+	Time         time.Time           `json:"time"`
+	Duration     time.Duration       `json:"duration"`
+	Method       string              `json:"method"`
+	Metadata     map[string][]string `json:"metadata,omitempty"`
+	RequestType  string              `json:"requestType"`
+	Request      json.RawMessage     `json:"request"`
+	ResponseType string              `json:"responseType,omitempty"`
+	Response     json.RawMessage     `json:"response,omitempty"`
+	Code         string              `json:"code"`
+	Error        string              `json:"error,omitempty"`
+}
+
+// Recorder is a gRPC server interceptor that appends each call it observes to
+// a JSON lines file.
+type Recorder struct {
+	mu   sync.Mutex
+	f    *os.File
+	enc  *json.Encoder
+	path string
+}
+
+// NewRecorder creates dir if needed and opens a new recording file within it.
+func NewRecorder(dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating record directory: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("requests-%d.jsonl", time.Now().UnixNano()))
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("error creating recording file: %w", err)
+	}
+	return &Recorder{f: f, enc: json.NewEncoder(f), path: path}, nil
+}
+
+// Path returns the location of the recording file.
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// Close flushes and closes the recording file.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
+
+func (r *Recorder) Intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	entry := Entry{
+		Time:     start,
+		Duration: time.Since(start),
+		Method:   info.FullMethod,
+		Code:     status.Code(err).String(),
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		entry.Metadata = make(map[string][]string, len(md))
+		for k, v := range md {
+			// Never persist credentials
+			if k != "authorization" {
+				entry.Metadata[k] = v
+			}
+		}
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	entry.RequestType, entry.Request = marshal(req)
+	if err == nil {
+		entry.ResponseType, entry.Response = marshal(resp)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Recording is best effort and must never fail the call
+	_ = r.enc.Encode(&entry)
+
+	return resp, err
+}
+
+func marshal(v interface{}) (string, json.RawMessage) {
+	msg, ok := v.(proto.Message)
+	if !ok || reflect.ValueOf(msg).IsNil() {
+		return "", nil
+	}
+	var m jsonpb.Marshaler
+	s, err := m.MarshalToString(msg)
+	if err != nil {
+		return proto.MessageName(msg), nil
+	}
+	return proto.MessageName(msg), json.RawMessage(s)
+}
+
+// ReplayOptions configures Replay.
+type ReplayOptions struct {
+	// IncludePolls replays long-poll calls, which would otherwise block until their timeout.
+	IncludePolls bool
+	// Timeout bounds each replayed call.
+	Timeout time.Duration
+}
+
+// Replay re-issues each call recorded at path on conn in order, writing a
+// summary line per call to out.
+func Replay(ctx context.Context, conn *grpc.ClientConn, path string, opts ReplayOptions, out io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if !opts.IncludePolls && strings.Contains(entry.Method, "/Poll") {
+			fmt.Fprintf(out, "%d %s SKIPPED\n", line, entry.Method)
+			continue
+		}
+
+		req, err := newMessage(entry.RequestType, entry.Request)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		respType := entry.ResponseType
+		if respType == "" {
+			// Only recorded for successful calls
+			respType = strings.TrimSuffix(entry.RequestType, "Request") + "Response"
+		}
+		resp, err := newMessage(respType, nil)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		code := invoke(ctx, conn, entry, req, resp, opts.Timeout)
+		result := "OK"
+		if code != entry.Code {
+			result = "MISMATCH"
+		}
+		fmt.Fprintf(out, "%d %s recorded=%s replayed=%s %s\n", line, entry.Method, entry.Code, code, result)
+	}
+	return scanner.Err()
+}
+
+func invoke(ctx context.Context, conn *grpc.ClientConn, entry Entry, req, resp proto.Message, timeout time.Duration) string {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	md := metadata.MD{}
+	for k, v := range entry.Metadata {
+		// Transport-level headers are set by the client connection itself
+		if !strings.HasPrefix(k, ":") && k != "content-type" && k != "user-agent" {
+			md[k] = v
+		}
+	}
+	ctx = metadata.NewOutgoingContext(ctx, md)
+	return status.Code(conn.Invoke(ctx, entry.Method, req, resp)).String()
+}
+
+// newMessage instantiates the registered proto message type name, populated from data if set.
+func newMessage(name string, data json.RawMessage) (proto.Message, error) {
+	t := proto.MessageType(name)
+	if t == nil {
+		return nil, fmt.Errorf("unknown message type %q", name)
+	}
+	msg := reflect.New(t.Elem()).Interface().(proto.Message)
+	if len(data) > 0 {
+		if err := jsonpb.UnmarshalString(string(data), msg); err != nil {
+			return nil, fmt.Errorf("error decoding %s: %w", name, err)
+		}
+	}
+	return msg, nil
+}
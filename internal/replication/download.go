@@ -0,0 +1,98 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package replication
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// Download copies the database snapshot at source to the file dst. Source may
+// be an s3://bucket/key, gs://bucket/key, or http(s):// URL, or a local path.
+func Download(ctx context.Context, source string, dst string) error {
+	u, err := url.Parse(source)
+	if err != nil {
+		return fmt.Errorf("invalid snapshot source %q: %w", source, err)
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch u.Scheme {
+	case "s3":
+		sess, err := session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable})
+		if err != nil {
+			return fmt.Errorf("unable to create aws session: %w", err)
+		}
+		_, err = s3manager.NewDownloader(sess).DownloadWithContext(ctx, f, &s3.GetObjectInput{
+			Bucket: aws.String(u.Host),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return err
+		}
+	case "gs":
+		c, err := storage.NewClient(ctx)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		r, err := c.Bucket(u.Host).Object(key).NewReader(ctx)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		if _, err := io.Copy(f, r); err != nil {
+			return err
+		}
+	case "http", "https":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unable to download %s: %s", source, resp.Status)
+		}
+		if _, err := io.Copy(f, resp.Body); err != nil {
+			return err
+		}
+	case "", "file":
+		path := source
+		if u.Scheme == "file" {
+			path = u.Path
+		}
+		r, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		if _, err := io.Copy(f, r); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported snapshot source scheme %q, expected s3, gs, http, or https", u.Scheme)
+	}
+	return f.Close()
+}
@@ -0,0 +1,165 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+// Package replication periodically copies a SQLite database to object storage.
+package replication
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	_ "github.com/mattn/go-sqlite3" // register sqlite3 driver
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/log/tag"
+)
+
+// DefaultInterval is how often a snapshot is uploaded when no interval is configured.
+const DefaultInterval = time.Minute
+
+type uploader interface {
+	upload(ctx context.Context, key string, r io.Reader) error
+}
+
+// Replicator uploads consistent snapshots of a SQLite database to an s3:// or
+// gs:// destination.
+type Replicator struct {
+	dbPath   string
+	key      string
+	interval time.Duration
+	logger   log.Logger
+	dest     uploader
+}
+
+// New returns a Replicator that copies the database at dbPath to destination,
+// which must be of the form s3://bucket/prefix or gs://bucket/prefix.
+//
+// The snapshot is written to <prefix>/<database file name>, overwriting the
+// previous snapshot.
+func New(destination string, dbPath string, interval time.Duration, logger log.Logger) (*Replicator, error) {
+	u, err := url.Parse(destination)
+	if err != nil {
+		return nil, fmt.Errorf("invalid replication destination %q: %w", destination, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid replication destination %q: missing bucket", destination)
+	}
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	r := &Replicator{
+		dbPath:   dbPath,
+		key:      path.Join(strings.Trim(u.Path, "/"), filepath.Base(dbPath)),
+		interval: interval,
+		logger:   logger,
+	}
+	switch u.Scheme {
+	case "s3":
+		sess, err := session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable})
+		if err != nil {
+			return nil, fmt.Errorf("unable to create aws session: %w", err)
+		}
+		r.dest = &s3Uploader{bucket: u.Host, uploader: s3manager.NewUploader(sess)}
+	case "gs":
+		r.dest = &gcsUploader{bucket: u.Host}
+	default:
+		return nil, fmt.Errorf("unsupported replication destination scheme %q, expected s3 or gs", u.Scheme)
+	}
+	return r, nil
+}
+
+// Run uploads a snapshot every interval until ctx is done.
+func (r *Replicator) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := r.Replicate(ctx); err != nil {
+			r.logger.Error("Unable to replicate database", tag.Error(err))
+		}
+	}
+}
+
+// Replicate takes a snapshot of the database and uploads it.
+func (r *Replicator) Replicate(ctx context.Context) error {
+	dir, err := os.MkdirTemp("", "temporalite-replica-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	snapshot := filepath.Join(dir, filepath.Base(r.dbPath))
+	if err := r.snapshot(ctx, snapshot); err != nil {
+		return fmt.Errorf("unable to snapshot database: %w", err)
+	}
+
+	f, err := os.Open(snapshot)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return r.dest.upload(ctx, r.key, f)
+}
+
+// snapshot writes a transactionally consistent copy of the database to dst.
+// VACUUM INTO reads within a single transaction, so concurrent writes by the
+// server neither block nor corrupt the copy.
+func (r *Replicator) snapshot(ctx context.Context, dst string) error {
+	db, err := sql.Open("sqlite3", "file:"+r.dbPath+"?mode=ro&_busy_timeout=5000")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.ExecContext(ctx, "VACUUM INTO ?", dst)
+	return err
+}
+
+type s3Uploader struct {
+	bucket   string
+	uploader *s3manager.Uploader
+}
+
+func (u *s3Uploader) upload(ctx context.Context, key string, r io.Reader) error {
+	_, err := u.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(u.bucket),
+		Key:    aws.String(key),
+		Body:   r,
+	})
+	return err
+}
+
+type gcsUploader struct {
+	bucket string
+}
+
+func (u *gcsUploader) upload(ctx context.Context, key string, r io.Reader) error {
+	c, err := storage.NewClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	w := c.Bucket(u.bucket).Object(key).NewWriter(ctx)
+	if _, err := io.Copy(w, r); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
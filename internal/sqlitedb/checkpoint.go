@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+// Package sqlitedb contains maintenance operations on temporalite's SQLite database file.
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3" // register sqlite3 driver
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/log/tag"
+)
+
+// CheckpointResult reports the outcome of a WAL checkpoint.
+type CheckpointResult struct {
+	// Busy is true if the checkpoint could not complete because of concurrent readers or writers.
+	Busy bool
+	// LogFrames is the number of frames in the write-ahead log.
+	LogFrames int
+	// CheckpointedFrames is the number of frames copied back into the database file.
+	CheckpointedFrames int
+}
+
+// Open opens the database file at path for maintenance, waiting up to five
+// seconds for locks held by a running server.
+func Open(path string) (*sql.DB, error) {
+	return sql.Open("sqlite3", "file:"+path+"?mode=rw&_busy_timeout=5000")
+}
+
+// Checkpoint copies the write-ahead log of the database at path back into the
+// database file and truncates the log.
+//
+// This is safe to run while a server is using the database.
+func Checkpoint(ctx context.Context, path string) (CheckpointResult, error) {
+	db, err := Open(path)
+	if err != nil {
+		return CheckpointResult{}, err
+	}
+	defer db.Close()
+
+	var (
+		result CheckpointResult
+		busy   int
+	)
+	err = db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &result.LogFrames, &result.CheckpointedFrames)
+	if err != nil {
+		return CheckpointResult{}, err
+	}
+	result.Busy = busy != 0
+	if result.LogFrames < 0 {
+		return result, errors.New("database is not in WAL journal mode")
+	}
+	return result, nil
+}
+
+// RunCheckpoints checkpoints the database at path every interval until ctx is done.
+func RunCheckpoints(ctx context.Context, path string, interval time.Duration, logger log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		result, err := Checkpoint(ctx, path)
+		if err != nil {
+			logger.Error("Unable to checkpoint database", tag.Error(err))
+		} else if result.Busy {
+			logger.Debug("Database checkpoint incomplete due to concurrent access")
+		}
+	}
+}
@@ -0,0 +1,283 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/server/common/persistence/serialization"
+	"go.temporal.io/server/common/primitives"
+)
+
+// runTables hold the mutable state of a workflow run, keyed by shard,
+// namespace, workflow ID, and run ID.
+var runTables = []string{
+	"executions",
+	"activity_info_maps",
+	"timer_info_maps",
+	"child_execution_info_maps",
+	"request_cancel_info_maps",
+	"signal_info_maps",
+	"signals_requested_sets",
+	"buffered_events",
+}
+
+// ErrInUse is returned by DeleteExpiredRuns when a server is using the database.
+var ErrInUse = errors.New("database is in use by a running server")
+
+// serverHeartbeatCutoff is how recently a running server has recorded a
+// membership heartbeat; upstream heartbeats every 10 to 15 seconds.
+const serverHeartbeatCutoff = 20 * time.Second
+
+// taskTables hold the history tasks of every run in a shard, each referring to
+// its run from its serialized data.
+var taskTables = []struct {
+	name   string
+	decode func(data []byte, encoding string) (runID string, err error)
+}{
+	{"transfer_tasks", func(data []byte, encoding string) (string, error) {
+		info, err := serialization.TransferTaskInfoFromBlob(data, encoding)
+		return info.GetRunId(), err
+	}},
+	{"timer_tasks", func(data []byte, encoding string) (string, error) {
+		info, err := serialization.TimerTaskInfoFromBlob(data, encoding)
+		return info.GetRunId(), err
+	}},
+	{"visibility_tasks", func(data []byte, encoding string) (string, error) {
+		info, err := serialization.VisibilityTaskInfoFromBlob(data, encoding)
+		return info.GetRunId(), err
+	}},
+}
+
+// DeleteExpiredRuns deletes the closed runs of namespace that closed longer
+// than its current retention period before now from the database at path,
+// along with their history tasks, and returns them. Runs closed under a longer
+// retention period are deleted too.
+//
+// Rows are deleted directly rather than through a server, so the database must
+// not be in use: ErrInUse is returned if a server has recently recorded a
+// heartbeat, and the deletion holds an exclusive lock that keeps servers from
+// starting until it completes. Runs are not archived, so namespaces with
+// history archival enabled are refused.
+func DeleteExpiredRuns(ctx context.Context, path, namespace string, now time.Time) ([]ClosedRun, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=rw&_busy_timeout=5000&_txlock=exclusive")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := checkNotInUse(ctx, tx, now); err != nil {
+		return nil, err
+	}
+
+	var (
+		namespaceID []byte
+		data        []byte
+		encoding    string
+	)
+	err = tx.QueryRowContext(ctx, "SELECT id, data, data_encoding FROM namespaces WHERE name = ?", namespace).Scan(&namespaceID, &data, &encoding)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("namespace %s not found", namespace)
+	} else if err != nil {
+		return nil, err
+	}
+	detail, err := serialization.NewSerializer().NamespaceDetailFromBlob(&commonpb.DataBlob{
+		Data:         data,
+		EncodingType: enumspb.EncodingType(enumspb.EncodingType_value[encoding]),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if detail.GetConfig().GetHistoryArchivalState() == enumspb.ARCHIVAL_STATE_ENABLED {
+		return nil, fmt.Errorf("namespace %s has history archival enabled, which deleting workflows right away would skip", namespace)
+	}
+	var retention time.Duration
+	if r := detail.GetConfig().GetRetention(); r != nil {
+		retention = *r
+	}
+
+	id := primitives.UUIDString(namespaceID)
+	rows, err := tx.QueryContext(ctx, "SELECT workflow_id, run_id, close_time FROM executions_visibility WHERE namespace_id = ? AND close_time IS NOT NULL", id)
+	if err != nil {
+		return nil, err
+	}
+	var runs []ClosedRun
+	for rows.Next() {
+		run := ClosedRun{NamespaceID: id}
+		if err := rows.Scan(&run.WorkflowID, &run.RunID, &run.CloseTime); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if run.CloseTime.Add(retention).Before(now) {
+			runs = append(runs, run)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	deleted := make(map[string]bool, len(runs))
+	for _, run := range runs {
+		if err := deleteClosedRun(ctx, tx, run); err != nil {
+			return nil, fmt.Errorf("unable to delete run %s of workflow %s: %w", run.RunID, run.WorkflowID, err)
+		}
+		deleted[run.RunID] = true
+	}
+	if err := deleteRunTasks(ctx, tx, deleted); err != nil {
+		return nil, fmt.Errorf("unable to delete history tasks: %w", err)
+	}
+	return runs, tx.Commit()
+}
+
+// checkNotInUse returns ErrInUse if a server recorded a membership heartbeat
+// in the database within serverHeartbeatCutoff of now.
+func checkNotInUse(ctx context.Context, tx *sql.Tx, now time.Time) error {
+	rows, err := tx.QueryContext(ctx, "SELECT last_heartbeat FROM cluster_membership")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var heartbeat time.Time
+		if err := rows.Scan(&heartbeat); err != nil {
+			return err
+		}
+		if heartbeat.After(now.Add(-serverHeartbeatCutoff)) {
+			return ErrInUse
+		}
+	}
+	return rows.Err()
+}
+
+// deleteRunTasks deletes the history tasks of the runs in runIDs, which would
+// otherwise be left for history to process against missing runs.
+func deleteRunTasks(ctx context.Context, tx *sql.Tx, runIDs map[string]bool) error {
+	if len(runIDs) == 0 {
+		return nil
+	}
+	for _, table := range taskTables {
+		rows, err := tx.QueryContext(ctx, "SELECT rowid, data, data_encoding FROM "+table.name)
+		if err != nil {
+			return err
+		}
+		var stale []int64
+		for rows.Next() {
+			var (
+				rowID    int64
+				data     []byte
+				encoding string
+			)
+			if err := rows.Scan(&rowID, &data, &encoding); err != nil {
+				rows.Close()
+				return err
+			}
+			runID, err := table.decode(data, encoding)
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("%s: %w", table.name, err)
+			}
+			if runIDs[runID] {
+				stale = append(stale, rowID)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for _, rowID := range stale {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM "+table.name+" WHERE rowid = ?", rowID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func deleteClosedRun(ctx context.Context, tx *sql.Tx, run ClosedRun) error {
+	namespaceID, err := primitives.ParseUUID(run.NamespaceID)
+	if err != nil {
+		return err
+	}
+	runID, err := primitives.ParseUUID(run.RunID)
+	if err != nil {
+		return err
+	}
+
+	var (
+		shardID  int32
+		data     []byte
+		encoding string
+	)
+	err = tx.QueryRowContext(ctx, "SELECT shard_id, data, data_encoding FROM executions WHERE namespace_id = ? AND workflow_id = ? AND run_id = ?",
+		namespaceID, run.WorkflowID, runID).Scan(&shardID, &data, &encoding)
+	if err == sql.ErrNoRows {
+		// History was deleted already; only the visibility record may remain.
+		return deleteVisibility(ctx, tx, run)
+	} else if err != nil {
+		return err
+	}
+	info, err := serialization.WorkflowExecutionInfoFromBlob(data, encoding)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range runTables {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE shard_id = ? AND namespace_id = ? AND workflow_id = ? AND run_id = ?",
+			shardID, namespaceID, run.WorkflowID, runID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM current_executions WHERE shard_id = ? AND namespace_id = ? AND workflow_id = ? AND run_id = ?",
+		shardID, namespaceID, run.WorkflowID, runID); err != nil {
+		return err
+	}
+
+	for _, history := range info.GetVersionHistories().GetHistories() {
+		branch, err := serialization.HistoryBranchFromBlob(history.GetBranchToken(), enumspb.ENCODING_TYPE_PROTO3.String())
+		if err != nil {
+			return err
+		}
+		treeID, err := primitives.ParseUUID(branch.GetTreeId())
+		if err != nil {
+			return err
+		}
+		branchID, err := primitives.ParseUUID(branch.GetBranchId())
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM history_tree WHERE shard_id = ? AND tree_id = ? AND branch_id = ?", shardID, treeID, branchID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM history_node WHERE shard_id = ? AND tree_id = ? AND branch_id = ?", shardID, treeID, branchID); err != nil {
+			return err
+		}
+		// Ancestor branches are shared with runs reset from this one, so their
+		// events are only deleted along with the last branch of the tree.
+		if _, err := tx.ExecContext(ctx, "DELETE FROM history_node WHERE shard_id = ? AND tree_id = ? AND NOT EXISTS "+
+			"(SELECT 1 FROM history_tree WHERE shard_id = ? AND tree_id = ?)", shardID, treeID, shardID, treeID); err != nil {
+			return err
+		}
+	}
+
+	return deleteVisibility(ctx, tx, run)
+}
+
+func deleteVisibility(ctx context.Context, tx *sql.Tx, run ClosedRun) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM executions_visibility WHERE namespace_id = ? AND run_id = ?", run.NamespaceID, run.RunID)
+	return err
+}
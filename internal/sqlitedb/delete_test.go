@@ -0,0 +1,117 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package sqlitedb_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/server/common/log"
+
+	"github.com/DataDog/temporalite"
+	"github.com/DataDog/temporalite/internal/sqlitedb"
+)
+
+// startServer starts a server storing its state in a new database file and
+// terminates a workflow in the default namespace, leaving one closed run.
+func startServer(t *testing.T) (*temporalite.Server, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "db.sqlite")
+	s, err := temporalite.NewServer(
+		temporalite.WithDatabaseFilePath(path),
+		temporalite.WithNamespaces("default"),
+		temporalite.WithDynamicPorts(),
+		temporalite.WithLogger(log.NewNoopLogger()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := s.AwaitNamespace(ctx, "default"); err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.NewClientWithOptions(ctx, client.Options{Namespace: "default", Logger: log.NewSdkLogger(log.NewNoopLogger())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	run, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{ID: "expired", TaskQueue: "none"}, "Never")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.TerminateWorkflow(ctx, run.GetID(), run.GetRunID(), "done"); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		resp, err := c.ListClosedWorkflow(ctx, &workflowservice.ListClosedWorkflowExecutionsRequest{Namespace: "default"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.GetExecutions()) > 0 {
+			return s, path
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("terminated workflow not visible")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func TestDeleteExpiredRuns(t *testing.T) {
+	s, path := startServer(t)
+	s.Stop()
+	ctx := context.Background()
+
+	// Closed runs are kept for a day by default. The stopped server's last
+	// heartbeat is recent, so look past it.
+	runs, err := sqlitedb.DeleteExpiredRuns(ctx, path, "default", time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 0 {
+		t.Errorf("deleted %d runs before retention passed, want 0", len(runs))
+	}
+
+	later := time.Now().Add(48 * time.Hour)
+	runs, err = sqlitedb.DeleteExpiredRuns(ctx, path, "default", later)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].WorkflowID != "expired" {
+		t.Errorf("deleted %v, want the expired run", runs)
+	}
+	closed, err := sqlitedb.ClosedRuns(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(closed) != 0 {
+		t.Errorf("%d closed runs remain after deletion, want 0", len(closed))
+	}
+
+	if _, err := sqlitedb.DeleteExpiredRuns(ctx, path, "missing", later); err == nil {
+		t.Error("deleting from a missing namespace succeeded, want error")
+	}
+}
+
+func TestDeleteExpiredRunsInUse(t *testing.T) {
+	s, path := startServer(t)
+	defer s.Stop()
+
+	_, err := sqlitedb.DeleteExpiredRuns(context.Background(), path, "default", time.Now())
+	if !errors.Is(err, sqlitedb.ErrInUse) {
+		t.Errorf("DeleteExpiredRuns() error = %v, want ErrInUse", err)
+	}
+}
@@ -0,0 +1,61 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package sqlitedb
+
+import (
+	"context"
+)
+
+// schemaTables lists tables of the upstream SQLite schema a usable database must contain.
+var schemaTables = []string{
+	"namespaces",
+	"namespace_metadata",
+	"shards",
+	"executions",
+	"current_executions",
+	"tasks",
+	"task_queues",
+	"history_node",
+	"history_tree",
+	"cluster_metadata_info",
+	"cluster_membership",
+	"executions_visibility",
+}
+
+// MissingTables returns the tables of the Temporal schema absent from the
+// database at path.
+func MissingTables(ctx context.Context, path string) ([]string, error) {
+	db, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table'")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, name := range schemaTables {
+		if !tables[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,66 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package sqlitedb
+
+import (
+	"context"
+	"os"
+)
+
+// NamespaceSize is the space taken by the workflow histories of one namespace.
+type NamespaceSize struct {
+	Namespace    string
+	HistoryBytes int64
+}
+
+// FileSize returns the size of the database file at path, including its
+// write-ahead log.
+func FileSize(path string) (int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+	if wal, err := os.Stat(path + "-wal"); err == nil {
+		size += wal.Size()
+	}
+	return size, nil
+}
+
+// LargestNamespaces returns up to limit namespaces of the database at path,
+// largest first, sized by the history events of their workflow runs.
+//
+// History trees are attributed to the run that created them, so the events of
+// runs reset from a run whose mutable state was deleted are not counted.
+//
+// This opens a second connection to the database and reads every history node,
+// which takes time proportional to the size of all histories, so callers should
+// bound ctx and call it rarely.
+func LargestNamespaces(ctx context.Context, path string, limit int) ([]NamespaceSize, error) {
+	db, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, "SELECT n.name, SUM(LENGTH(h.data)) AS size FROM history_node h "+
+		"JOIN executions e ON e.shard_id = h.shard_id AND e.run_id = h.tree_id "+
+		"JOIN namespaces n ON n.id = e.namespace_id "+
+		"GROUP BY n.name ORDER BY size DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sizes []NamespaceSize
+	for rows.Next() {
+		var size NamespaceSize
+		if err := rows.Scan(&size.Namespace, &size.HistoryBytes); err != nil {
+			return nil, err
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes, rows.Err()
+}
@@ -0,0 +1,34 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Snapshot copies the database at src into a new database file at dst, without
+// its pending timer tasks, so that a server using the copy fires no timers and
+// shows workflows as they were.
+//
+// src is opened read-only, so it is never written, even while a server is using it.
+func Snapshot(ctx context.Context, src, dst string) error {
+	db, err := sql.Open("sqlite3", "file:"+src+"?mode=ro&_busy_timeout=5000")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", dst); err != nil {
+		return err
+	}
+
+	snapshot, err := Open(dst)
+	if err != nil {
+		return err
+	}
+	defer snapshot.Close()
+	_, err = snapshot.ExecContext(ctx, "DELETE FROM timer_tasks")
+	return err
+}
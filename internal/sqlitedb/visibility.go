@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package sqlitedb
+
+import (
+	"context"
+	"time"
+)
+
+// ClosedRun is the visibility record of a closed workflow run.
+type ClosedRun struct {
+	NamespaceID string
+	WorkflowID  string
+	RunID       string
+	CloseTime   time.Time
+}
+
+// ClosedRuns returns the closed workflow runs recorded in the visibility
+// table of the database at path.
+func ClosedRuns(ctx context.Context, path string) ([]ClosedRun, error) {
+	db, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, "SELECT namespace_id, workflow_id, run_id, close_time FROM executions_visibility WHERE close_time IS NOT NULL")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []ClosedRun
+	for rows.Next() {
+		var run ClosedRun
+		if err := rows.Scan(&run.NamespaceID, &run.WorkflowID, &run.RunID, &run.CloseTime); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// StatusCount is the number of workflow runs of a namespace with one status.
+type StatusCount struct {
+	NamespaceID string
+	// Status is the run's enumspb.WorkflowExecutionStatus.
+	Status int32
+	Count  int64
+}
+
+// CountRuns counts the workflow runs recorded in the visibility table of the
+// database at path by namespace and status.
+func CountRuns(ctx context.Context, path string) ([]StatusCount, error) {
+	db, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, "SELECT namespace_id, status, COUNT(*) FROM executions_visibility GROUP BY namespace_id, status")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []StatusCount
+	for rows.Next() {
+		var count StatusCount
+		if err := rows.Scan(&count.NamespaceID, &count.Status, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
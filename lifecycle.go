@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"time"
+
+	"go.temporal.io/api/workflowservice/v1"
+	"google.golang.org/grpc"
+
+	"github.com/DataDog/temporalite/internal/liteconfig"
+)
+
+// LifecycleEventType identifies a state transition of a Server.
+type LifecycleEventType = liteconfig.LifecycleEventType
+
+// LifecycleEvent describes a state transition of a Server, for tools embedding
+// temporalite that track its state. See WithLifecycleListener.
+type LifecycleEvent = liteconfig.LifecycleEvent
+
+const (
+	// LifecycleStarted is emitted once the frontend accepts connections.
+	LifecycleStarted LifecycleEventType = "started"
+	// LifecycleNamespaceCreated is emitted for each namespace registered,
+	// whether pre-created or by a client.
+	LifecycleNamespaceCreated LifecycleEventType = "namespace-created"
+	// LifecycleShuttingDown is emitted when Stop is called.
+	LifecycleShuttingDown LifecycleEventType = "shutting-down"
+	// LifecycleStopped is emitted once all services have stopped.
+	LifecycleStopped LifecycleEventType = "stopped"
+)
+
+// notifyLifecycle invokes the lifecycle listeners of c with event.
+func notifyLifecycle(c *liteconfig.Config, event LifecycleEvent) {
+	if len(c.LifecycleListeners) == 0 {
+		return
+	}
+	event.Time = time.Now()
+	for _, listener := range c.LifecycleListeners {
+		listener(event)
+	}
+}
+
+// announceStart emits LifecycleStarted once the frontend accepts connections.
+func (s *Server) announceStart(ctx context.Context) {
+	conn, err := s.Dial(ctx)
+	if err != nil {
+		return
+	}
+	_ = conn.Close()
+	notifyLifecycle(s.config, LifecycleEvent{Type: LifecycleStarted, FrontendAddress: s.FrontendHostPort()})
+}
+
+// announceNamespaces emits LifecycleNamespaceCreated for namespaces registered
+// through the frontend.
+func (s *Server) announceNamespaces(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if r, ok := req.(*workflowservice.RegisterNamespaceRequest); ok && err == nil {
+		notifyLifecycle(s.config, LifecycleEvent{Type: LifecycleNamespaceCreated, Namespace: r.GetNamespace()})
+	}
+	return resp, err
+}
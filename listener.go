@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"go.temporal.io/server/common/log/tag"
+	"google.golang.org/grpc/peer"
+)
+
+// frontendPipeSize is the buffer size of the in-memory pipe used by Server.Dial.
+const frontendPipeSize = 256 * 1024
+
+// forwardedPeers maps the local address of each connection forwarded to the
+// frontend, which the frontend sees as its peer, to the address of the client
+// the connection was accepted from.
+type forwardedPeers struct {
+	mu    sync.Mutex
+	addrs map[string]net.Addr
+}
+
+func (f *forwardedPeers) add(local, client net.Addr) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.addrs == nil {
+		f.addrs = make(map[string]net.Addr)
+	}
+	f.addrs[local.String()] = client
+}
+
+func (f *forwardedPeers) remove(local net.Addr) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.addrs, local.String())
+}
+
+// peerAddr returns the address of the client that sent the request in ctx,
+// looking through connections forwarded to the frontend.
+func (f *forwardedPeers) peerAddr(ctx context.Context) net.Addr {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if client, ok := f.addrs[p.Addr.String()]; ok {
+		return client
+	}
+	return p.Addr
+}
+
+// serveFrontendListener forwards connections accepted on l to the frontend.
+//
+// The upstream frontend binds its own listener, so a listener passed in with
+// WithFrontendListener, or the in-memory pipe used by Dial, is served by copying bytes to a frontend bound to a
+// loopback port. TLS and gRPC, including API key checks, are handled end to
+// end by the frontend, and the client's own address is recorded in s.peers.
+func (s *Server) serveFrontendListener(ctx context.Context, l net.Listener) {
+	s.serveListener(ctx, l, func() (net.Conn, error) {
+		return net.Dial("tcp", s.frontendHostPort)
+	})
+}
+
+// checkLoopbackAddress returns an error unless addr is a host and port on the
+// loopback interface.
+func checkLoopbackAddress(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("%s is not a loopback address", addr)
+	}
+	return nil
+}
+
+// serveLocalFrontend forwards plaintext connections accepted on l to the
+// frontend, which may be serving TLS. TLS connections to the frontend present
+// temporalite's own certificate, so local clients need none of their own; the
+// certificate only secures the connection, as it grants no roles unless
+// certificate claims are mapped, which NewServer refuses.
+func (s *Server) serveLocalFrontend(ctx context.Context, l net.Listener) {
+	s.serveListener(ctx, l, func() (net.Conn, error) {
+		if s.clientTLS == nil {
+			return net.Dial("tcp", s.frontendHostPort)
+		}
+		// gRPC requires HTTP/2 to be negotiated over TLS.
+		cfg := s.clientTLS.Clone()
+		cfg.NextProtos = []string{"h2"}
+		return tls.Dial("tcp", s.frontendHostPort, cfg)
+	})
+}
+
+func (s *Server) serveListener(ctx context.Context, l net.Listener, dial func() (net.Conn, error)) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				s.logger.Warn("Temporary error accepting frontend connection", tag.Error(err))
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			reportErr(s.errCh, err)
+			return
+		}
+		go s.forwardToFrontend(conn, dial)
+	}
+}
+
+func (s *Server) forwardToFrontend(conn net.Conn, dial func() (net.Conn, error)) {
+	defer conn.Close()
+	upstream, err := dial()
+	if err != nil {
+		s.logger.Warn("Unable to connect to frontend", tag.Error(err))
+		return
+	}
+	defer upstream.Close()
+	s.peers.add(upstream.LocalAddr(), conn.RemoteAddr())
+	defer s.peers.remove(upstream.LocalAddr())
+
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(upstream, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(conn, upstream)
+		done <- struct{}{}
+	}()
+	<-done
+}
@@ -0,0 +1,107 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.temporal.io/api/workflowservice/v1"
+	"google.golang.org/grpc"
+)
+
+func TestFrontendListenerRecordsClientAddress(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+	s := newTestServer(t, WithFrontendListener(l), WithAuditLog(auditPath))
+	defer s.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var clientAddr net.Addr
+	conn, err := grpc.DialContext(ctx, l.Addr().String(), grpc.WithInsecure(), grpc.WithBlock(),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			c, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+			if err == nil {
+				clientAddr = c.LocalAddr()
+			}
+			return c, err
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	retention := 24 * time.Hour
+	_, err = workflowservice.NewWorkflowServiceClient(conn).RegisterNamespace(ctx, &workflowservice.RegisterNamespaceRequest{
+		Namespace:                        "audited",
+		WorkflowExecutionRetentionPeriod: &retention,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, line := range bytes.Split(bytes.TrimSpace(b), []byte("\n")) {
+		var entry auditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry.Namespace != "audited" {
+			continue
+		}
+		found = true
+		if entry.Peer != clientAddr.String() {
+			t.Errorf("audit log peer = %q, want client address %q", entry.Peer, clientAddr)
+		}
+	}
+	if !found {
+		t.Error("registration missing from audit log")
+	}
+}
+
+func TestFrontendListenerConflicts(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if _, err := NewServer(WithPersistenceDisabled(), WithFrontendListener(l), WithFrontendPort(7233)); err == nil {
+		t.Error("NewServer() with a frontend listener and port succeeded, want error")
+	}
+}
+
+func TestCheckLoopbackAddress(t *testing.T) {
+	tests := []struct {
+		addr    string
+		wantErr bool
+	}{
+		{addr: "127.0.0.1:7234"},
+		{addr: "[::1]:7234"},
+		{addr: "localhost:7234"},
+		{addr: ":7234", wantErr: true},
+		{addr: "0.0.0.0:7234", wantErr: true},
+		{addr: "10.0.0.5:7234", wantErr: true},
+		{addr: "127.0.0.1", wantErr: true},
+	}
+	for _, tc := range tests {
+		if err := checkLoopbackAddress(tc.addr); (err != nil) != tc.wantErr {
+			t.Errorf("checkLoopbackAddress(%q) error = %v, wantErr %v", tc.addr, err, tc.wantErr)
+		}
+	}
+}
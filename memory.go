@@ -0,0 +1,247 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/common/dynamicconfig"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/log/tag"
+	"google.golang.org/grpc"
+)
+
+// memoryCheckInterval is how often memory usage is compared to the limit and
+// the cache shrink threshold, independently of how often it is reported.
+const memoryCheckInterval = 2 * time.Second
+
+const (
+	// minCacheSize is the smallest size history caches are shrunk to.
+	minCacheSize = 16
+	// cacheShrinkInterval is the minimum time between cache shrinks, giving the
+	// history shard time to reload and memory time to be returned to the OS.
+	cacheShrinkInterval = time.Minute
+	// shrunkGCPercent is the garbage collection target used once caches have been shrunk.
+	shrunkGCPercent = 50
+)
+
+// memoryGuard logs memory usage, refuses to start new workflows while the
+// process is over its memory limit, and shrinks history caches while it is over
+// the cache shrink threshold.
+type memoryGuard struct {
+	limit           uint64
+	shrinkThreshold uint64
+	reportInterval  time.Duration
+	logger          log.Logger
+	dynamicConfig   *dynamicconfig.MutableEphemeralClient
+	// closeShard unloads the history shard, which is reloaded with caches of
+	// the currently configured size on its next request.
+	closeShard func(ctx context.Context) error
+
+	over       int32
+	lastShrink time.Time
+	// gcLowered reports whether a shrink by this guard lowered the garbage
+	// collection target, and stopped whether the server has stopped. Both are
+	// guarded by gcMu.
+	gcLowered bool
+	stopped   bool
+}
+
+var (
+	gcMu sync.Mutex
+	// gcLoweredBy counts the memory guards that lowered the process-wide garbage
+	// collection target from gcPercent, which is restored once none of them
+	// needs it lowered.
+	gcLoweredBy int
+	gcPercent   int
+)
+
+// usage returns the memory held by the process: resident set size where the
+// platform exposes it, otherwise memory obtained from the OS by the Go runtime.
+func (g *memoryGuard) usage(mem *runtime.MemStats) uint64 {
+	if rss, ok := residentSetSize(); ok {
+		return rss
+	}
+	return mem.Sys - mem.HeapReleased
+}
+
+func (g *memoryGuard) Run(ctx context.Context) {
+	var check, report <-chan time.Time
+	if g.limit > 0 || g.shrinkThreshold > 0 {
+		ticker := time.NewTicker(memoryCheckInterval)
+		defer ticker.Stop()
+		check = ticker.C
+	}
+	if g.reportInterval > 0 {
+		ticker := time.NewTicker(g.reportInterval)
+		defer ticker.Stop()
+		report = ticker.C
+	}
+	defer g.stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-check:
+			g.check()
+		case <-report:
+			g.report()
+		}
+	}
+}
+
+// report logs memory usage and the configured cache sizes.
+func (g *memoryGuard) report() {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	historyCache, _ := g.dynamicConfig.GetIntValue(dynamicconfig.HistoryCacheMaxSize, nil, 512)
+	eventsCache, _ := g.dynamicConfig.GetIntValue(dynamicconfig.EventsCacheMaxSize, nil, 512)
+	g.logger.Info("Memory usage",
+		tag.NewInt64("memory-bytes", int64(g.usage(&mem))),
+		tag.NewInt64("heap-alloc-bytes", int64(mem.HeapAlloc)),
+		tag.NewInt("goroutines", runtime.NumGoroutine()),
+		tag.NewInt("history-cache-max-size", historyCache),
+		tag.NewInt("events-cache-max-size", eventsCache),
+	)
+}
+
+// check compares memory usage to the limit and the cache shrink threshold.
+func (g *memoryGuard) check() {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	usage := g.usage(&mem)
+
+	if g.limit > 0 && usage > g.limit {
+		// Return freed heap to the OS before concluding the limit is exceeded.
+		debug.FreeOSMemory()
+		runtime.ReadMemStats(&mem)
+		usage = g.usage(&mem)
+	}
+
+	if g.shrinkThreshold > 0 && usage > g.shrinkThreshold && time.Since(g.lastShrink) > cacheShrinkInterval {
+		g.lastShrink = time.Now()
+		g.shrinkCaches(usage)
+	} else if usage <= g.shrinkThreshold {
+		g.restoreGCPercent()
+	}
+
+	if g.limit == 0 {
+		return
+	}
+	if usage > g.limit {
+		if atomic.SwapInt32(&g.over, 1) == 0 {
+			g.logger.Warn("Memory limit exceeded, refusing new workflows",
+				tag.NewInt64("memory-bytes", int64(usage)),
+				tag.NewInt64("limit-bytes", int64(g.limit)),
+			)
+		}
+	} else if atomic.SwapInt32(&g.over, 0) == 1 {
+		g.logger.Info("Memory usage below limit, accepting new workflows", tag.NewInt64("memory-bytes", int64(usage)))
+	}
+}
+
+// shrinkCaches halves the configured history and events cache sizes and reloads
+// the history shard so they take effect, since upstream only sizes a shard's
+// caches when the shard is loaded.
+func (g *memoryGuard) shrinkCaches(usage uint64) {
+	historyCache, _ := g.dynamicConfig.GetIntValue(dynamicconfig.HistoryCacheMaxSize, nil, 512)
+	eventsCache, _ := g.dynamicConfig.GetIntValue(dynamicconfig.EventsCacheMaxSize, nil, 512)
+	if historyCache <= minCacheSize && eventsCache <= minCacheSize {
+		return
+	}
+	if !g.lowerGCPercent() {
+		return
+	}
+	newHistoryCache, newEventsCache := shrunkCacheSize(historyCache), shrunkCacheSize(eventsCache)
+	g.dynamicConfig.Update(
+		dynamicconfig.Set(dynamicconfig.HistoryCacheMaxSize, newHistoryCache),
+		dynamicconfig.Set(dynamicconfig.HistoryCacheInitialSize, newHistoryCache),
+		dynamicconfig.Set(dynamicconfig.EventsCacheMaxSize, newEventsCache),
+		dynamicconfig.Set(dynamicconfig.EventsCacheInitialSize, newEventsCache),
+	)
+	g.logger.Warn("Memory over cache shrink threshold, shrinking history caches",
+		tag.NewInt64("memory-bytes", int64(usage)),
+		tag.NewInt64("threshold-bytes", int64(g.shrinkThreshold)),
+		tag.NewInt("history-cache-max-size", newHistoryCache),
+		tag.NewInt("events-cache-max-size", newEventsCache),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := g.closeShard(ctx); err != nil {
+		g.logger.Warn("Unable to reload history shard with smaller caches", tag.Error(err))
+	}
+}
+
+// lowerGCPercent lowers the garbage collection target for the process,
+// reporting false if the guard has stopped.
+func (g *memoryGuard) lowerGCPercent() bool {
+	gcMu.Lock()
+	defer gcMu.Unlock()
+	if g.stopped {
+		return false
+	}
+	if !g.gcLowered {
+		g.gcLowered = true
+		if gcLoweredBy++; gcLoweredBy == 1 {
+			gcPercent = debug.SetGCPercent(shrunkGCPercent)
+		}
+	}
+	return true
+}
+
+// restoreGCPercent restores the garbage collection target a shrink lowered,
+// unless another guard still needs it lowered.
+func (g *memoryGuard) restoreGCPercent() {
+	gcMu.Lock()
+	defer gcMu.Unlock()
+	g.restoreGCPercentLocked()
+}
+
+func (g *memoryGuard) restoreGCPercentLocked() {
+	if g.gcLowered {
+		g.gcLowered = false
+		if gcLoweredBy--; gcLoweredBy == 0 {
+			debug.SetGCPercent(gcPercent)
+		}
+	}
+}
+
+// stop restores the garbage collection target and keeps later checks from
+// lowering it again. It is called when the server stops, so that the target
+// is restored by the time Stop returns.
+func (g *memoryGuard) stop() {
+	gcMu.Lock()
+	defer gcMu.Unlock()
+	g.stopped = true
+	g.restoreGCPercentLocked()
+}
+
+func shrunkCacheSize(size int) int {
+	if size /= 2; size < minCacheSize {
+		return minCacheSize
+	}
+	return size
+}
+
+// Intercept rejects workflow starts while over the memory limit. Requests that
+// make progress on existing workflows are always allowed so they can drain.
+func (g *memoryGuard) Intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if atomic.LoadInt32(&g.over) == 1 {
+		switch req.(type) {
+		case *workflowservice.StartWorkflowExecutionRequest, *workflowservice.SignalWithStartWorkflowExecutionRequest:
+			return nil, serviceerror.NewResourceExhausted("temporalite is over its memory limit")
+		}
+	}
+	return handler(ctx, req)
+}
@@ -0,0 +1,12 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+//go:build !linux
+// +build !linux
+
+package temporalite
+
+func residentSetSize() (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"math"
+	"runtime/debug"
+	"testing"
+	"time"
+
+	"go.temporal.io/server/common/dynamicconfig"
+	"go.temporal.io/server/common/log"
+)
+
+func TestMemoryGuardRestoresGCPercent(t *testing.T) {
+	const gcPercent = 150
+	defer debug.SetGCPercent(debug.SetGCPercent(gcPercent))
+
+	shards := 0
+	g := &memoryGuard{
+		shrinkThreshold: 1,
+		logger:          log.NewNoopLogger(),
+		dynamicConfig:   dynamicconfig.NewMutableEphemeralClient(),
+		closeShard: func(ctx context.Context) error {
+			shards++
+			return nil
+		},
+	}
+	g.check()
+	if shards != 1 {
+		t.Fatalf("shard reloaded %d times, want 1", shards)
+	}
+	if got := debug.SetGCPercent(gcPercent); got != shrunkGCPercent {
+		t.Errorf("GC percent after shrink = %d, want %d", got, shrunkGCPercent)
+	}
+	debug.SetGCPercent(shrunkGCPercent)
+
+	g.shrinkThreshold = math.MaxUint64
+	g.check()
+	if got := debug.SetGCPercent(gcPercent); got != gcPercent {
+		t.Errorf("GC percent once usage dropped = %d, want %d", got, gcPercent)
+	}
+}
+
+func TestMemoryGuardRestoresGCPercentOnStop(t *testing.T) {
+	const gcPercent = 150
+	defer debug.SetGCPercent(debug.SetGCPercent(gcPercent))
+
+	g := newShrinkingGuard()
+	g.check()
+	g.stop()
+	if got := debug.SetGCPercent(gcPercent); got != gcPercent {
+		t.Errorf("GC percent after stop = %d, want %d", got, gcPercent)
+	}
+	g.lastShrink = time.Time{}
+	g.check()
+	if got := debug.SetGCPercent(gcPercent); got != gcPercent {
+		t.Errorf("GC percent after a check following stop = %d, want %d", got, gcPercent)
+	}
+}
+
+func TestMemoryGuardsShareGCPercent(t *testing.T) {
+	const gcPercent = 150
+	defer debug.SetGCPercent(debug.SetGCPercent(gcPercent))
+
+	first, second := newShrinkingGuard(), newShrinkingGuard()
+	first.check()
+	second.check()
+	first.stop()
+	if got := debug.SetGCPercent(shrunkGCPercent); got != shrunkGCPercent {
+		t.Errorf("GC percent while another guard needs it lowered = %d, want %d", got, shrunkGCPercent)
+	}
+	second.stop()
+	if got := debug.SetGCPercent(gcPercent); got != gcPercent {
+		t.Errorf("GC percent after both guards stopped = %d, want %d", got, gcPercent)
+	}
+}
+
+// newShrinkingGuard returns a memory guard that shrinks caches on every check.
+func newShrinkingGuard() *memoryGuard {
+	return &memoryGuard{
+		shrinkThreshold: 1,
+		logger:          log.NewNoopLogger(),
+		dynamicConfig:   dynamicconfig.NewMutableEphemeralClient(),
+		closeShard:      func(ctx context.Context) error { return nil },
+	}
+}
+
+func TestMemoryGuardLimit(t *testing.T) {
+	g := &memoryGuard{limit: 1, logger: log.NewNoopLogger()}
+	g.check()
+	if g.over != 1 {
+		t.Fatal("guard not over its limit")
+	}
+	g.limit = math.MaxUint64
+	g.check()
+	if g.over != 0 {
+		t.Error("guard still over its limit")
+	}
+}
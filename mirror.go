@@ -0,0 +1,136 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"crypto/tls"
+	"expvar"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/gogo/status"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/log/tag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// mirrorTimeout bounds mirrored calls, which run after the local call has
+// returned and so have no deadline of their own.
+const mirrorTimeout = 30 * time.Second
+
+// maxMirroredCalls bounds how many mirrored calls may be in flight at once.
+// Calls beyond it are not mirrored, so that a slow remote cluster cannot pile
+// up goroutines.
+const maxMirroredCalls = 64
+
+var (
+	mirrorMismatches = expvar.NewMap("temporalite.mirror.mismatches")
+	mirrorDropped    = expvar.NewMap("temporalite.mirror.dropped")
+)
+
+// workflowServiceMessage returns the type of the request or response message
+// of a WorkflowService method, or nil if there is no such method.
+func workflowServiceMessage(method, kind string) reflect.Type {
+	return proto.MessageType("temporal.api.workflowservice.v1." + method + kind)
+}
+
+// trafficMirror sends selected frontend calls to a remote cluster, either in
+// place of serving them locally or in addition to it.
+type trafficMirror struct {
+	conn    *grpc.ClientConn
+	methods map[string]bool
+	forward bool
+	logger  log.Logger
+	// inFlight holds a slot for each mirrored call in progress.
+	inFlight chan struct{}
+}
+
+func newTrafficMirror(address string, methods []string, forward, useTLS bool, logger log.Logger) (*trafficMirror, error) {
+	m := &trafficMirror{methods: make(map[string]bool), forward: forward, logger: logger, inFlight: make(chan struct{}, maxMirroredCalls)}
+	for _, method := range methods {
+		if workflowServiceMessage(method, "Request") == nil || workflowServiceMessage(method, "Response") == nil {
+			return nil, fmt.Errorf("ERROR: unknown WorkflowService method %q", method)
+		}
+		if !forward && strings.HasPrefix(method, "Poll") {
+			return nil, fmt.Errorf("ERROR: mirroring %s would take tasks from the remote cluster's workers; forward it instead", method)
+		}
+		m.methods[workflowServicePrefix+method] = true
+	}
+	creds := grpc.WithInsecure()
+	if useTLS {
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{}))
+	}
+	conn, err := grpc.Dial(address, creds)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to %s: %w", address, err)
+	}
+	m.conn = conn
+	return m, nil
+}
+
+// outgoingContext carries the caller's metadata over to the call to the remote
+// cluster, except for its authorization header, which was issued for this
+// server and must not be disclosed to another.
+func outgoingContext(ctx, incoming context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(incoming)
+	out := metadata.MD{}
+	for k, v := range md {
+		if strings.HasPrefix(k, ":") || strings.HasPrefix(k, "grpc-") || k == "content-type" || k == "user-agent" || k == "authorization" {
+			continue
+		}
+		out[k] = v
+	}
+	return metadata.NewOutgoingContext(ctx, out)
+}
+
+func (m *trafficMirror) invoke(ctx context.Context, fullMethod string, req interface{}) (interface{}, error) {
+	method := strings.TrimPrefix(fullMethod, workflowServicePrefix)
+	resp := reflect.New(workflowServiceMessage(method, "Response").Elem()).Interface()
+	if err := m.conn.Invoke(ctx, fullMethod, req, resp); err != nil {
+		// Return the same error types as the local frontend.
+		return nil, serviceerror.FromStatus(status.Convert(err))
+	}
+	return resp, nil
+}
+
+func (m *trafficMirror) Intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !m.methods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	if m.forward {
+		return m.invoke(outgoingContext(ctx, ctx), info.FullMethod, req)
+	}
+
+	resp, err := handler(ctx, req)
+	select {
+	case m.inFlight <- struct{}{}:
+	default:
+		mirrorDropped.Add(info.FullMethod, 1)
+		return resp, err
+	}
+	go func() {
+		defer func() { <-m.inFlight }()
+		mctx, cancel := context.WithTimeout(outgoingContext(context.Background(), ctx), mirrorTimeout)
+		defer cancel()
+		_, remoteErr := m.invoke(mctx, info.FullMethod, req)
+		if local, remote := serviceerror.ToStatus(err).Code(), serviceerror.ToStatus(remoteErr).Code(); local != remote {
+			mirrorMismatches.Add(info.FullMethod, 1)
+			m.logger.Warn("Mirrored call had a different outcome on the remote cluster",
+				tag.NewStringTag("method", info.FullMethod),
+				tag.NewStringTag("local-code", local.String()),
+				tag.NewStringTag("remote-code", remote.String()),
+				tag.NewStringTag("remote-error", fmt.Sprint(remoteErr)),
+			)
+		}
+	}()
+	return resp, err
+}
@@ -0,0 +1,26 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestOutgoingContext(t *testing.T) {
+	incoming := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"authorization", "Bearer secret",
+		"client-name", "temporal-go",
+		"user-agent", "grpc-go",
+		":authority", "localhost:7233",
+	))
+	md, _ := metadata.FromOutgoingContext(outgoingContext(context.Background(), incoming))
+	if want := (metadata.MD{"client-name": {"temporal-go"}}); !reflect.DeepEqual(md, want) {
+		t.Errorf("outgoing metadata = %v, want %v", md, want)
+	}
+}
@@ -0,0 +1,85 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/common/namespace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// namespaceWaitWindow bounds how long after registration a namespace may
+// still be missing from upstream's namespace caches.
+const namespaceWaitWindow = namespace.CacheRefreshInterval + time.Second
+
+// skipNamespaceWaitHeader marks RegisterNamespace calls that should return as
+// soon as the namespace is stored, such as the server's own registrations of
+// namespaces passed to WithNamespaces, which are awaited separately.
+const skipNamespaceWaitHeader = "temporalite-skip-namespace-wait"
+
+// namespaceWaiter holds RegisterNamespace responses until the namespace the
+// call created is usable.
+//
+// Upstream refreshes its namespace caches on a fixed interval and rejects
+// requests for namespaces missing from the cache before custom interceptors run,
+// so without this a namespace registered at runtime is unusable for up to 10 seconds.
+// Calls that fail, such as those for namespaces that already exist, return
+// right away.
+type namespaceWaiter struct {
+	await func(ctx context.Context, namespace string) error
+
+	mu sync.Mutex
+	// creating holds namespaces still being created, closed once usable, so
+	// that concurrent waits for one namespace share a single probe.
+	creating map[string]chan struct{}
+}
+
+// wait blocks until ns is usable, the wait window passes, or ctx is done.
+func (w *namespaceWaiter) wait(ctx context.Context, ns string) {
+	w.mu.Lock()
+	if w.creating == nil {
+		w.creating = make(map[string]chan struct{})
+	}
+	ready, ok := w.creating[ns]
+	if !ok {
+		ready = make(chan struct{})
+		w.creating[ns] = ready
+		go func() {
+			waitCtx, cancel := context.WithTimeout(context.Background(), namespaceWaitWindow)
+			defer cancel()
+			// The namespace is registered either way; it will become usable on the next refresh.
+			_ = w.await(waitCtx, ns)
+			w.mu.Lock()
+			delete(w.creating, ns)
+			w.mu.Unlock()
+			close(ready)
+		}()
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-ready:
+	case <-ctx.Done():
+	}
+}
+
+func (w *namespaceWaiter) Intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+
+	r, ok := req.(*workflowservice.RegisterNamespaceRequest)
+	if !ok || err != nil {
+		return resp, err
+	}
+	if md, _ := metadata.FromIncomingContext(ctx); len(md.Get(skipNamespaceWaitHeader)) > 0 {
+		return resp, err
+	}
+	w.wait(ctx, r.GetNamespace())
+	return resp, err
+}
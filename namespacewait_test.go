@@ -0,0 +1,75 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"go.temporal.io/api/workflowservice/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestNamespaceWaiter(t *testing.T) {
+	register := &workflowservice.RegisterNamespaceRequest{Namespace: "orders"}
+	succeed := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	fail := func(ctx context.Context, req interface{}) (interface{}, error) { return nil, errors.New("already exists") }
+	info := &grpc.UnaryServerInfo{FullMethod: "/temporal.api.workflowservice.v1.WorkflowService/RegisterNamespace"}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		req     interface{}
+		handler grpc.UnaryHandler
+		awaits  int32
+	}{
+		{"register", context.Background(), register, succeed, 1},
+		{"failed register", context.Background(), register, fail, 0},
+		{"skipped register", metadata.NewIncomingContext(context.Background(), metadata.Pairs(skipNamespaceWaitHeader, "true")), register, succeed, 0},
+		{"other call", context.Background(), &workflowservice.StartWorkflowExecutionRequest{Namespace: "orders"}, succeed, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var awaits int32
+			w := &namespaceWaiter{await: func(ctx context.Context, namespace string) error {
+				atomic.AddInt32(&awaits, 1)
+				return nil
+			}}
+			_, _ = w.Intercept(tt.ctx, tt.req, info, tt.handler)
+			if got := atomic.LoadInt32(&awaits); got != tt.awaits {
+				t.Errorf("awaited %d times, want %d", got, tt.awaits)
+			}
+		})
+	}
+}
+
+func TestNamespaceWaiterSharesWait(t *testing.T) {
+	release := make(chan struct{})
+	var awaits int32
+	w := &namespaceWaiter{await: func(ctx context.Context, namespace string) error {
+		atomic.AddInt32(&awaits, 1)
+		<-release
+		return nil
+	}}
+
+	// Callers whose context is done return while the namespace is still being
+	// created, without starting another probe.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 3; i++ {
+		w.wait(ctx, "orders")
+	}
+	w.mu.Lock()
+	ready := w.creating["orders"]
+	w.mu.Unlock()
+	close(release)
+	<-ready
+	if got := atomic.LoadInt32(&awaits); got != 1 {
+		t.Errorf("awaited %d times, want 1", got)
+	}
+}
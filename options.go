@@ -5,19 +5,44 @@
 package temporalite
 
 import (
+	"encoding/json"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/server/common/authorization"
+	"go.temporal.io/server/common/dynamicconfig"
 	"go.temporal.io/server/common/log"
+	persistenceclient "go.temporal.io/server/common/persistence/client"
 	"go.temporal.io/server/temporal"
+	"google.golang.org/grpc"
 
 	"github.com/DataDog/temporalite/internal/liteconfig"
 )
 
-// WithLogger overrides the default logger.
+// WithLogger overrides the default logger, which logs temporalite's startup
+// messages, such as namespace registration and configuration warnings, and
+// everything logged while the server runs unless WithServerLogger is also
+// passed.
 func WithLogger(logger log.Logger) ServerOption {
 	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
 		cfg.Logger = logger
 	})
 }
 
+// WithServerLogger logs the messages of the Temporal services, and those
+// temporalite logs while the server runs, such as stuck workflow and database
+// size warnings, to logger instead of the logger set by WithLogger, so that
+// startup messages can be configured independently, for example printed for
+// humans while the running server logs JSON for ingestion.
+func WithServerLogger(logger log.Logger) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.ServerLogger = logger
+	})
+}
+
 // WithDatabaseFilePath persists state to the file at the specified path.
 func WithDatabaseFilePath(filepath string) ServerOption {
 	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
@@ -56,8 +81,20 @@ func WithFrontendPort(port int) ServerOption {
 	})
 }
 
+// WithPortRetries tries up to n successive frontend ports when the configured
+// port or any of the service ports derived from it are already in use.
+//
+// This option has no effect when dynamic ports are enabled.
+func WithPortRetries(n int) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.PortRetries = n
+	})
+}
+
 // WithFrontendIP binds the temporal-frontend GRPC service to a specific IP (eg. `0.0.0.0`)
-// Check net.ParseIP for supported syntax; only IPv4 is supported.
+// Check net.ParseIP for supported syntax. IPv6 addresses are supported; when an IPv6
+// address is given (eg. `::1`, or `::` for dual-stack), internal services bind to the
+// IPv6 loopback address.
 //
 // When unspecified, the frontend service will bind to localhost.
 func WithFrontendIP(address string) ServerOption {
@@ -66,6 +103,18 @@ func WithFrontendIP(address string) ServerOption {
 	})
 }
 
+// WithBroadcastAddress sets the address advertised to other Temporal services for
+// membership, independent of the address services bind to.
+//
+// This is useful in containers or NATed environments where the bind IP (eg. `0.0.0.0`)
+// is not routable. When combined with WithFrontendIP, all services bind to the
+// frontend IP so they are reachable via the broadcast address.
+func WithBroadcastAddress(address string) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.BroadcastAddress = address
+	})
+}
+
 // WithDynamicPorts starts Temporal on system-chosen ports.
 func WithDynamicPorts() ServerOption {
 	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
@@ -80,6 +129,24 @@ func WithNamespaces(namespaces ...string) ServerOption {
 	})
 }
 
+// WithNamespaceRetention sets the workflow execution retention period of namespaces
+// registered with WithNamespaces. When unspecified, new namespaces retain closed
+// workflows for 24 hours.
+func WithNamespaceRetention(retention time.Duration) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.NamespaceRetention = retention
+	})
+}
+
+// WithNamespaceReconciliation updates namespaces registered with WithNamespaces that
+// already exist with a retention period other than the one set by WithNamespaceRetention.
+// Otherwise existing namespaces are left unchanged and the difference is logged.
+func WithNamespaceReconciliation() ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.ReconcileNamespaces = true
+	})
+}
+
 // WithSQLitePragmas applies pragma statements to SQLite on Temporal start.
 func WithSQLitePragmas(pragmas map[string]string) ServerOption {
 	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
@@ -92,6 +159,732 @@ func WithSQLitePragmas(pragmas map[string]string) ServerOption {
 	})
 }
 
+// WithNamespaceAvailabilityWait controls whether RegisterNamespace calls made while the
+// server is running block until the new namespace is usable, rather than returning
+// immediately and failing requests for the namespace with NotFound errors until
+// upstream's namespace cache refreshes (up to 10 seconds). Only calls that
+// create a namespace wait; those that fail, for example because the namespace
+// already exists, return right away.
+//
+// When unspecified, this is enabled for ephemeral servers and disabled otherwise.
+// Namespaces registered with WithNamespaces are always available immediately.
+func WithNamespaceAvailabilityWait(enabled bool) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.NamespaceWait = &enabled
+	})
+}
+
+// WithFrontendInterceptors chains gRPC interceptors that are invoked, in order, for all
+// frontend API calls after temporalite's own interceptors.
+//
+// Prefer this over passing temporal.WithChainedFrontendGrpcInterceptors to
+// WithUpstreamOptions, which replaces temporalite's interceptors.
+func WithFrontendInterceptors(interceptors ...grpc.UnaryServerInterceptor) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.FrontendInterceptors = append(cfg.FrontendInterceptors, interceptors...)
+	})
+}
+
+// WithRequestRecording captures all frontend requests and responses to a new
+// JSON lines file in dir, for later use with `temporalite replay-requests`.
+func WithRequestRecording(dir string) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.RecordDir = dir
+	})
+}
+
+// WithWorkflowCompletionListener invokes listener once for each workflow execution
+// that closes in any namespace while the server is running.
+//
+// Closed workflows are discovered from visibility records shortly after they close.
+// Listeners are invoked sequentially from a single goroutine and should not block.
+func WithWorkflowCompletionListener(listener func(WorkflowClosedEvent)) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.CompletionListeners = append(cfg.CompletionListeners, listener)
+	})
+}
+
+// WithLifecycleListener invokes listener as the server starts, registers
+// namespaces, and stops, so that tools embedding temporalite can track its state
+// without parsing logs. See LifecycleEventType for the events emitted.
+//
+// Pre-created namespaces are reported from NewServer, before the server starts.
+// Listeners are invoked synchronously and must not block or call back into the
+// server.
+func WithLifecycleListener(listener func(LifecycleEvent)) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.LifecycleListeners = append(cfg.LifecycleListeners, listener)
+	})
+}
+
+// WithNotificationSink writes each lifecycle event to w as a line of JSON, such
+// as {"type":"started","time":"...","frontend_address":"127.0.0.1:7233"}. See
+// WithLifecycleListener.
+func WithNotificationSink(w io.Writer) ServerOption {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return WithLifecycleListener(func(event LifecycleEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		_ = enc.Encode(event)
+	})
+}
+
+// WithDynamicConfigValue sets the value of a Temporal dynamic config key, overriding
+// upstream and temporalite defaults.
+//
+// Keys are not guaranteed to be compatible across Temporal versions; prefer a
+// dedicated option when one exists.
+func WithDynamicConfigValue(key dynamicconfig.Key, value interface{}) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		if cfg.DynamicConfig == nil {
+			cfg.DynamicConfig = make(map[dynamicconfig.Key]interface{})
+		}
+		cfg.DynamicConfig[key] = value
+	})
+}
+
+// WithTaskQueuePartitions sets the number of read and write partitions for every task queue.
+//
+// Upstream defaults to 4 partitions, which suits multi-node clusters but adds
+// sync-match latency on a single node. When unspecified, ephemeral servers use
+// 1 partition and file-backed servers use the upstream default.
+func WithTaskQueuePartitions(read, write int) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		WithDynamicConfigValue(dynamicconfig.MatchingNumTaskqueueReadPartitions, read).apply(cfg)
+		WithDynamicConfigValue(dynamicconfig.MatchingNumTaskqueueWritePartitions, write).apply(cfg)
+	})
+}
+
+// WithDeterministicTaskOrdering processes history's transfer, timer, and
+// visibility tasks on a single worker each, in the order they were created,
+// and gives every task queue a single partition so tasks are dispatched to
+// pollers first in, first out. Integration tests then see the same event
+// ordering from run to run, at the cost of throughput.
+//
+// Workers must also run one task at a time for histories to be fully
+// reproducible; see temporaltest.WithDeterministicTaskOrdering.
+func WithDeterministicTaskOrdering() ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		for _, key := range []dynamicconfig.Key{
+			dynamicconfig.TransferTaskWorkerCount,
+			dynamicconfig.TimerTaskWorkerCount,
+			dynamicconfig.VisibilityTaskWorkerCount,
+		} {
+			WithDynamicConfigValue(key, 1).apply(cfg)
+		}
+		WithTaskQueuePartitions(1, 1).apply(cfg)
+	})
+}
+
+// WithBatcherMaxConcurrentOperations limits how many batch operations (as started
+// by `tctl batch start`) the system batcher worker processes at once.
+//
+// The batcher is always enabled; when unspecified, upstream's default of 1000 is used.
+func WithBatcherMaxConcurrentOperations(n int) ServerOption {
+	return WithDynamicConfigValue(dynamicconfig.WorkerBatcherMaxConcurrentActivityExecutionSize, n)
+}
+
+// WithArchival archives the histories of closed workflows to uri once their
+// namespace's retention period has passed, for namespaces with history archival
+// enabled. Namespaces registered after startup have it enabled by default, as
+// do those created with WithNamespaces; existing namespaces must be updated.
+//
+// The URI selects the archiver: file:///path for a local directory,
+// s3://bucket/path for S3, or gs://bucket/path for Google Cloud Storage.
+// Archived histories can be read back with GetWorkflowExecutionHistory.
+// Credentials are read from the environment using the AWS or Google Cloud SDK's
+// default credential chain.
+func WithArchival(uri string) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.HistoryArchivalURI = uri
+	})
+}
+
+// WithVisibilityArchival archives the visibility records of closed workflows
+// to uri, for namespaces with visibility archival enabled, which are chosen as
+// for WithArchival. Archived records are listed with
+// ListArchivedWorkflowExecutions.
+//
+// With a file:/// URI the query must filter on at least one of WorkflowId,
+// RunId, WorkflowType, ExecutionStatus, or CloseTime, joined with "and".
+// CloseTime accepts =, <, <=, >, and >= with an RFC3339 timestamp; the other
+// fields accept only =.
+func WithVisibilityArchival(uri string) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.VisibilityArchivalURI = uri
+	})
+}
+
+// WithS3Archival sets the region and endpoint of the S3 archiver, eg. to
+// archive to a MinIO server at http://localhost:9000. Buckets are addressed by
+// path when an endpoint is set. The region defaults to us-east-1.
+func WithS3Archival(region, endpoint string) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.ArchivalS3Region = region
+		cfg.ArchivalS3Endpoint = endpoint
+	})
+}
+
+// WithSeedDatabase starts the server from a copy of the database snapshot at
+// source, such as one uploaded by WithReplication, and discards all changes when
+// the server stops. Source may be an s3://bucket/key, gs://bucket/key, or
+// http(s):// URL, or a local path.
+//
+// The snapshot is downloaded to a temporary file, which takes the place of the
+// database file set with WithDatabaseFilePath or WithPersistenceDisabled.
+func WithSeedDatabase(source string) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.SeedDatabase = source
+	})
+}
+
+// WithReplication periodically uploads a snapshot of the database file to
+// destination, which must be of the form s3://bucket/prefix or gs://bucket/prefix.
+//
+// Snapshots are taken every interval (one minute if zero) and overwrite the
+// previous one, so at most one interval of writes is lost on disk failure.
+// Credentials are read from the environment using each cloud SDK's default chain.
+//
+// Replication is not supported with WithPersistenceDisabled.
+func WithReplication(destination string, interval time.Duration) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.ReplicateTo = destination
+		cfg.ReplicateInterval = interval
+	})
+}
+
+// WithReadOnly serves workflow histories, descriptions, and list queries from an
+// existing database file while rejecting every request that could modify it.
+//
+// The worker service is not started and no namespaces are created. Upstream
+// writes to its database at startup, so the server runs on a snapshot taken
+// when it is created, and the file itself is only opened read-only. Timers
+// don't fire in the snapshot, so workflows don't time out and closed workflows
+// are not deleted, and changes to the file after the snapshot are not shown.
+func WithReadOnly() ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.ReadOnly = true
+	})
+}
+
+// WithCheckpointInterval periodically copies the write-ahead log back into the
+// database file and truncates it.
+//
+// File-backed servers use the SQLite WAL journal mode unless overridden with
+// WithSQLitePragmas. When unspecified, SQLite checkpoints automatically once the
+// log reaches 1000 pages, which can leave the log file larger than needed.
+func WithCheckpointInterval(interval time.Duration) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.CheckpointInterval = interval
+	})
+}
+
+// WithDatabaseSizeWarnings logs a warning naming the largest namespaces when
+// the database file, including its write-ahead log, grows beyond each of
+// thresholds bytes, so unbounded growth is noticed before the disk fills. The
+// size is checked every minute and published as temporalite.database.bytes on
+// /debug/vars and as the temporalite_database_bytes gauge with the server's
+// metrics. Ephemeral servers have no file to check.
+//
+// Finding the largest namespaces reads every workflow history in the database,
+// so it is only done when a threshold is passed, and given up after ten
+// seconds.
+func WithDatabaseSizeWarnings(thresholds ...int64) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.DatabaseSizeWarnings = append(cfg.DatabaseSizeWarnings, thresholds...)
+	})
+}
+
+// WithDurability trades write durability for speed by setting SQLite's
+// synchronous pragma. Supported levels are:
+//
+//   - "full": every committed transaction survives power loss or an OS crash.
+//   - "normal": the most recent transactions may be lost on power loss or an
+//     OS crash, but the database is never corrupted.
+//   - "off": fastest; power loss or an OS crash may corrupt the database.
+//
+// All levels survive a crash of the temporalite process itself. When
+// unspecified, file-backed servers use "full" and ephemeral servers use "off".
+// A synchronous pragma set with WithSQLitePragmas takes precedence.
+func WithDurability(level string) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.Durability = level
+	})
+}
+
+// WithResourceProfile sizes history caches and task processor worker pools for
+// the expected workload: "small" for a single developer, "medium" (the default)
+// for shared development or CI servers, or "large" for upstream's cluster defaults.
+//
+// Values set with WithDynamicConfigValue take precedence over the profile.
+func WithResourceProfile(profile string) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.ResourceProfile = profile
+	})
+}
+
+// WithConcurrencyProfile raises the rate limits, task processor pools, and
+// matching batch sizes that bound how fast the server dispatches work, so load
+// tests can push a single node without tuning individual dynamic config keys:
+// "default" keeps upstream's values, "high" raises them severalfold, and "max"
+// effectively removes rate limits. The profile's task processor sizes take
+// precedence over WithResourceProfile.
+//
+// Values set with WithDynamicConfigValue take precedence over the profile. The
+// server always runs a single history shard, which profiles can't change.
+func WithConcurrencyProfile(profile string) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.ConcurrencyProfile = profile
+	})
+}
+
+// WithPersistenceQPS sets the maximum rate at which each service may query the
+// database, which otherwise throttles local load tests. Temporalite keeps
+// upstream's defaults: 2000 queries per second for the frontend, 9000 for
+// history, 3000 for matching, and 500 for the system worker.
+//
+// It takes precedence over the persistence limits of WithConcurrencyProfile.
+func WithPersistenceQPS(max int) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		WithDynamicConfigValue(dynamicconfig.FrontendPersistenceMaxQPS, max).apply(cfg)
+		WithDynamicConfigValue(dynamicconfig.HistoryPersistenceMaxQPS, max).apply(cfg)
+		WithDynamicConfigValue(dynamicconfig.MatchingPersistenceMaxQPS, max).apply(cfg)
+		WithDynamicConfigValue(dynamicconfig.WorkerPersistenceMaxQPS, max).apply(cfg)
+	})
+}
+
+// WithIdentityRateLimit limits each caller identity, such as a worker's
+// "pid@host", to rps frontend requests per second in each namespace, so that a
+// misbehaving worker fleet on a shared server can't starve other callers of
+// the namespace-wide limit. Requests over the limit fail with a
+// ResourceExhausted error, which clients retry with backoff.
+func WithIdentityRateLimit(rps float64) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.IdentityRPS = rps
+	})
+}
+
+// WithIdentityRateLimitOverride gives identity its own limit, taking precedence
+// over WithIdentityRateLimit. A limit of zero exempts the identity.
+func WithIdentityRateLimitOverride(identity string, rps float64) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		if cfg.IdentityRPSOverrides == nil {
+			cfg.IdentityRPSOverrides = make(map[string]float64)
+		}
+		cfg.IdentityRPSOverrides[identity] = rps
+	})
+}
+
+// WithMemoryReporting logs the process's memory usage and configured cache
+// sizes every interval.
+func WithMemoryReporting(interval time.Duration) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.MemoryReportInterval = interval
+	})
+}
+
+// WithMaxMemory refuses new workflow starts with a ResourceExhausted error while
+// the process uses more than limit bytes, rather than growing until it is killed.
+//
+// Requests for existing workflows are still served so they can complete and
+// release memory. Usage is checked every two seconds, and measured as resident
+// set size on Linux and as memory obtained from the OS by the Go runtime
+// elsewhere.
+func WithMaxMemory(limit uint64) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.MaxMemory = limit
+	})
+}
+
+// WithCacheShrinking halves the history and events cache sizes, down to a
+// minimum of 16 entries, each time the process uses more than threshold bytes.
+// At most one shrink happens per minute.
+//
+// Caches are sized when the history shard is loaded, so each shrink also
+// reloads the shard and lowers the garbage collection target, which applies to
+// the whole process. The target is restored when memory usage drops below
+// threshold or by the time Stop returns, once no other server in the process
+// has it lowered, but caches are not grown again.
+func WithCacheShrinking(threshold uint64) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.CacheShrinkThreshold = threshold
+	})
+}
+
+// WithMetricsExporter pushes server metrics to a statsd ("statsd") or M3 ("m3")
+// collector at endpoint instead of serving them for Prometheus scraping.
+//
+// flushInterval applies to statsd only; M3 metrics are reported every second.
+// When unspecified, Prometheus metrics are served on port --port + 200.
+func WithMetricsExporter(exporter, endpoint string, flushInterval time.Duration) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.MetricsExporter = exporter
+		cfg.MetricsEndpoint = endpoint
+		cfg.MetricsFlushInterval = flushInterval
+	})
+}
+
+// WithMetricsTags adds tags to every emitted metric, for example to distinguish
+// temporalite instances reporting to a shared collector.
+func WithMetricsTags(tags map[string]string) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		if cfg.MetricsTags == nil {
+			cfg.MetricsTags = make(map[string]string, len(tags))
+		}
+		for k, v := range tags {
+			cfg.MetricsTags[k] = v
+		}
+	})
+}
+
+// WithMetricsPrefix sets the prefix of every emitted metric name. For the M3
+// exporter the prefix is used as the service name instead.
+func WithMetricsPrefix(prefix string) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.MetricsPrefix = prefix
+	})
+}
+
+// WithAuditLog appends a JSON line to the file at path for every namespace change,
+// admin service mutation such as adding or removing search attributes, and
+// workflow operation in the system namespace, where batch operations run.
+//
+// Each line records the caller's identity, authorization subject, and address.
+func WithAuditLog(path string) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.AuditLogPath = path
+	})
+}
+
+// WithAPIKeyFile rejects frontend requests unless their authorization header
+// holds one of the keys in the file at path, one per line as either KEY or
+// NAME:KEY. The key name is recorded as the caller's subject.
+//
+// This keeps a server shared over a private network from accepting anonymous
+// requests; it is not a substitute for TLS. Temporalite's own clients present a
+// key generated for each server. Upstream's system worker cannot present a key,
+// so requests to the system namespace are allowed without one. API keys cannot
+// be combined with WithAuthorizer.
+func WithAPIKeyFile(path string) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.APIKeyFile = path
+	})
+}
+
+// WithAuthorizer authorizes frontend requests with authorizer, using claims
+// derived by claimMapper. Either may be nil to keep upstream's default.
+//
+// Prefer this over passing temporal.WithAuthorizer or temporal.WithClaimMapper
+// to WithUpstreamOptions, which silently replaces the authorization set up by
+// WithAPIKeyFile or WithCertificateClaims; those cannot be combined with this.
+func WithAuthorizer(authorizer authorization.Authorizer, claimMapper authorization.ClaimMapper) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.Authorizer = authorizer
+		cfg.ClaimMapper = claimMapper
+	})
+}
+
+// WithFrontendTLS serves the frontend over mutual TLS using the certificate and
+// key in certFile and keyFile, requiring clients to present a certificate signed
+// by clientCAFile.
+//
+// Temporalite's own clients, including the system worker, present certFile, so it
+// must be signed by clientCAFile and allow client authentication. The web UI does
+// not support TLS and cannot connect to a TLS frontend.
+func WithFrontendTLS(certFile, keyFile, clientCAFile string) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.TLSCertFile = certFile
+		cfg.TLSKeyFile = keyFile
+		cfg.TLSClientCAFile = clientCAFile
+	})
+}
+
+// WithCertificateClaims grants permissions to TLS clients based on the subject
+// alternative names or common name of their certificate, as listed in the file
+// at path. Requires WithFrontendTLS.
+//
+// Each line holds a name followed by one or more NAMESPACE=ROLE grants, where
+// ROLE is reader, writer, worker (an alias of writer), or admin and the namespace * applies to the
+// whole server. Lines starting with # are ignored. For example:
+//
+//	payments.dev.example.com payments=admin billing=reader
+//	ops.dev.example.com *=admin
+//
+// Clients matching no line may only call APIs outside of user namespaces.
+func WithCertificateClaims(path string) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.CertClaimsFile = path
+	})
+}
+
+// WithSearchAttributes registers custom search attributes on Temporal start.
+//
+// Attributes already registered with the same type are left as is. Changing the
+// type of a registered attribute requires removing it first.
+func WithSearchAttributes(attrs map[string]enumspb.IndexedValueType) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		if cfg.SearchAttributes == nil {
+			cfg.SearchAttributes = make(map[string]enumspb.IndexedValueType)
+		}
+		for k, v := range attrs {
+			cfg.SearchAttributes[k] = v
+		}
+	})
+}
+
+// WithCustomDataStoreFactory stores Temporal state using a custom data store
+// factory instead of SQLite. This option is experimental.
+//
+// Upstream does not support custom visibility stores, so visibility records are
+// still kept in SQLite. Namespaces registered with WithNamespaces are created
+// through the frontend once the server starts.
+func WithCustomDataStoreFactory(factory persistenceclient.AbstractDataStoreFactory) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.DataStoreFactory = factory
+	})
+}
+
+// WithMaxOpenWorkflows refuses workflow starts with a ResourceExhausted error in
+// a namespace that already has max open workflows, so that a runaway load test
+// can't fill a shared server's database. Each namespace has its own limit.
+//
+// The limit is approximate, as open workflows are counted from visibility
+// records, which are written shortly after workflows start and close. Child
+// workflows are never refused, but count towards the limit.
+func WithMaxOpenWorkflows(max int) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.MaxOpenWorkflows = max
+	})
+}
+
+// WithTimeScale makes durable timers started by workflows, such as those of
+// workflow.Sleep and workflow.NewTimer, fire factor times sooner: with a factor
+// of 3600, a 24 hour timer fires after 24 seconds. Timers are shortened as they
+// are started, so histories record the scaled durations and timers started
+// before the option was set are unaffected.
+//
+// Cron schedules of workflows and child workflows started while the option is
+// set are accelerated too, so that an @daily workflow runs every 24 seconds with
+// a factor of 3600. The schedule is replaced with an @every schedule at the
+// scaled interval between its next two activations, no shorter than a second,
+// so schedules whose intervals vary, such as weekdays only, run at a steady
+// cadence, and workflows see the replaced schedule in their info.
+//
+// Only timers and cron schedules are scaled. Workflow and activity timeouts,
+// retry backoffs, and the time workflows see with workflow.Now keep running
+// at normal speed.
+func WithTimeScale(factor float64) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.TimeScale = factor
+	})
+}
+
+// WithHeartbeatTimeouts lowers the heartbeat timeout of activities that set one
+// to at most maxActivity, so that tests of activities that stop heartbeating,
+// and of cancellation, which workers only learn of when they heartbeat, don't
+// wait for production timeouts. Histories record the lowered timeouts, and
+// workers throttle heartbeats to match.
+//
+// workflowTask sets how long a workflow task may be extended by heartbeats
+// while it runs local activities before it times out, 30 minutes upstream.
+// Either timeout is left unchanged by passing zero.
+func WithHeartbeatTimeouts(maxActivity, workflowTask time.Duration) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.MaxHeartbeatTimeout = maxActivity
+		if workflowTask > 0 {
+			WithDynamicConfigValue(dynamicconfig.WorkflowTaskHeartbeatTimeout, workflowTask).apply(cfg)
+		}
+	})
+}
+
+// WithTightHeartbeatTimeouts is a preset of WithHeartbeatTimeouts for tests,
+// timing out activities that miss a heartbeat for a second, and workflow tasks
+// running local activities after ten seconds.
+func WithTightHeartbeatTimeouts() ServerOption {
+	return WithHeartbeatTimeouts(tightActivityHeartbeatTimeout, tightWorkflowTaskHeartbeatTimeout)
+}
+
+// WithBlobSizeLimit sets upstream's limit on the size of a single payload, such
+// as a workflow input or activity result, which defaults to 2 MiB. Requests from
+// clients carrying larger payloads are rejected, and workflows whose commands do
+// are failed. Warnings are logged for payloads over a quarter of the limit,
+// matching upstream's ratio.
+//
+// Lower it to catch oversized payloads early, or raise it to test legitimately
+// large payloads; messages over 4 MiB are still rejected by the GRPC transport.
+func WithBlobSizeLimit(maxBytes int) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		WithDynamicConfigValue(dynamicconfig.BlobSizeLimitError, maxBytes).apply(cfg)
+		WithDynamicConfigValue(dynamicconfig.BlobSizeLimitWarn, maxBytes/4).apply(cfg)
+	})
+}
+
+// WithHistoryLimits terminates workflows whose history grows beyond maxEvents
+// events or maxBytes bytes, so long-running loops hit the same guardrails locally
+// as on a production cluster. A limit of zero keeps upstream's default of 51200
+// events or 50 MiB. Warnings are logged once a history reaches a fifth of either
+// limit, matching upstream's ratio.
+//
+// The first time the history of a workflow terminated for exceeding a limit is
+// read, eg. in tctl or the web UI, a warning naming the limits and suggesting
+// continue-as-new is logged.
+func WithHistoryLimits(maxEvents, maxBytes int) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		if maxEvents > 0 {
+			WithDynamicConfigValue(dynamicconfig.HistoryCountLimitError, maxEvents).apply(cfg)
+			WithDynamicConfigValue(dynamicconfig.HistoryCountLimitWarn, maxEvents/5).apply(cfg)
+		}
+		if maxBytes > 0 {
+			WithDynamicConfigValue(dynamicconfig.HistorySizeLimitError, maxBytes).apply(cfg)
+			WithDynamicConfigValue(dynamicconfig.HistorySizeLimitWarn, maxBytes/5).apply(cfg)
+		}
+	})
+}
+
+// WithRetentionReport watches for closed workflows deleted after their
+// namespace's retention period, logging and counting each deletion and each
+// workflow kept past its retention period, and reporting them through
+// Server.RetentionReport.
+//
+// Closed workflows due for deletion within the next hour are listed once a
+// minute, so leave this off for servers closing many workflows.
+func WithRetentionReport() ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.RetentionReport = true
+	})
+}
+
+// WithStuckWorkflowDetection logs a warning with the workflow ID and last failure
+// when a workflow task has been attempted more than maxAttempts times, or has
+// gone longer than timeout without completing. Either check is disabled by
+// passing zero.
+//
+// This catches common mistakes when learning the SDK, such as nondeterministic
+// workflow code or a worker that isn't polling the workflow's task queue. Open
+// workflows are inspected every few seconds, so leave this off for servers with
+// many open workflows.
+func WithStuckWorkflowDetection(maxAttempts int, timeout time.Duration) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.StuckTaskAttempts = int32(maxAttempts)
+		cfg.StuckTaskTimeout = timeout
+	})
+}
+
+// WithHistoryGuard catches workflows stuck in unbounded loops before they bloat
+// the database. It lowers upstream's history warning limit to maxEvents events,
+// so the history service logs a warning with the workflow ID each time a run
+// beyond it completes a workflow task. If terminate is true, such runs are
+// terminated instead, as with WithHistoryLimits(maxEvents, 0), whose event
+// limit this replaces.
+//
+// The guard is meant to sit well below the limits of WithHistoryLimits, which
+// match a production cluster.
+func WithHistoryGuard(maxEvents int, terminate bool) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		if terminate {
+			WithHistoryLimits(maxEvents, 0).apply(cfg)
+			return
+		}
+		WithDynamicConfigValue(dynamicconfig.HistoryCountLimitWarn, maxEvents).apply(cfg)
+	})
+}
+
+// WithParentWatchdog stops the server when the process with the given PID exits,
+// typically os.Getppid(), so a server spawned by a test runner or IDE doesn't
+// outlive it while holding the database lock and ports. An error is then sent
+// on the channel returned by Server.Err.
+func WithParentWatchdog(pid int) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.ParentPID = pid
+	})
+}
+
+// WithFrontendListener serves the temporal-frontend GRPC service on an already
+// bound listener, such as one passed in by systemd socket activation, instead of
+// binding the frontend port. The frontend itself then listens on a system-chosen
+// loopback port, as do the metrics and pprof endpoints. It cannot be combined
+// with WithFrontendPort or WithFrontendIP.
+//
+// The listener is closed when the server stops.
+func WithFrontendListener(l net.Listener) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.FrontendListener = l
+	})
+}
+
+// WithLocalFrontend additionally serves the frontend without TLS on address,
+// such as 127.0.0.1:7234, so local tools can connect without a certificate while
+// remote workers use the mutual TLS frontend set up with WithFrontendTLS.
+//
+// Address must be on the loopback interface. Connections are forwarded to the
+// frontend presenting temporalite's own certificate, so this cannot be combined
+// with WithCertificateClaims or WithAuthorizer, which could grant that
+// certificate roles on the caller's behalf.
+func WithLocalFrontend(address string) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.LocalFrontendAddress = address
+	})
+}
+
+// WithTrafficMirror sends a copy of each call to the named WorkflowService
+// methods, such as StartWorkflowExecution, to the frontend of the Temporal
+// cluster at address after serving it locally, to compare the behavior of
+// temporalite and a production-like cluster. Calls whose outcome differs are
+// logged and counted in /debug/vars.
+//
+// Set forward to serve the methods from the remote cluster instead of locally.
+// Poll methods can only be forwarded, since mirroring them would take tasks from
+// the remote cluster's workers. Set useTLS to connect over TLS, verifying the
+// remote cluster against the system's root certificates.
+//
+// This is experimental.
+func WithTrafficMirror(address string, forward, useTLS bool, methods ...string) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.MirrorAddress = address
+		cfg.MirrorForward = forward
+		cfg.MirrorTLS = useTLS
+		cfg.MirrorMethods = methods
+	})
+}
+
+// WithDeterministicIDs derives run IDs and other generated UUIDs from seed, so
+// recorded demos and golden-file tests see the same IDs when a scenario is run
+// again. Task tokens embed these IDs and become reproducible too.
+//
+// This is a best-effort debugging aid and must not be used for real workloads:
+// IDs repeat across runs, and only match between runs that generate them in the
+// same order, which concurrent workers and timers do not guarantee. The seeded
+// generator replaces the UUID source for the whole process, including SDK
+// clients, until the server stops, so NewServer fails while another server in
+// the process uses this option.
+func WithDeterministicIDs(seed int64) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.IDSeed = &seed
+	})
+}
+
+// WithCoverageReport records the workflow and activity types that workers
+// execute and adds them to a JSON report at path when the server stops, so teams
+// can find workflows that integration tests never run. Counts already in the
+// file are kept, so one report can cover every server in a test run.
+//
+// This is experimental, and the report format may change.
+func WithCoverageReport(path string) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.CoverageReport = path
+	})
+}
+
+// WithFaultInjection lets tests force workflow and activity tasks to fail or
+// time out with Server.InjectFault. Task queue polls are only intercepted when
+// this is set.
+func WithFaultInjection() ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.FaultInjection = true
+	})
+}
+
 // WithUpstreamOptions registers Temporal server options.
 func WithUpstreamOptions(options ...temporal.ServerOption) ServerOption {
 	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
@@ -99,6 +892,21 @@ func WithUpstreamOptions(options ...temporal.ServerOption) ServerOption {
 	})
 }
 
+// WithServerOptionsModifier calls modify with the complete list of Temporal
+// server options temporalite constructs the upstream server with, including
+// those of WithUpstreamOptions, and uses the list it returns instead. This lets
+// libraries wrap, reorder, or drop options for integrations temporalite doesn't
+// support directly.
+//
+// Later options override earlier ones of the same kind upstream, so appending
+// replaces temporalite's own choices, which may break features that depend on
+// them. Several modifiers are applied in the order they are passed.
+func WithServerOptionsModifier(modify func([]temporal.ServerOption) []temporal.ServerOption) ServerOption {
+	return newApplyFuncContainer(func(cfg *liteconfig.Config) {
+		cfg.UpstreamModifiers = append(cfg.UpstreamModifiers, modify)
+	})
+}
+
 type applyFuncContainer struct {
 	applyInternal func(*liteconfig.Config)
 }
@@ -0,0 +1,39 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"go.temporal.io/server/common/log/tag"
+)
+
+const parentPollInterval = time.Second
+
+// watchParent stops the server once process pid exits.
+func (s *Server) watchParent(ctx context.Context, pid int) {
+	// An orphaned process is reparented, so a changed parent PID means the
+	// original parent exited even if its PID has since been reused.
+	wasParent := os.Getppid() == pid
+
+	ticker := time.NewTicker(parentPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if (wasParent && os.Getppid() != pid) || !processAlive(pid) {
+			break
+		}
+	}
+	s.logger.Warn("Parent process exited, stopping server", tag.NewInt("parent-pid", pid))
+	s.Stop()
+	reportErr(s.errCh, fmt.Errorf("parent process %d exited", pid))
+}
@@ -0,0 +1,143 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/common"
+	"google.golang.org/grpc"
+)
+
+// PauseTaskProcessing stops handing workflow and activity tasks to workers
+// until ResumeTaskProcessing is called, so tests can inspect workflows in an
+// intermediate state. Worker polls that arrive while processing is paused get
+// no task.
+//
+// Only tasks are paused: the server keeps accepting client requests, and timers
+// and timeouts keep firing, adding tasks that are delivered once processing
+// resumes. A poll that was already waiting for a task when processing paused
+// may still be handed one. Tasks of the temporal-system namespace, which run
+// the server's own system workflows, are never paused.
+func (s *Server) PauseTaskProcessing() {
+	s.taskGate.pause()
+}
+
+// ResumeTaskProcessing hands tasks to workers again after PauseTaskProcessing.
+func (s *Server) ResumeTaskProcessing() {
+	s.taskGate.resume()
+}
+
+// TaskProcessingPaused reports whether task processing is paused.
+func (s *Server) TaskProcessingPaused() bool {
+	return s.taskGate.paused()
+}
+
+// taskGate holds worker polls while task processing is paused. Polls are only
+// gated before they reach matching, so no task is ever taken from its queue
+// and withheld from the worker it was handed to.
+type taskGate struct {
+	mu sync.Mutex
+	// resumed is closed when processing resumes, and nil while it isn't paused.
+	resumed chan struct{}
+}
+
+// pollHoldMargin is how long before its deadline a poll held by a paused
+// taskGate is answered, so that the worker sees an empty response rather than
+// a deadline error.
+const pollHoldMargin = time.Second
+
+func (g *taskGate) pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed == nil {
+		g.resumed = make(chan struct{})
+	}
+}
+
+func (g *taskGate) resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed != nil {
+		close(g.resumed)
+		g.resumed = nil
+	}
+}
+
+func (g *taskGate) paused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.resumed != nil
+}
+
+// wait blocks while processing is paused, returning false if ctx is done, or
+// about to be, first.
+func (g *taskGate) wait(ctx context.Context) bool {
+	g.mu.Lock()
+	resumed := g.resumed
+	g.mu.Unlock()
+	if resumed == nil {
+		return true
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline.Add(-pollHoldMargin))
+		defer cancel()
+	}
+	select {
+	case <-resumed:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (g *taskGate) Intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	var empty interface{}
+	switch r := req.(type) {
+	case *workflowservice.PollWorkflowTaskQueueRequest:
+		if r.GetNamespace() == common.SystemLocalNamespace {
+			return handler(ctx, req)
+		}
+		empty = &workflowservice.PollWorkflowTaskQueueResponse{}
+	case *workflowservice.PollActivityTaskQueueRequest:
+		if r.GetNamespace() == common.SystemLocalNamespace {
+			return handler(ctx, req)
+		}
+		empty = &workflowservice.PollActivityTaskQueueResponse{}
+	default:
+		return handler(ctx, req)
+	}
+	if !g.wait(ctx) {
+		// An empty response looks to the worker like a poll that timed out.
+		return empty, nil
+	}
+	return handler(ctx, req)
+}
+
+// servePause handles POST /debug/pause and /debug/resume on the pprof port. As
+// with /debug/taskqueue, the frontend parameter selects a server when several
+// run in one process.
+func servePause(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s, err := debugServer(r.URL.Query().Get("frontend"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.URL.Path == "/debug/resume" {
+		s.ResumeTaskProcessing()
+	} else {
+		s.PauseTaskProcessing()
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.temporal.io/api/workflowservice/v1"
+	"google.golang.org/grpc"
+)
+
+func TestTaskGate(t *testing.T) {
+	tests := []struct {
+		name        string
+		paused      bool
+		req         interface{}
+		wantHandled bool
+	}{
+		{
+			name:        "running",
+			req:         &workflowservice.PollWorkflowTaskQueueRequest{Namespace: "default"},
+			wantHandled: true,
+		},
+		{
+			name:   "paused workflow poll",
+			paused: true,
+			req:    &workflowservice.PollWorkflowTaskQueueRequest{Namespace: "default"},
+		},
+		{
+			name:   "paused activity poll",
+			paused: true,
+			req:    &workflowservice.PollActivityTaskQueueRequest{Namespace: "default"},
+		},
+		{
+			name:        "paused system namespace",
+			paused:      true,
+			req:         &workflowservice.PollWorkflowTaskQueueRequest{Namespace: "temporal-system"},
+			wantHandled: true,
+		},
+		{
+			name:        "paused other request",
+			paused:      true,
+			req:         &workflowservice.DescribeNamespaceRequest{Namespace: "default"},
+			wantHandled: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var g taskGate
+			if tc.paused {
+				g.pause()
+			}
+			// The margin leaves the held poll 100ms before it is answered.
+			ctx, cancel := context.WithTimeout(context.Background(), pollHoldMargin+100*time.Millisecond)
+			defer cancel()
+
+			handled := false
+			resp, err := g.Intercept(ctx, tc.req, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				handled = true
+				return "task", nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if handled != tc.wantHandled {
+				t.Errorf("handled = %v, want %v", handled, tc.wantHandled)
+			}
+			if !tc.wantHandled && resp == "task" {
+				t.Errorf("paused poll got a task")
+			}
+			if ctx.Err() != nil {
+				t.Errorf("poll answered after its deadline")
+			}
+		})
+	}
+}
+
+func TestTaskGateResume(t *testing.T) {
+	var g taskGate
+	g.pause()
+	time.AfterFunc(50*time.Millisecond, g.resume)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	resp, err := g.Intercept(ctx, &workflowservice.PollWorkflowTaskQueueRequest{Namespace: "default"}, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "task", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != "task" {
+		t.Errorf("poll after resume got %v, want a task", resp)
+	}
+	if g.paused() {
+		t.Error("gate still paused after resume")
+	}
+}
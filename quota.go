@@ -0,0 +1,161 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/common"
+	"google.golang.org/grpc"
+)
+
+// openWorkflowRecount is how long a namespace's count of open workflows is
+// trusted before it is listed again. Starts in between are counted as they
+// are accepted.
+const openWorkflowRecount = time.Second
+
+// openWorkflowQuota refuses workflow starts in a namespace that already has max
+// open workflows.
+//
+// Visibility records are written shortly after a workflow starts or closes, so
+// the limit is approximate: workflows closed within the last moments may still
+// count against it. Child workflows and continued-as-new runs are not refused,
+// though they count once they are listed.
+type openWorkflowQuota struct {
+	max             int
+	workflowService func() (workflowservice.WorkflowServiceClient, error)
+
+	mu     sync.Mutex
+	counts map[string]*openWorkflowCount
+}
+
+type openWorkflowCount struct {
+	open    int
+	counted time.Time
+	// refreshing is set while a caller lists the namespace's open workflows.
+	refreshing bool
+}
+
+// count lists the open workflows in namespace, stopping once max are found.
+func (q *openWorkflowQuota) count(ctx context.Context, namespace string) (int, error) {
+	svc, err := q.workflowService()
+	if err != nil {
+		return 0, err
+	}
+	var (
+		open  int
+		token []byte
+	)
+	for {
+		resp, err := svc.ListOpenWorkflowExecutions(ctx, &workflowservice.ListOpenWorkflowExecutionsRequest{
+			Namespace:       namespace,
+			MaximumPageSize: int32(q.max - open),
+			NextPageToken:   token,
+		})
+		if err != nil {
+			return 0, err
+		}
+		open += len(resp.GetExecutions())
+		if token = resp.GetNextPageToken(); len(token) == 0 || open >= q.max {
+			return open, nil
+		}
+	}
+}
+
+// refreshDue reports whether the caller should list the open workflows in
+// namespace, marking the listing as in progress if so.
+func (q *openWorkflowQuota) refreshDue(namespace string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.counts == nil {
+		q.counts = make(map[string]*openWorkflowCount)
+	}
+	c, ok := q.counts[namespace]
+	if !ok {
+		c = &openWorkflowCount{}
+		q.counts[namespace] = c
+	}
+	if c.refreshing || time.Since(c.counted) <= openWorkflowRecount {
+		return false
+	}
+	c.refreshing = true
+	return true
+}
+
+// reserve counts a workflow about to start in namespace, returning an error if
+// the namespace is at its limit. A reservation for a start that fails is
+// returned with release.
+//
+// Open workflows are listed without holding q.mu, so that starts in other
+// namespaces, or in the same namespace while another call lists it, are not
+// held up by the listing. Those use the previous count.
+func (q *openWorkflowQuota) reserve(ctx context.Context, namespace string) error {
+	if q.refreshDue(namespace) {
+		listed := time.Now()
+		open, err := q.count(ctx, namespace)
+		q.mu.Lock()
+		c := q.counts[namespace]
+		c.refreshing = false
+		// On error, keep the previous count and let the start itself report
+		// the namespace or frontend error.
+		if err == nil {
+			c.open, c.counted = open, listed
+		}
+		q.mu.Unlock()
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	c := q.counts[namespace]
+	if c.counted.IsZero() {
+		// The namespace has not been listed yet.
+		return nil
+	}
+	if c.open >= q.max {
+		return serviceerror.NewResourceExhausted(fmt.Sprintf("namespace %q has reached its limit of %d open workflows; "+
+			"wait for workflows to complete or terminate some before starting more (configured with WithMaxOpenWorkflows or "+
+			"--max-open-workflows)", namespace, q.max))
+	}
+	c.open++
+	return nil
+}
+
+func (q *openWorkflowQuota) release(namespace string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if c, ok := q.counts[namespace]; ok && c.open > 0 {
+		c.open--
+	}
+}
+
+func (q *openWorkflowQuota) Intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	var namespace string
+	switch r := req.(type) {
+	case *workflowservice.StartWorkflowExecutionRequest:
+		namespace = r.GetNamespace()
+	case *workflowservice.SignalWithStartWorkflowExecutionRequest:
+		// The workflow may already be open, in which case it is only
+		// signaled, but it is counted until the next listing regardless.
+		namespace = r.GetNamespace()
+	default:
+		return handler(ctx, req)
+	}
+	if namespace == common.SystemLocalNamespace {
+		return handler(ctx, req)
+	}
+	if err := q.reserve(ctx, namespace); err != nil {
+		return nil, err
+	}
+	resp, err := handler(ctx, req)
+	if err != nil {
+		q.release(namespace)
+	}
+	return resp, err
+}
@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	commonpb "go.temporal.io/api/common/v1"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"google.golang.org/grpc"
+)
+
+// fakeOpenWorkflows lists open workflows in pages of at most the requested size.
+type fakeOpenWorkflows struct {
+	workflowservice.WorkflowServiceClient
+	open  int
+	err   error
+	lists int
+}
+
+func (f *fakeOpenWorkflows) ListOpenWorkflowExecutions(_ context.Context, req *workflowservice.ListOpenWorkflowExecutionsRequest, _ ...grpc.CallOption) (*workflowservice.ListOpenWorkflowExecutionsResponse, error) {
+	f.lists++
+	if f.err != nil {
+		return nil, f.err
+	}
+	resp := &workflowservice.ListOpenWorkflowExecutionsResponse{}
+	for i := 0; i < f.open && i < int(req.GetMaximumPageSize()); i++ {
+		resp.Executions = append(resp.Executions, &workflowpb.WorkflowExecutionInfo{Execution: &commonpb.WorkflowExecution{}})
+	}
+	return resp, nil
+}
+
+func TestOpenWorkflowQuota(t *testing.T) {
+	tests := []struct {
+		name      string
+		max       int
+		open      int
+		listErr   error
+		starts    int
+		wantErrAt int // index of the first refused start, or -1
+	}{
+		{name: "below limit", max: 3, open: 1, starts: 2, wantErrAt: -1},
+		{name: "reaches limit", max: 3, open: 1, starts: 3, wantErrAt: 2},
+		{name: "already full", max: 2, open: 5, starts: 1, wantErrAt: 0},
+		{name: "listing fails", max: 1, open: 5, listErr: errors.New("unavailable"), starts: 3, wantErrAt: -1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := &fakeOpenWorkflows{open: tc.open, err: tc.listErr}
+			q := &openWorkflowQuota{max: tc.max, workflowService: func() (workflowservice.WorkflowServiceClient, error) { return svc, nil }}
+			for i := 0; i < tc.starts; i++ {
+				err := q.reserve(context.Background(), "default")
+				if wantErr := i == tc.wantErrAt; (err != nil) != wantErr {
+					t.Fatalf("start %d: reserve() error = %v, want error %v", i, err, wantErr)
+				}
+				if err != nil {
+					break
+				}
+			}
+			if tc.listErr == nil && svc.lists != 1 {
+				t.Errorf("listed open workflows %d times, want once", svc.lists)
+			}
+		})
+	}
+}
+
+func TestOpenWorkflowQuotaRelease(t *testing.T) {
+	svc := &fakeOpenWorkflows{open: 0}
+	q := &openWorkflowQuota{max: 1, workflowService: func() (workflowservice.WorkflowServiceClient, error) { return svc, nil }}
+	if err := q.reserve(context.Background(), "default"); err != nil {
+		t.Fatal(err)
+	}
+	q.release("default")
+	if err := q.reserve(context.Background(), "default"); err != nil {
+		t.Errorf("reserve() after release: %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"strings"
+
+	"go.temporal.io/api/serviceerror"
+	"google.golang.org/grpc"
+)
+
+const workflowServicePrefix = "/temporal.api.workflowservice.v1.WorkflowService/"
+
+// readOnlyMethods are the workflow service methods that never modify persisted state.
+var readOnlyMethods = map[string]struct{}{
+	"CountWorkflowExecutions":        {},
+	"DescribeNamespace":              {},
+	"DescribeTaskQueue":              {},
+	"DescribeWorkflowExecution":      {},
+	"GetClusterInfo":                 {},
+	"GetSearchAttributes":            {},
+	"GetWorkflowExecutionHistory":    {},
+	"ListArchivedWorkflowExecutions": {},
+	"ListClosedWorkflowExecutions":   {},
+	"ListNamespaces":                 {},
+	"ListOpenWorkflowExecutions":     {},
+	"ListTaskQueuePartitions":        {},
+	"ListWorkflowExecutions":         {},
+	"ScanWorkflowExecutions":         {},
+}
+
+// rejectMutations fails every Temporal API request that could modify
+// persisted state, including task polls and the admin service.
+func rejectMutations(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !strings.HasPrefix(info.FullMethod, "/temporal.") {
+		return handler(ctx, req)
+	}
+	if strings.HasPrefix(info.FullMethod, workflowServicePrefix) {
+		if _, ok := readOnlyMethods[strings.TrimPrefix(info.FullMethod, workflowServicePrefix)]; ok {
+			return handler(ctx, req)
+		}
+	}
+	return nil, serviceerror.NewPermissionDenied("temporalite is running in read-only mode", "")
+}
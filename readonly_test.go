@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/server/common/log"
+)
+
+func TestReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "temporalite.db")
+	s, err := NewServer(WithDatabaseFilePath(path), WithDynamicPorts(), WithNamespaces("default"), WithLogger(log.NewNoopLogger()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := s.AwaitNamespace(ctx, "default"); err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.NewClient(ctx, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	run, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{ID: "order-1", TaskQueue: "orders", WorkflowRunTimeout: time.Second}, "Order")
+	c.Close()
+	s.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The run's timeout passes while the server starts, but its timer must not fire.
+	s, err = NewServer(WithDatabaseFilePath(path), WithDynamicPorts(), WithReadOnly(), WithLogger(log.NewNoopLogger()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	ctx, cancel = context.WithTimeout(context.Background(), 90*time.Second)
+	defer cancel()
+	if err := s.AwaitNamespace(ctx, "default"); err != nil {
+		t.Fatal(err)
+	}
+	c, err = s.NewClient(ctx, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{TaskQueue: "orders"}, "Order"); err == nil {
+		t.Error("started a workflow in read-only mode")
+	}
+	open, err := c.ListOpenWorkflow(ctx, &workflowservice.ListOpenWorkflowExecutionsRequest{Namespace: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(open.GetExecutions()) != 1 || open.GetExecutions()[0].GetExecution().GetRunId() != run.GetRunID() {
+		t.Errorf("open workflows = %v, want run %s", open.GetExecutions(), run.GetRunID())
+	}
+
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("read-only server modified the database file")
+	}
+}
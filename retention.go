@@ -0,0 +1,332 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/common"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/log/tag"
+
+	"github.com/DataDog/temporalite/internal/sqlitedb"
+)
+
+const (
+	retentionCheckInterval = time.Minute
+	// retentionLookahead is how long before their due time closed runs are
+	// watched. It bounds both the runs listed on each check and those reported
+	// as upcoming.
+	retentionLookahead = time.Hour
+	// retentionGrace is how long a run may outlive its due time before it is
+	// reported as overdue, allowing for delays in processing deletion timers.
+	retentionGrace = 5 * time.Minute
+	// maxRetentionDeletions bounds the number of deletions remembered per server.
+	maxRetentionDeletions = 1000
+	// maxReportedRuns bounds the overdue and upcoming runs listed in a report.
+	maxReportedRuns = 20
+	// maxLoggedRuns bounds the deletions and overdue runs logged one by one on
+	// each check; the rest are logged as a count.
+	maxLoggedRuns = 10
+)
+
+var retentionDeletions = expvar.NewMap("temporalite.retention.deleted")
+
+// RetainedRun is a closed workflow run kept until its namespace's retention
+// period has passed.
+type RetainedRun struct {
+	Namespace  string    `json:"namespace"`
+	WorkflowID string    `json:"workflow_id"`
+	RunID      string    `json:"run_id"`
+	CloseTime  time.Time `json:"close_time"`
+	// DueTime is when the run is deleted under the namespace's current
+	// retention period. Runs are scheduled for deletion when they close, so a
+	// retention period changed since then does not apply to them.
+	DueTime time.Time `json:"due_time"`
+	// DeletedAt is when the run was found to be deleted.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// NamespaceRetention summarizes the closed runs of a namespace due for
+// deletion.
+type NamespaceRetention struct {
+	Namespace string        `json:"namespace"`
+	Retention time.Duration `json:"retention"`
+	// Due counts the closed runs due for deletion within the next hour,
+	// including overdue ones.
+	Due     int `json:"due"`
+	Overdue int `json:"overdue"`
+}
+
+// RetentionReport describes what retention deleted since the server started
+// and what it will delete next.
+type RetentionReport struct {
+	Namespaces []NamespaceRetention `json:"namespaces"`
+	// Deleted lists runs deleted since the server started, most recent first.
+	Deleted []RetainedRun `json:"deleted"`
+	// Overdue lists the runs longest past their due time that are still stored.
+	Overdue []RetainedRun `json:"overdue"`
+	// Upcoming lists the runs due to be deleted next.
+	Upcoming []RetainedRun `json:"upcoming"`
+	// LastScan is when the server last listed closed runs, or zero before the
+	// first listing or when the report is disabled.
+	LastScan time.Time `json:"last_scan"`
+}
+
+// retentionLog notices closed runs disappearing from visibility once they are
+// due, which happens when history deletes them after their retention period.
+type retentionLog struct {
+	mu sync.Mutex
+	// seed holds closed runs read from the database before the server started,
+	// so that runs deleted while it starts up are noticed too.
+	seed       []sqlitedb.ClosedRun
+	runs       map[string]RetainedRun
+	warned     map[string]bool
+	namespaces []NamespaceRetention
+	deleted    []RetainedRun
+	lastScan   time.Time
+}
+
+// watchRetention periodically lists the closed runs of every user namespace
+// due for deletion soon, and logs each run deleted after its retention period
+// and each run kept past it.
+func (s *Server) watchRetention(ctx context.Context) {
+	ticker := time.NewTicker(retentionCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		svc, err := s.workflowService()
+		if err != nil {
+			continue
+		}
+		_ = s.retention.scan(ctx, svc, s.logger, time.Now())
+	}
+}
+
+// listNamespaceRetention returns the retention period of each user namespace
+// and the names of namespaces by ID.
+func listNamespaceRetention(ctx context.Context, svc workflowservice.WorkflowServiceClient) ([]NamespaceRetention, map[string]string, error) {
+	var (
+		namespaces []NamespaceRetention
+		names      = make(map[string]string)
+		token      []byte
+	)
+	for {
+		resp, err := svc.ListNamespaces(ctx, &workflowservice.ListNamespacesRequest{PageSize: 100, NextPageToken: token})
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, ns := range resp.GetNamespaces() {
+			info := ns.GetNamespaceInfo()
+			if info.GetName() == common.SystemLocalNamespace {
+				continue
+			}
+			names[info.GetId()] = info.GetName()
+			var retention time.Duration
+			if ttl := ns.GetConfig().GetWorkflowExecutionRetentionTtl(); ttl != nil {
+				retention = *ttl
+			}
+			namespaces = append(namespaces, NamespaceRetention{Namespace: info.GetName(), Retention: retention})
+		}
+		if token = resp.GetNextPageToken(); len(token) == 0 {
+			return namespaces, names, nil
+		}
+	}
+}
+
+func (l *retentionLog) scan(ctx context.Context, svc workflowservice.WorkflowServiceClient, logger log.Logger, now time.Time) error {
+	namespaces, names, err := listNamespaceRetention(ctx, svc)
+	if err != nil {
+		return err
+	}
+	horizon := now.Add(retentionLookahead)
+	retention := make(map[string]time.Duration, len(namespaces))
+	current := make(map[string]RetainedRun)
+	for _, ns := range namespaces {
+		retention[ns.Namespace] = ns.Retention
+		closed, err := listClosedInNamespace(ctx, svc, ns.Namespace, time.Time{}, horizon.Add(-ns.Retention))
+		if err != nil {
+			return err
+		}
+		for _, ev := range closed {
+			current[ev.RunID] = RetainedRun{
+				Namespace:  ns.Namespace,
+				WorkflowID: ev.WorkflowID,
+				RunID:      ev.RunID,
+				CloseTime:  ev.CloseTime,
+				DueTime:    ev.CloseTime.Add(ns.Retention),
+			}
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	previous := l.runs
+	if previous == nil {
+		previous = make(map[string]RetainedRun)
+		for _, run := range l.seed {
+			name, ok := names[run.NamespaceID]
+			if !ok || run.CloseTime.Add(retention[name]).After(horizon) {
+				continue
+			}
+			previous[run.RunID] = RetainedRun{
+				Namespace:  name,
+				WorkflowID: run.WorkflowID,
+				RunID:      run.RunID,
+				CloseTime:  run.CloseTime,
+				DueTime:    run.CloseTime.Add(retention[name]),
+			}
+		}
+		l.seed = nil
+	}
+
+	// Runs that disappear before they are due, such as those deleted through
+	// the API or along with their namespace, were not deleted by retention.
+	var deleted []RetainedRun
+	for runID, run := range previous {
+		if _, ok := current[runID]; ok {
+			continue
+		}
+		if _, ok := retention[run.Namespace]; ok && !now.Before(run.DueTime) {
+			deleted = append(deleted, run)
+		}
+	}
+	sortByDueTime(deleted)
+	for i, run := range deleted {
+		l.recordDeletion(run, now, logger, i < maxLoggedRuns)
+	}
+	if n := len(deleted) - maxLoggedRuns; n > 0 {
+		logger.Info("Deleted more closed workflows after retention period", tag.Counter(n))
+	}
+
+	warned := make(map[string]bool)
+	var overdue []RetainedRun
+	for i, ns := range namespaces {
+		for _, run := range current {
+			if run.Namespace != ns.Namespace {
+				continue
+			}
+			namespaces[i].Due++
+			if now.Before(run.DueTime.Add(retentionGrace)) {
+				continue
+			}
+			namespaces[i].Overdue++
+			warned[run.RunID] = true
+			if !l.warned[run.RunID] {
+				overdue = append(overdue, run)
+			}
+		}
+	}
+	sortByDueTime(overdue)
+	for i, run := range overdue {
+		if i == maxLoggedRuns {
+			logger.Warn("More closed workflows are past their namespace's retention period but have not been deleted",
+				tag.Counter(len(overdue)-maxLoggedRuns))
+			break
+		}
+		logger.Warn("Closed workflow is past its namespace's retention period but has not been deleted; "+
+			"deletion is scheduled with the retention period in effect when the workflow closed",
+			tag.WorkflowNamespace(run.Namespace),
+			tag.WorkflowID(run.WorkflowID),
+			tag.WorkflowRunID(run.RunID),
+			tag.NewTimeTag("close-time", run.CloseTime),
+			tag.NewDurationTag("retention", retention[run.Namespace]),
+		)
+	}
+
+	l.runs, l.warned, l.namespaces, l.lastScan = current, warned, namespaces, now
+	return nil
+}
+
+func sortByDueTime(runs []RetainedRun) {
+	sort.Slice(runs, func(i, j int) bool { return runs[i].DueTime.Before(runs[j].DueTime) })
+}
+
+// recordDeletion remembers the deletion of run, and logs it if verbose is set.
+// l.mu must be held.
+func (l *retentionLog) recordDeletion(run RetainedRun, now time.Time, logger log.Logger, verbose bool) {
+	run.DeletedAt = &now
+	if len(l.deleted) >= maxRetentionDeletions {
+		l.deleted = l.deleted[1:]
+	}
+	l.deleted = append(l.deleted, run)
+	retentionDeletions.Add(run.Namespace, 1)
+	if !verbose {
+		return
+	}
+	logger.Info("Deleted closed workflow after retention period",
+		tag.WorkflowNamespace(run.Namespace),
+		tag.WorkflowID(run.WorkflowID),
+		tag.WorkflowRunID(run.RunID),
+		tag.NewTimeTag("close-time", run.CloseTime),
+		tag.NewDurationTag("retention", run.DueTime.Sub(run.CloseTime)),
+	)
+}
+
+func (l *retentionLog) report() *RetentionReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r := &RetentionReport{
+		Namespaces: append([]NamespaceRetention(nil), l.namespaces...),
+		Deleted:    make([]RetainedRun, 0, len(l.deleted)),
+		Overdue:    []RetainedRun{},
+		Upcoming:   []RetainedRun{},
+		LastScan:   l.lastScan,
+	}
+	for i := len(l.deleted) - 1; i >= 0; i-- {
+		r.Deleted = append(r.Deleted, l.deleted[i])
+	}
+	for _, run := range l.runs {
+		if l.lastScan.Before(run.DueTime.Add(retentionGrace)) {
+			r.Upcoming = append(r.Upcoming, run)
+		} else {
+			r.Overdue = append(r.Overdue, run)
+		}
+	}
+	for _, runs := range []*[]RetainedRun{&r.Overdue, &r.Upcoming} {
+		sortByDueTime(*runs)
+		if len(*runs) > maxReportedRuns {
+			*runs = (*runs)[:maxReportedRuns]
+		}
+	}
+	return r
+}
+
+// RetentionReport lists the closed runs deleted after their namespace's
+// retention period since the server started, those overdue for deletion, and
+// those due within the next hour. Closed runs are listed once a minute, so
+// deletions are seen up to a minute late.
+//
+// The report is empty unless the server was created with WithRetentionReport.
+func (s *Server) RetentionReport() *RetentionReport {
+	return s.retention.report()
+}
+
+// serveRetention handles /debug/retention on the pprof port. As with
+// /debug/taskqueue, the frontend parameter selects a server when several run
+// in one process.
+func serveRetention(w http.ResponseWriter, r *http.Request) {
+	s, err := debugServer(r.URL.Query().Get("frontend"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.RetentionReport())
+}
@@ -0,0 +1,90 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	commonpb "go.temporal.io/api/common/v1"
+	namespacepb "go.temporal.io/api/namespace/v1"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/common/log"
+	"google.golang.org/grpc"
+)
+
+// fakeClosedRuns serves one namespace with the given closed runs.
+type fakeClosedRuns struct {
+	workflowservice.WorkflowServiceClient
+	retention time.Duration
+	closed    map[string]time.Time
+}
+
+func (f *fakeClosedRuns) ListNamespaces(context.Context, *workflowservice.ListNamespacesRequest, ...grpc.CallOption) (*workflowservice.ListNamespacesResponse, error) {
+	return &workflowservice.ListNamespacesResponse{Namespaces: []*workflowservice.DescribeNamespaceResponse{{
+		NamespaceInfo: &namespacepb.NamespaceInfo{Name: "default", Id: "default-id"},
+		Config:        &namespacepb.NamespaceConfig{WorkflowExecutionRetentionTtl: &f.retention},
+	}}}, nil
+}
+
+func (f *fakeClosedRuns) ListClosedWorkflowExecutions(_ context.Context, req *workflowservice.ListClosedWorkflowExecutionsRequest, _ ...grpc.CallOption) (*workflowservice.ListClosedWorkflowExecutionsResponse, error) {
+	resp := &workflowservice.ListClosedWorkflowExecutionsResponse{}
+	for runID, closeTime := range f.closed {
+		if closeTime.After(*req.GetStartTimeFilter().GetLatestTime()) {
+			continue
+		}
+		closeTime := closeTime
+		resp.Executions = append(resp.Executions, &workflowpb.WorkflowExecutionInfo{
+			Execution: &commonpb.WorkflowExecution{WorkflowId: runID, RunId: runID},
+			CloseTime: &closeTime,
+		})
+	}
+	return resp, nil
+}
+
+func TestRetentionLog(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	svc := &fakeClosedRuns{
+		retention: 2 * time.Hour,
+		closed: map[string]time.Time{
+			"due":     now.Add(-110 * time.Minute),
+			"early":   now.Add(-100 * time.Minute),
+			"overdue": now.Add(-3 * time.Hour),
+			"later":   now.Add(-30 * time.Minute),
+		},
+	}
+	var l retentionLog
+	ctx := context.Background()
+
+	if err := l.scan(ctx, svc, log.NewNoopLogger(), now); err != nil {
+		t.Fatal(err)
+	}
+	r := l.report()
+	if ns := r.Namespaces[0]; ns.Due != 3 || ns.Overdue != 1 {
+		t.Errorf("first scan counted %d due and %d overdue, want 3 and 1", ns.Due, ns.Overdue)
+	}
+	if len(r.Overdue) != 1 || r.Overdue[0].RunID != "overdue" {
+		t.Errorf("overdue = %+v, want only the overdue run", r.Overdue)
+	}
+	if len(r.Upcoming) != 2 || r.Upcoming[0].RunID != "due" || r.Upcoming[1].RunID != "early" {
+		t.Errorf("upcoming = %+v, want the due and early runs", r.Upcoming)
+	}
+
+	// The early run disappears before it is due, as if deleted through the API.
+	delete(svc.closed, "due")
+	delete(svc.closed, "early")
+	if err := l.scan(ctx, svc, log.NewNoopLogger(), now.Add(15*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	r = l.report()
+	if len(r.Deleted) != 1 || r.Deleted[0].RunID != "due" {
+		t.Errorf("deleted = %+v, want only the due run", r.Deleted)
+	}
+	if len(r.Upcoming) != 0 {
+		t.Errorf("upcoming = %+v, want none", r.Upcoming)
+	}
+}
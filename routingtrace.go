@@ -0,0 +1,289 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/common"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/log/tag"
+	"google.golang.org/grpc"
+)
+
+// maxRoutingEvents bounds the number of events kept per traced workflow.
+const maxRoutingEvents = 1000
+
+// RoutingEvent is one step of a traced workflow's tasks through the server.
+type RoutingEvent struct {
+	Time  time.Time `json:"time"`
+	RunID string    `json:"run_id,omitempty"`
+	// Stage is one of started, signaled, workflow_task_dispatched,
+	// workflow_task_completed, workflow_task_failed, activity_task_dispatched,
+	// activity_task_completed, activity_task_failed, or activity_task_canceled.
+	Stage  string `json:"stage"`
+	Detail string `json:"detail"`
+	// Latency is the time spent in the previous stage: for dispatches, from the
+	// task being scheduled to it being delivered to a poller, and for
+	// completions, from delivery to the worker's response.
+	Latency time.Duration `json:"latency_ns,omitempty"`
+}
+
+type routingTrace struct {
+	events []RoutingEvent
+	// dispatched holds the delivery time of tasks awaiting a response, by run
+	// and schedule event ID.
+	dispatched map[string]time.Time
+}
+
+// routingTracer records the tasks of traced workflows as they pass through the
+// frontend. The time between a task being scheduled and delivered to a poller
+// covers both the history service's transfer queue and the matching service.
+type routingTracer struct {
+	logger     log.Logger
+	serializer common.TaskTokenSerializer
+
+	mu     sync.Mutex
+	traces map[string]*routingTrace
+}
+
+func newRoutingTracer(logger log.Logger) *routingTracer {
+	return &routingTracer{
+		logger:     logger,
+		serializer: common.NewProtoTaskTokenSerializer(),
+		traces:     make(map[string]*routingTrace),
+	}
+}
+
+func routingTraceKey(namespace, workflowID string) string {
+	return namespace + "/" + workflowID
+}
+
+func (t *routingTracer) start(namespace, workflowID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.traces[routingTraceKey(namespace, workflowID)]; !ok {
+		t.traces[routingTraceKey(namespace, workflowID)] = &routingTrace{dispatched: make(map[string]time.Time)}
+	}
+}
+
+func (t *routingTracer) stop(namespace, workflowID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.traces, routingTraceKey(namespace, workflowID))
+}
+
+func (t *routingTracer) events(namespace, workflowID string) ([]RoutingEvent, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	trace, ok := t.traces[routingTraceKey(namespace, workflowID)]
+	if !ok {
+		return nil, false
+	}
+	return append([]RoutingEvent{}, trace.events...), true
+}
+
+func (t *routingTracer) tracing() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.traces) > 0
+}
+
+// record appends an event to the trace of workflowID, if it is traced. The
+// delivery time of dispatchedTask is remembered so the latency of the response
+// to it, recorded with completedTask, can be measured.
+func (t *routingTracer) record(namespace, workflowID string, event RoutingEvent, dispatchedTask, completedTask string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	trace, ok := t.traces[routingTraceKey(namespace, workflowID)]
+	if !ok {
+		return
+	}
+	if dispatchedTask != "" {
+		trace.dispatched[dispatchedTask] = event.Time
+	}
+	if dispatched, ok := trace.dispatched[completedTask]; ok && completedTask != "" {
+		event.Latency = event.Time.Sub(dispatched)
+		delete(trace.dispatched, completedTask)
+	}
+	if len(trace.events) >= maxRoutingEvents {
+		trace.events = trace.events[1:]
+	}
+	trace.events = append(trace.events, event)
+	t.logger.Info("Task routing trace",
+		tag.WorkflowNamespace(namespace),
+		tag.WorkflowID(workflowID),
+		tag.WorkflowRunID(event.RunID),
+		tag.NewStringTag("stage", event.Stage),
+		tag.NewStringTag("detail", event.Detail),
+		tag.NewDurationTag("latency", event.Latency),
+	)
+}
+
+// recordCompletion records a worker's response to the task identified by token.
+func (t *routingTracer) recordCompletion(namespace string, token []byte, stage, identity string, err error) {
+	task, terr := t.serializer.Deserialize(token)
+	if terr != nil {
+		return
+	}
+	detail := "by " + identity
+	if err != nil {
+		detail += ", rejected: " + err.Error()
+	}
+	t.record(namespace, task.GetWorkflowId(), RoutingEvent{
+		Time:   time.Now(),
+		RunID:  task.GetRunId(),
+		Stage:  stage,
+		Detail: detail,
+	}, "", fmt.Sprintf("%s/%d", task.GetRunId(), task.GetScheduleId()))
+}
+
+// recordDispatch records a task delivered to a poller. Its latency is measured
+// from when the history service scheduled it.
+func (t *routingTracer) recordDispatch(namespace, workflowID, runID string, token []byte, stage, detail string, scheduled *time.Time) {
+	task, err := t.serializer.Deserialize(token)
+	if err != nil {
+		return
+	}
+	event := RoutingEvent{Time: time.Now(), RunID: runID, Stage: stage, Detail: detail}
+	if scheduled != nil {
+		event.Latency = event.Time.Sub(*scheduled)
+	}
+	t.record(namespace, workflowID, event, fmt.Sprintf("%s/%d", task.GetRunId(), task.GetScheduleId()), "")
+}
+
+func (t *routingTracer) Intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if !t.tracing() {
+		return resp, err
+	}
+
+	switch req := req.(type) {
+	case *workflowservice.StartWorkflowExecutionRequest:
+		if err == nil {
+			t.record(req.GetNamespace(), req.GetWorkflowId(), RoutingEvent{
+				Time:   time.Now(),
+				RunID:  resp.(*workflowservice.StartWorkflowExecutionResponse).GetRunId(),
+				Stage:  "started",
+				Detail: fmt.Sprintf("first workflow task scheduled on task queue %s", req.GetTaskQueue().GetName()),
+			}, "", "")
+		}
+	case *workflowservice.SignalWithStartWorkflowExecutionRequest:
+		if err == nil {
+			t.record(req.GetNamespace(), req.GetWorkflowId(), RoutingEvent{
+				Time:   time.Now(),
+				RunID:  resp.(*workflowservice.SignalWithStartWorkflowExecutionResponse).GetRunId(),
+				Stage:  "signaled",
+				Detail: fmt.Sprintf("signal %s, starting the workflow on task queue %s if not running", req.GetSignalName(), req.GetTaskQueue().GetName()),
+			}, "", "")
+		}
+	case *workflowservice.SignalWorkflowExecutionRequest:
+		if err == nil {
+			t.record(req.GetNamespace(), req.GetWorkflowExecution().GetWorkflowId(), RoutingEvent{
+				Time:   time.Now(),
+				RunID:  req.GetWorkflowExecution().GetRunId(),
+				Stage:  "signaled",
+				Detail: fmt.Sprintf("signal %s", req.GetSignalName()),
+			}, "", "")
+		}
+	case *workflowservice.PollWorkflowTaskQueueRequest:
+		if r, ok := resp.(*workflowservice.PollWorkflowTaskQueueResponse); ok && len(r.GetTaskToken()) > 0 {
+			t.recordDispatch(req.GetNamespace(), r.GetWorkflowExecution().GetWorkflowId(), r.GetWorkflowExecution().GetRunId(), r.GetTaskToken(),
+				"workflow_task_dispatched",
+				fmt.Sprintf("attempt %d delivered from task queue %s to %s", r.GetAttempt(), req.GetTaskQueue().GetName(), req.GetIdentity()),
+				r.GetScheduledTime(),
+			)
+		}
+	case *workflowservice.PollActivityTaskQueueRequest:
+		if r, ok := resp.(*workflowservice.PollActivityTaskQueueResponse); ok && len(r.GetTaskToken()) > 0 {
+			t.recordDispatch(req.GetNamespace(), r.GetWorkflowExecution().GetWorkflowId(), r.GetWorkflowExecution().GetRunId(), r.GetTaskToken(),
+				"activity_task_dispatched",
+				fmt.Sprintf("activity %s (%s) attempt %d delivered from task queue %s to %s",
+					r.GetActivityId(), r.GetActivityType().GetName(), r.GetAttempt(), req.GetTaskQueue().GetName(), req.GetIdentity()),
+				r.GetCurrentAttemptScheduledTime(),
+			)
+		}
+	case *workflowservice.RespondWorkflowTaskCompletedRequest:
+		t.recordCompletion(req.GetNamespace(), req.GetTaskToken(), "workflow_task_completed", req.GetIdentity(), err)
+	case *workflowservice.RespondWorkflowTaskFailedRequest:
+		t.recordCompletion(req.GetNamespace(), req.GetTaskToken(), "workflow_task_failed", req.GetIdentity(), err)
+	case *workflowservice.RespondActivityTaskCompletedRequest:
+		t.recordCompletion(req.GetNamespace(), req.GetTaskToken(), "activity_task_completed", req.GetIdentity(), err)
+	case *workflowservice.RespondActivityTaskFailedRequest:
+		t.recordCompletion(req.GetNamespace(), req.GetTaskToken(), "activity_task_failed", req.GetIdentity(), err)
+	case *workflowservice.RespondActivityTaskCanceledRequest:
+		t.recordCompletion(req.GetNamespace(), req.GetTaskToken(), "activity_task_canceled", req.GetIdentity(), err)
+	}
+	return resp, err
+}
+
+// TraceWorkflowRouting starts recording each task of workflowID as it is
+// dispatched to a poller and completed, with timestamps and latencies, to show
+// where a workflow's latency comes from or where its tasks are stuck.
+//
+// Tracing is per workflow ID, so it covers all of its runs, and only observes
+// what passes through the frontend: tasks scheduled before tracing started are
+// reported when they are dispatched.
+func (s *Server) TraceWorkflowRouting(namespace, workflowID string) {
+	s.routingTracer.start(namespace, workflowID)
+}
+
+// StopTracingWorkflowRouting stops tracing workflowID and discards its events.
+func (s *Server) StopTracingWorkflowRouting(namespace, workflowID string) {
+	s.routingTracer.stop(namespace, workflowID)
+}
+
+// WorkflowRoutingTrace returns the events recorded for workflowID, oldest
+// first, and whether it is being traced.
+func (s *Server) WorkflowRoutingTrace(namespace, workflowID string) ([]RoutingEvent, bool) {
+	return s.routingTracer.events(namespace, workflowID)
+}
+
+// serveRoutingTrace handles /debug/routingtrace?namespace=NS&workflow_id=ID on
+// the pprof port. POST starts tracing the workflow, GET returns its events, and
+// DELETE stops tracing it. The frontend parameter selects a server when several
+// run in one process.
+func serveRoutingTrace(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	workflowID := query.Get("workflow_id")
+	if workflowID == "" {
+		http.Error(w, "workflow_id parameter is required", http.StatusBadRequest)
+		return
+	}
+	namespace := query.Get("namespace")
+	if namespace == "" {
+		namespace = "default"
+	}
+	s, err := debugServer(query.Get("frontend"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		s.TraceWorkflowRouting(namespace, workflowID)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		s.StopTracingWorkflowRouting(namespace, workflowID)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		events, ok := s.WorkflowRoutingTrace(namespace, workflowID)
+		if !ok {
+			http.Error(w, fmt.Sprintf("workflow %s is not being traced", workflowID), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(events)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,49 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/server/api/adminservice/v1"
+)
+
+// SearchAttributes returns the custom search attributes registered with the server.
+func (s *Server) SearchAttributes(ctx context.Context) (map[string]enumspb.IndexedValueType, error) {
+	admin, err := s.adminService()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := admin.GetSearchAttributes(ctx, &adminservice.GetSearchAttributesRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetCustomAttributes(), nil
+}
+
+// AddSearchAttributes registers custom search attributes, returning once they
+// can be used. Attributes that are already registered must not be included.
+func (s *Server) AddSearchAttributes(ctx context.Context, attrs map[string]enumspb.IndexedValueType) error {
+	admin, err := s.adminService()
+	if err != nil {
+		return err
+	}
+	_, err = admin.AddSearchAttributes(ctx, &adminservice.AddSearchAttributesRequest{SearchAttributes: attrs})
+	return err
+}
+
+// RemoveSearchAttributes unregisters custom search attributes.
+//
+// In file-backed mode registered search attributes persist across restarts, so
+// this is the only way to undo a mistaken registration.
+func (s *Server) RemoveSearchAttributes(ctx context.Context, names ...string) error {
+	admin, err := s.adminService()
+	if err != nil {
+		return err
+	}
+	_, err = admin.RemoveSearchAttributes(ctx, &adminservice.RemoveSearchAttributesRequest{SearchAttributes: names})
+	return err
+}
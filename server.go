@@ -6,19 +6,40 @@ package temporalite
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"math"
+	"net"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/api/workflowservice/v1"
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/server/api/adminservice/v1"
 	"go.temporal.io/server/common/authorization"
 	"go.temporal.io/server/common/config"
 	"go.temporal.io/server/common/dynamicconfig"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/log/tag"
+	"go.temporal.io/server/common/metrics"
+	"go.temporal.io/server/common/primitives"
+	"go.temporal.io/server/common/rpc/encryption"
 	"go.temporal.io/server/schema/sqlite"
 	"go.temporal.io/server/temporal"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/test/bufconn"
 
 	"github.com/DataDog/temporalite/internal/liteconfig"
+	"github.com/DataDog/temporalite/internal/recording"
+	"github.com/DataDog/temporalite/internal/replication"
+	"github.com/DataDog/temporalite/internal/sqlitedb"
 )
 
 // Server wraps temporal.Server.
@@ -26,7 +47,45 @@ type Server struct {
 	internal         temporal.Server
 	ui               liteconfig.UIServer
 	frontendHostPort string
+	pipe             *bufconn.Listener
 	config           *liteconfig.Config
+	upstreamConfig   *config.Config
+	errCh            chan error
+	stopHooks        []func()
+	dynamicConfig    *dynamicconfig.MutableEphemeralClient
+	replicator       *replication.Replicator
+	memoryGuard      *memoryGuard
+	faults           *faultInjector
+	usage            usageCounter
+	startConflicts   startConflictLog
+	retention        retentionLog
+	routingTracer    *routingTracer
+	barriers         *barrierSet
+	peers            forwardedPeers
+	taskGate         taskGate
+	clientTLS        *tls.Config
+	// internalAPIKey authenticates temporalite's own clients when API keys
+	// are required.
+	internalAPIKey string
+	// systemWorkerListener forwards upstream's system worker to the frontend
+	// when API keys are required.
+	systemWorkerListener net.Listener
+	searchAttributes     map[string]enumspb.IndexedValueType
+	// metrics reports temporalite's own metrics alongside upstream's, or is nil
+	// if none are reported.
+	metrics metrics.Client
+	// logger logs what happens while the server runs, such as warnings about
+	// stuck workflows or the database size, alongside the Temporal services.
+	// c.Logger only logs startup and configuration messages.
+	logger log.Logger
+
+	backgroundCtx  context.Context
+	stopBackground context.CancelFunc
+	// stopOnce guards Stop, as upstream's Stop panics when called twice.
+	stopOnce sync.Once
+
+	connMu sync.Mutex
+	conn   *grpc.ClientConn
 }
 
 type ServerOption interface {
@@ -34,7 +93,21 @@ type ServerOption interface {
 }
 
 // NewServer returns a new instance of Server.
+//
+// Failures caused by a port already in use, a database without the expected
+// schema, or a locked database match ErrPortInUse, ErrSchemaMismatch, or
+// ErrDatabaseLocked respectively when tested with errors.Is. Unless dynamic
+// ports are used, the ports of every service are checked before the server is
+// created, moving on to the next port first when WithPortRetries is passed.
 func NewServer(opts ...ServerOption) (*Server, error) {
+	return NewServerWithContext(context.Background(), opts...)
+}
+
+// NewServerWithContext is like NewServer, but stops setting up the database and
+// registering namespaces once ctx is done, returning ctx's error.
+//
+// The context only bounds setup; it does not affect the returned server.
+func NewServerWithContext(ctx context.Context, opts ...ServerOption) (*Server, error) {
 	c, err := liteconfig.NewDefaultConfig()
 	if err != nil {
 		return nil, err
@@ -49,78 +122,585 @@ func NewServer(opts ...ServerOption) (*Server, error) {
 		}
 	}
 
+	if c.FrontendListener != nil {
+		if c.FrontendPort != 0 || c.FrontendIP != "" {
+			return nil, errors.New("ERROR: a frontend listener cannot be combined with a frontend port or IP")
+		}
+		c.DynamicPorts, c.FrontendIP = true, "127.0.0.1"
+	}
+
+	// Check the service ports up front, as upstream only fails to bind them
+	// once the server is created or started.
+	if !c.DynamicPorts {
+		requestedPort := c.FrontendPort
+		if requestedPort == 0 {
+			requestedPort = liteconfig.DefaultFrontendPort
+		}
+		port, err := liteconfig.FindAvailablePort(c.FrontendIP, requestedPort, c.PortRetries)
+		if err != nil {
+			return nil, classifyStartError(err)
+		}
+		if port != requestedPort {
+			c.Logger.Info("Requested frontend port is in use, using next available port", tag.Port(port))
+		}
+		c.FrontendPort = port
+	}
+
+	if _, ok := liteconfig.DurabilityLevels[c.Durability]; c.Durability != "" && !ok {
+		return nil, fmt.Errorf("ERROR: unsupported durability %q, one of full, normal, or off allowed", c.Durability)
+	}
+
+	if _, ok := liteconfig.ResourceProfiles[c.ResourceProfile]; c.ResourceProfile != "" && !ok {
+		return nil, fmt.Errorf("ERROR: unsupported resource profile %q, one of small, medium, or large allowed", c.ResourceProfile)
+	}
+	if _, ok := liteconfig.ConcurrencyProfiles[c.ConcurrencyProfile]; c.ConcurrencyProfile != "" && !ok {
+		return nil, fmt.Errorf("ERROR: unsupported concurrency profile %q, one of default, high, or max allowed", c.ConcurrencyProfile)
+	}
+
+	switch c.MetricsExporter {
+	case "", "prometheus":
+	case "statsd", "m3":
+		if c.MetricsEndpoint == "" {
+			return nil, fmt.Errorf("ERROR: the %s metrics exporter requires an endpoint", c.MetricsExporter)
+		}
+	default:
+		return nil, fmt.Errorf("ERROR: unsupported metrics exporter %q, %v allowed", c.MetricsExporter, liteconfig.MetricsExporters)
+	}
+
+	for _, uri := range []string{c.HistoryArchivalURI, c.VisibilityArchivalURI} {
+		if uri == "" {
+			continue
+		}
+		if err := liteconfig.ValidateArchivalURI(uri); err != nil {
+			return nil, fmt.Errorf("ERROR: %w", err)
+		}
+	}
+
+	if c.NamespaceRetention != 0 && c.NamespaceRetention < time.Second {
+		return nil, fmt.Errorf("ERROR: namespace retention must be at least 1s, got %s", c.NamespaceRetention)
+	}
+
+	if c.TimeScale != 0 && (math.IsNaN(c.TimeScale) || math.IsInf(c.TimeScale, 0) || c.TimeScale < 1) {
+		return nil, fmt.Errorf("ERROR: time scale must be a finite number of at least 1, got %v", c.TimeScale)
+	}
+
+	// setupHooks release what setup acquired, such as the seed database, if a
+	// later step fails. Once the server is created they become its stop hooks.
+	var setupHooks []func()
+	release := func() {
+		for _, hook := range setupHooks {
+			hook()
+		}
+	}
+	created := false
+	defer func() {
+		if !created {
+			release()
+		}
+	}()
+
+	if c.SeedDatabase != "" {
+		if c.DataStoreFactory != nil {
+			return nil, errors.New("ERROR: a seed database is not supported with a custom data store")
+		}
+		dir, err := os.MkdirTemp("", "temporalite-seed-")
+		if err != nil {
+			return nil, err
+		}
+		setupHooks = append(setupHooks, func() { _ = os.RemoveAll(dir) })
+		c.Ephemeral = false
+		c.DatabaseFilePath = filepath.Join(dir, "seed.db")
+		c.Logger.Info("Downloading seed database", tag.NewStringTag("source", c.SeedDatabase))
+		if err := replication.Download(ctx, c.SeedDatabase, c.DatabaseFilePath); err != nil {
+			return nil, fmt.Errorf("unable to download seed database: %w", err)
+		}
+	}
+
+	if c.ReadOnly {
+		if c.Ephemeral {
+			return nil, errors.New("ERROR: read-only mode is not supported for ephemeral servers")
+		}
+		if len(c.Namespaces) > 0 {
+			return nil, errors.New("ERROR: namespaces cannot be created in read-only mode")
+		}
+		if len(c.SearchAttributes) > 0 {
+			return nil, errors.New("ERROR: search attributes cannot be registered in read-only mode")
+		}
+		if _, err := os.Stat(c.DatabaseFilePath); err != nil {
+			return nil, fmt.Errorf("ERROR: read-only mode requires an existing database: %w", err)
+		}
+		if c.RetentionReport {
+			return nil, errors.New("ERROR: nothing is deleted in read-only mode, so retention cannot be reported")
+		}
+	}
+	if c.ReplicateTo != "" && c.Ephemeral {
+		return nil, errors.New("ERROR: replication is not supported for ephemeral servers")
+	}
+	if c.DataStoreFactory != nil {
+		if c.ReadOnly || c.ReplicateTo != "" {
+			return nil, errors.New("ERROR: read-only mode and replication are not supported with a custom data store")
+		}
+		if len(c.SearchAttributes) > 0 {
+			return nil, errors.New("ERROR: search attributes cannot be registered at startup with a custom data store")
+		}
+	}
+
+	if c.ReadOnly {
+		// Upstream writes to its database at startup, such as to register system
+		// namespaces and record shard ownership, so it is given a snapshot and the
+		// file itself is only opened read-only.
+		dir, err := os.MkdirTemp("", "temporalite-readonly-")
+		if err != nil {
+			return nil, err
+		}
+		setupHooks = append(setupHooks, func() { _ = os.RemoveAll(dir) })
+		snapshot := filepath.Join(dir, filepath.Base(c.DatabaseFilePath))
+		if err := sqlitedb.Snapshot(ctx, c.DatabaseFilePath, snapshot); err != nil {
+			return nil, fmt.Errorf("unable to snapshot database: %w", classifyDatabaseError(err))
+		}
+		c.DatabaseFilePath = snapshot
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.SQLiteUnlockNotify = sqliteUnlockNotify
 	cfg := liteconfig.Convert(c)
 	sqlConfig := cfg.Persistence.DataStores[liteconfig.PersistenceStoreName].SQL
 
 	// Apply migrations if file does not already exist
 	if c.Ephemeral {
+		if !sqliteUnlockNotify {
+			c.Logger.Debug("Built without the sqlite_unlock_notify tag; concurrent writes to the in-memory database fail and are retried")
+		}
 		if err := sqlite.SetupSchema(sqlConfig); err != nil {
-			return nil, fmt.Errorf("error setting up schema: %w", err)
+			return nil, fmt.Errorf("error setting up schema: %w", classifyDatabaseError(err))
 		}
 	} else if _, err := os.Stat(c.DatabaseFilePath); os.IsNotExist(err) {
 		if err := sqlite.SetupSchema(sqlConfig); err != nil {
-			return nil, fmt.Errorf("error setting up schema: %w", err)
+			return nil, fmt.Errorf("error setting up schema: %w", classifyDatabaseError(err))
+		}
+	} else {
+		missing, err := sqlitedb.MissingTables(ctx, c.DatabaseFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading schema: %w", classifyDatabaseError(err))
+		}
+		if len(missing) > 0 {
+			return nil, &serverError{
+				kind: ErrSchemaMismatch,
+				err:  fmt.Errorf("ERROR: %s is not a temporalite database: missing tables %s", c.DatabaseFilePath, strings.Join(missing, ", ")),
+			}
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Seed before namespaces are created so their IDs are reproducible too.
+	if c.IDSeed != nil {
+		restoreIDs, err := seedIDs(*c.IDSeed)
+		if err != nil {
+			return nil, fmt.Errorf("ERROR: %w", err)
 		}
+		setupHooks = append(setupHooks, restoreIDs)
 	}
 
-	// Pre-create namespaces
-	var namespaces []*sqlite.NamespaceConfig
-	for _, ns := range c.Namespaces {
-		namespaces = append(namespaces, sqlite.NewNamespaceConfig(cfg.ClusterMetadata.CurrentClusterName, ns, false))
+	// Pre-create namespaces. A custom data store is only reachable through the
+	// server, so its namespaces are registered once it has started.
+	if c.DataStoreFactory == nil {
+		if err := precreateNamespaces(ctx, sqlConfig, cfg.ClusterMetadata.CurrentClusterName, c); err != nil {
+			return nil, fmt.Errorf("error creating namespaces: %w", classifyDatabaseError(err))
+		}
 	}
-	if err := sqlite.CreateNamespaces(sqlConfig, namespaces...); err != nil {
-		return nil, fmt.Errorf("error creating namespaces: %w", err)
+
+	// Search attributes are registered once the server is running, so check for
+	// conflicting types up front rather than failing after startup.
+	var searchAttributes map[string]enumspb.IndexedValueType
+	if len(c.SearchAttributes) > 0 {
+		registered, err := registeredSearchAttributes(ctx, sqlConfig, cfg.ClusterMetadata.CurrentClusterName)
+		if err != nil {
+			return nil, fmt.Errorf("error reading search attributes: %w", classifyDatabaseError(err))
+		}
+		if searchAttributes, err = missingSearchAttributes(registered, c.SearchAttributes); err != nil {
+			return nil, fmt.Errorf("ERROR: %w", err)
+		}
+		for name, typ := range c.SearchAttributes {
+			if _, ok := searchAttributes[name]; !ok {
+				c.Logger.Info("Search attribute already registered, skipping", tag.NewStringTag("name", name), tag.NewStringTag("type", typ.String()))
+			}
+		}
 	}
 
+	serverLogger := c.ServerLogger
+	if serverLogger == nil {
+		serverLogger = c.Logger
+	}
 	authorizer, err := authorization.GetAuthorizerFromConfig(&cfg.Global.Authorization)
 	if err != nil {
 		return nil, fmt.Errorf("unable to instantiate authorizer: %w", err)
 	}
 
-	claimMapper, err := authorization.GetClaimMapperFromConfig(&cfg.Global.Authorization, c.Logger)
+	claimMapper, err := authorization.GetClaimMapperFromConfig(&cfg.Global.Authorization, serverLogger)
 	if err != nil {
 		return nil, fmt.Errorf("unable to instantiate claim mapper: %w", err)
 	}
 
+	var clientTLS *tls.Config
+	if c.TLSCertFile != "" {
+		if clientTLS, err = newClientTLSConfig(c.TLSCertFile, c.TLSKeyFile, c.TLSClientCAFile); err != nil {
+			return nil, fmt.Errorf("unable to load TLS configuration: %w", err)
+		}
+	}
+
+	if c.Authorizer != nil {
+		authorizer = c.Authorizer
+	}
+	if c.ClaimMapper != nil {
+		claimMapper = c.ClaimMapper
+	}
+	customAuth := c.Authorizer != nil || c.ClaimMapper != nil
+
+	if c.CertClaimsFile != "" {
+		if clientTLS == nil {
+			return nil, errors.New("ERROR: certificate claim mapping requires TLS")
+		}
+		if c.APIKeyFile != "" {
+			return nil, errors.New("ERROR: certificate claim mapping and API keys cannot be combined")
+		}
+		if customAuth {
+			return nil, errors.New("ERROR: certificate claim mapping cannot be combined with a custom authorizer or claim mapper")
+		}
+		rules, err := loadCertificateClaims(c.CertClaimsFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load certificate claim mapping: %w", err)
+		}
+		authorizer = authorization.NewDefaultAuthorizer()
+		claimMapper = &certificateClaimMapper{rules: rules, selfCert: clientTLS.Certificates[0].Certificate[0]}
+	}
+
+	if addr := c.LocalFrontendAddress; addr != "" {
+		if err := checkLoopbackAddress(addr); err != nil {
+			return nil, fmt.Errorf("ERROR: invalid local frontend address: %w", err)
+		}
+		// Forwarded connections present the server certificate, which must not
+		// stand in for the caller's own credentials.
+		if clientTLS != nil && (c.CertClaimsFile != "" || customAuth) {
+			return nil, errors.New("ERROR: the local frontend cannot be combined with certificate claim mapping or a custom authorizer")
+		}
+	}
+
+	var internalAPIKey string
+	var apiKeys *apiKeyAuthorizer
+	if c.APIKeyFile != "" {
+		if customAuth {
+			return nil, errors.New("ERROR: API keys cannot be combined with a custom authorizer or claim mapper")
+		}
+		keys, err := loadAPIKeys(c.APIKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load API keys: %w", err)
+		}
+		if internalAPIKey, err = newInternalAPIKey(); err != nil {
+			return nil, fmt.Errorf("unable to generate internal API key: %w", err)
+		}
+		keys[internalAPIKey] = internalAPIKeyName
+		apiKeys = &apiKeyAuthorizer{}
+		authorizer = apiKeys
+		claimMapper = &apiKeyClaimMapper{keys: keys}
+	}
+
+	s := &Server{
+		ui:               c.UIServer,
+		frontendHostPort: cfg.PublicClient.HostPort,
+		pipe:             bufconn.Listen(frontendPipeSize),
+		config:           c,
+		upstreamConfig:   cfg,
+		errCh:            make(chan error, 16),
+		dynamicConfig:    liteconfig.NewDynamicConfigClient(c),
+		clientTLS:        clientTLS,
+		internalAPIKey:   internalAPIKey,
+		searchAttributes: searchAttributes,
+		routingTracer:    newRoutingTracer(serverLogger),
+		barriers:         newBarrierSet(),
+		logger:           serverLogger,
+	}
+	s.stopHooks = append(s.stopHooks, setupHooks...)
+	s.stopHooks = append(s.stopHooks, func() { _ = s.pipe.Close() })
+	s.backgroundCtx, s.stopBackground = context.WithCancel(context.Background())
+	// From here on, a failed step also releases what was set up for the server,
+	// such as the mirror's connection.
+	release = func() {
+		s.stopBackground()
+		for _, hook := range s.stopHooks {
+			hook()
+		}
+	}
+
+	if c.ReplicateTo != "" {
+		if s.replicator, err = replication.New(c.ReplicateTo, c.DatabaseFilePath, c.ReplicateInterval, serverLogger); err != nil {
+			return nil, err
+		}
+	}
+
+	interceptors := []grpc.UnaryServerInterceptor{s.usage.Intercept, s.startConflicts.Intercept, s.routingTracer.Intercept}
+	if c.IdentityRPS > 0 || len(c.IdentityRPSOverrides) > 0 {
+		interceptors = append(interceptors, (&identityLimiter{rps: c.IdentityRPS, overrides: c.IdentityRPSOverrides}).Intercept)
+	}
+	services := temporal.Services
+	if c.ReadOnly {
+		interceptors = append(interceptors, rejectMutations)
+		services = []string{primitives.FrontendService, primitives.HistoryService, primitives.MatchingService}
+	}
+	if c.RecordDir != "" {
+		recorder, err := recording.NewRecorder(c.RecordDir)
+		if err != nil {
+			return nil, err
+		}
+		c.Logger.Info("Recording frontend requests", tag.NewStringTag("path", recorder.Path()))
+		interceptors = append(interceptors, recorder.Intercept)
+		s.stopHooks = append(s.stopHooks, func() { _ = recorder.Close() })
+	}
+	if c.AuditLogPath != "" {
+		audit, err := newAuditLog(c.AuditLogPath, &s.peers)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open audit log: %w", err)
+		}
+		interceptors = append(interceptors, audit.Intercept)
+		s.stopHooks = append(s.stopHooks, func() { _ = audit.Close() })
+	}
+	if c.MaxMemory > 0 || c.MemoryReportInterval > 0 || c.CacheShrinkThreshold > 0 {
+		s.memoryGuard = &memoryGuard{
+			limit:           c.MaxMemory,
+			shrinkThreshold: c.CacheShrinkThreshold,
+			reportInterval:  c.MemoryReportInterval,
+			logger:          serverLogger,
+			dynamicConfig:   s.dynamicConfig,
+			closeShard:      s.closeHistoryShard,
+		}
+		interceptors = append(interceptors, s.memoryGuard.Intercept)
+	}
+	if c.MaxOpenWorkflows > 0 {
+		interceptors = append(interceptors, (&openWorkflowQuota{max: c.MaxOpenWorkflows, workflowService: s.workflowService}).Intercept)
+	}
+	// Mirror calls only once the guards above have let them through.
+	if c.MirrorAddress != "" {
+		mirror, err := newTrafficMirror(c.MirrorAddress, c.MirrorMethods, c.MirrorForward, c.MirrorTLS, serverLogger)
+		if err != nil {
+			return nil, err
+		}
+		interceptors = append(interceptors, mirror.Intercept)
+		s.stopHooks = append(s.stopHooks, func() { _ = mirror.conn.Close() })
+	}
+	if c.TimeScale > 1 {
+		interceptors = append(interceptors, (&timeScaler{factor: c.TimeScale}).Intercept)
+	}
+	if c.MaxHeartbeatTimeout > 0 {
+		interceptors = append(interceptors, (&heartbeatLimiter{max: c.MaxHeartbeatTimeout}).Intercept)
+	}
+	if (c.NamespaceWait == nil && c.Ephemeral) || (c.NamespaceWait != nil && *c.NamespaceWait) {
+		interceptors = append(interceptors, (&namespaceWaiter{await: s.AwaitNamespace}).Intercept)
+	}
+	if c.CoverageReport != "" {
+		coverage := newCoverageRecorder(c.CoverageReport)
+		interceptors = append(interceptors, coverage.Intercept)
+		s.stopHooks = append(s.stopHooks, func() {
+			if err := coverage.Write(); err != nil {
+				serverLogger.Error("Unable to write coverage report", tag.Error(err))
+			}
+		})
+	}
+	if configuresHistoryLimits(c.DynamicConfig) {
+		interceptors = append(interceptors, (&historyLimitExplainer{dynamicConfig: s.dynamicConfig, logger: serverLogger}).Intercept)
+	}
+	interceptors = append(interceptors, (&visibilityFallback{workflowService: s.workflowService}).Intercept)
+	interceptors = append(interceptors, s.barriers.Intercept, s.taskGate.Intercept)
+	if c.FaultInjection {
+		s.faults = &faultInjector{workflowService: s.workflowService}
+		interceptors = append(interceptors, s.faults.Intercept)
+	}
+	if len(c.LifecycleListeners) > 0 {
+		interceptors = append(interceptors, s.announceNamespaces)
+	}
+	interceptors = append(interceptors, c.FrontendInterceptors...)
+
 	serverOpts := []temporal.ServerOption{
 		temporal.WithConfig(cfg),
-		temporal.ForServices(temporal.Services),
-		temporal.WithLogger(c.Logger),
+		temporal.ForServices(services),
+		temporal.WithLogger(&errChanLogger{Logger: serverLogger, errCh: s.errCh, stop: s.Stop}),
 		temporal.WithAuthorizer(authorizer),
 		temporal.WithClaimMapper(func(cfg *config.Config) authorization.ClaimMapper {
 			return claimMapper
 		}),
-		temporal.WithDynamicConfigClient(dynamicconfig.NewNoopClient()),
+		temporal.WithDynamicConfigClient(s.dynamicConfig),
+		temporal.WithChainedFrontendGrpcInterceptors(interceptors...),
+	}
+
+	if c.DataStoreFactory != nil {
+		serverOpts = append(serverOpts, temporal.WithCustomDataStoreFactory(c.DataStoreFactory))
+	}
+
+	if apiKeys != nil {
+		apiKeys.peers = &s.peers
+		// Upstream's system worker, which runs batch operations among others,
+		// cannot present an API key, so it reaches the frontend through a
+		// listener of its own instead.
+		workerServerTLS, workerClientTLS, err := newSystemWorkerTLS()
+		if err != nil {
+			return nil, fmt.Errorf("unable to generate system worker certificate: %w", err)
+		}
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, fmt.Errorf("unable to listen for the system worker: %w", err)
+		}
+		s.stopHooks = append(s.stopHooks, func() { _ = l.Close() })
+		s.systemWorkerListener = tls.NewListener(systemWorkerListener{l}, workerServerTLS)
+		cfg.PublicClient.HostPort = l.Addr().String()
+		tlsProvider, err := encryption.NewTLSConfigProviderFromConfig(cfg.Global.TLS, metrics.NoopScope(metrics.Server), serverLogger, nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load TLS configuration: %w", err)
+		}
+		serverOpts = append(serverOpts, temporal.WithTLSConfigFactory(systemWorkerTLSProvider{TLSConfigProvider: tlsProvider, client: workerClientTLS}))
+	}
+
+	// Build upstream's metrics reporter here, just as upstream would, so that
+	// the database size gauge is reported with its metrics.
+	if m := cfg.Global.Metrics; m != nil && len(c.DatabaseSizeWarnings) > 0 && !c.Ephemeral && c.DataStoreFactory == nil {
+		reporter, _, err := m.InitMetricReporters(serverLogger, nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to initialize metrics: %w", err)
+		}
+		if s.metrics, err = reporter.NewClient(serverLogger, metrics.Server); err != nil {
+			reporter.Stop(serverLogger)
+			return nil, fmt.Errorf("unable to initialize metrics: %w", err)
+		}
+		serverOpts = append(serverOpts, temporal.WithCustomMetricsReporter(reporter))
 	}
 
 	if len(c.UpstreamOptions) > 0 {
 		serverOpts = append(serverOpts, c.UpstreamOptions...)
 	}
+	for _, modify := range c.UpstreamModifiers {
+		serverOpts = modify(serverOpts)
+	}
 
-	s := &Server{
-		internal:         temporal.NewServer(serverOpts...),
-		ui:               c.UIServer,
-		frontendHostPort: cfg.PublicClient.HostPort,
-		config:           c,
+	if err := guardFatal(func() error {
+		s.internal = temporal.NewServer(serverOpts...)
+		return nil
+	}); err != nil {
+		return nil, err
 	}
 
+	created = true
 	return s, nil
 }
 
 // Start temporal server.
 func (s *Server) Start() error {
+	if addr := s.config.LocalFrontendAddress; addr != "" {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return classifyStartError(fmt.Errorf("unable to listen for local frontend connections: %w", err))
+		}
+		s.stopHooks = append(s.stopHooks, func() { _ = l.Close() })
+		go s.serveLocalFrontend(s.backgroundCtx, l)
+	}
 	go func() {
 		if err := s.ui.Start(); err != nil {
-			panic(err)
+			reportErr(s.errCh, fmt.Errorf("ui server error: %w", err))
 		}
 	}()
-	return s.internal.Start()
+	if len(s.config.CompletionListeners) > 0 {
+		go s.watchCompletions(s.backgroundCtx, s.config.CompletionListeners)
+	}
+	if s.replicator != nil {
+		go s.replicator.Run(s.backgroundCtx)
+	}
+	if s.config.DataStoreFactory != nil && len(s.config.Namespaces) > 0 {
+		go s.registerNamespaces(s.backgroundCtx, s.config.Namespaces)
+	}
+	if len(s.searchAttributes) > 0 {
+		go s.registerSearchAttributes(s.backgroundCtx, s.searchAttributes)
+	}
+	go s.serveFrontendListener(s.backgroundCtx, s.pipe)
+	if s.systemWorkerListener != nil {
+		go s.serveLocalFrontend(s.backgroundCtx, s.systemWorkerListener)
+	}
+	if l := s.config.FrontendListener; l != nil {
+		s.stopHooks = append(s.stopHooks, func() { _ = l.Close() })
+		go s.serveFrontendListener(s.backgroundCtx, l)
+	}
+	if s.config.ParentPID > 0 {
+		go s.watchParent(s.backgroundCtx, s.config.ParentPID)
+	}
+	if s.config.StuckTaskAttempts > 0 || s.config.StuckTaskTimeout > 0 {
+		go s.watchStuckWorkflows(s.backgroundCtx, s.config.StuckTaskAttempts, s.config.StuckTaskTimeout)
+	}
+	if s.memoryGuard != nil {
+		go s.memoryGuard.Run(s.backgroundCtx)
+	}
+	if s.config.RetentionReport {
+		if !s.config.Ephemeral && s.config.DataStoreFactory == nil {
+			// Read closed runs before history starts, as it deletes runs whose
+			// retention period passed while the server was stopped right away.
+			if runs, err := sqlitedb.ClosedRuns(s.backgroundCtx, s.config.DatabaseFilePath); err == nil {
+				s.retention.seed = runs
+			}
+		}
+		go s.watchRetention(s.backgroundCtx)
+	}
+	if len(s.config.DatabaseSizeWarnings) > 0 && !s.config.Ephemeral && s.config.DataStoreFactory == nil {
+		go s.watchDatabaseSize(s.backgroundCtx, s.config.DatabaseSizeWarnings)
+	}
+	if s.config.CheckpointInterval > 0 && !s.config.Ephemeral && !s.config.ReadOnly {
+		go sqlitedb.RunCheckpoints(s.backgroundCtx, s.config.DatabaseFilePath, s.config.CheckpointInterval, s.logger)
+	}
+	trackRunningServer(s, true)
+	if len(s.config.LifecycleListeners) > 0 {
+		go s.announceStart(s.backgroundCtx)
+	}
+	return classifyStartError(guardFatal(s.internal.Start))
 }
 
-// Stop the server.
+// Stop the server. It is safe to call Stop more than once, for example after
+// the parent watchdog has stopped the server.
 func (s *Server) Stop() {
-	s.ui.Stop()
-	s.internal.Stop()
+	s.stopOnce.Do(func() {
+		notifyLifecycle(s.config, LifecycleEvent{Type: LifecycleShuttingDown})
+		s.stopBackground()
+		if s.memoryGuard != nil {
+			s.memoryGuard.stop()
+		}
+		s.barriers.stop()
+		s.taskGate.resume()
+		trackRunningServer(s, false)
+		s.ui.Stop()
+		_ = guardFatal(func() error {
+			s.internal.Stop()
+			return nil
+		})
+		for _, hook := range s.stopHooks {
+			hook()
+		}
+		s.connMu.Lock()
+		if s.conn != nil {
+			_ = s.conn.Close()
+		}
+		s.connMu.Unlock()
+		notifyLifecycle(s.config, LifecycleEvent{Type: LifecycleStopped})
+	})
+}
+
+// Err returns a channel that receives fatal errors encountered asynchronously
+// by the web UI or any internal Temporal service after Start. A fatal error of
+// an internal service also stops the server. NewServer checks the service
+// ports, but a service failing to bind a port taken since then reports an error
+// matching ErrPortInUse here.
+//
+// Errors are dropped if the channel's buffer is full, so callers interested in
+// them should consume the channel for the lifetime of the server.
+func (s *Server) Err() <-chan error {
+	return s.errCh
 }
 
 // NewClient initializes a client ready to communicate with the Temporal
@@ -139,10 +719,135 @@ func (s *Server) NewClientWithOptions(ctx context.Context, options client.Option
 	options.ConnectionOptions = client.ConnectionOptions{
 		DisableHealthCheck: false,
 		HealthCheckTimeout: timeoutFromContext(ctx, time.Minute),
+		TLS:                s.clientTLS,
+	}
+	if s.internalAPIKey != "" {
+		options.HeadersProvider = internalCredentials{key: s.internalAPIKey, next: options.HeadersProvider}
 	}
 	return client.NewClient(options)
 }
 
+// AwaitNamespace blocks until the namespace accepts workflow requests or ctx is done.
+//
+// Start returns before internal services finish initializing, so requests made
+// immediately afterwards may fail. Call this after Start for namespaces that
+// should be usable right away.
+func (s *Server) AwaitNamespace(ctx context.Context, namespace string) error {
+	c, err := s.NewClientWithOptions(ctx, client.Options{Namespace: namespace, Logger: log.NewSdkLogger(s.logger)})
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if namespaceReady(ctx, c, namespace) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("namespace %q not ready: %w", namespace, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// namespaceReady reports whether namespace is present in the frontend's
+// namespace cache and the history and matching services are serving requests for it.
+func namespaceReady(ctx context.Context, c client.Client, namespace string) bool {
+	// CountWorkflowExecutions requires advanced visibility, so list instead.
+	if _, err := c.ListOpenWorkflow(ctx, &workflowservice.ListOpenWorkflowExecutionsRequest{Namespace: namespace, MaximumPageSize: 1}); err != nil {
+		return false
+	}
+	// With the namespace known to be registered, NotFound means history looked up
+	// the (nonexistent) workflow.
+	_, err := c.DescribeWorkflowExecution(ctx, "temporalite-readiness-probe", "")
+	var notFound *serviceerror.NotFound
+	if err != nil && !errors.As(err, &notFound) {
+		return false
+	}
+	_, err = c.DescribeTaskQueue(ctx, "temporalite-readiness-probe", enumspb.TASK_QUEUE_TYPE_WORKFLOW)
+	return err == nil
+}
+
+// Dial returns a gRPC connection to the frontend service, blocking until the
+// connection is established or ctx is done. Additional dial options, such as
+// client interceptors, are applied after temporalite's own.
+//
+// The connection is made over an in-memory pipe, so callers bind no ports of
+// their own. The upstream frontend service owns its listener, so the pipe is
+// forwarded to it like a listener passed in with WithFrontendListener.
+//
+// The caller is responsible for closing the returned connection.
+func (s *Server) Dial(ctx context.Context, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	pipeDialer := func(ctx context.Context, _ string) (net.Conn, error) {
+		return s.pipe.DialContext(ctx)
+	}
+	opts = append(append(s.dialOptions(), grpc.WithBlock(), grpc.WithContextDialer(pipeDialer)), opts...)
+	// The frontend's address is still the target, so TLS verifies the same
+	// server name as for any other client.
+	return grpc.DialContext(ctx, s.frontendHostPort, opts...)
+}
+
+// frontendConn returns a connection to the frontend service shared by
+// temporalite's own background tasks and administrative methods.
+func (s *Server) frontendConn() (*grpc.ClientConn, error) {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	if s.conn == nil {
+		conn, err := grpc.Dial(s.frontendHostPort, s.dialOptions()...)
+		if err != nil {
+			return nil, err
+		}
+		s.conn = conn
+	}
+	return s.conn, nil
+}
+
+// workflowService returns a client for the frontend's workflow service API.
+func (s *Server) workflowService() (workflowservice.WorkflowServiceClient, error) {
+	conn, err := s.frontendConn()
+	if err != nil {
+		return nil, err
+	}
+	return workflowservice.NewWorkflowServiceClient(conn), nil
+}
+
+// adminService returns a client for the frontend's admin service API.
+func (s *Server) adminService() (adminservice.AdminServiceClient, error) {
+	conn, err := s.frontendConn()
+	if err != nil {
+		return nil, err
+	}
+	return adminservice.NewAdminServiceClient(conn), nil
+}
+
+// closeHistoryShard unloads the history shard. It is reloaded on the next
+// request for a workflow, with caches of the currently configured size.
+func (s *Server) closeHistoryShard(ctx context.Context) error {
+	admin, err := s.adminService()
+	if err != nil {
+		return err
+	}
+	_, err = admin.CloseShard(ctx, &adminservice.CloseShardRequest{ShardId: 1})
+	return err
+}
+
+// dialOptions returns the dial options securing and authenticating connections
+// to the frontend service.
+func (s *Server) dialOptions() []grpc.DialOption {
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if s.clientTLS != nil {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(s.clientTLS))}
+	}
+	if s.internalAPIKey != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(internalCredentials{key: s.internalAPIKey}))
+	}
+	return opts
+}
+
 // FrontendHostPort returns the host:port for this server.
 //
 // When constructing a Temporalite client from within the same process,
@@ -151,6 +856,20 @@ func (s *Server) FrontendHostPort() string {
 	return s.frontendHostPort
 }
 
+// MetricsHostPort returns the host:port serving Prometheus metrics, or an empty
+// string when metrics are pushed to a statsd or M3 collector.
+func (s *Server) MetricsHostPort() string {
+	if m := s.upstreamConfig.Global.Metrics; m != nil && m.Prometheus != nil {
+		return m.Prometheus.ListenAddress
+	}
+	return ""
+}
+
+// PProfHostPort returns the host:port serving /debug/pprof and /debug/vars.
+func (s *Server) PProfHostPort() string {
+	return fmt.Sprintf("localhost:%d", s.upstreamConfig.Global.PProf.Port)
+}
+
 func timeoutFromContext(ctx context.Context, defaultTimeout time.Duration) time.Duration {
 	if deadline, ok := ctx.Deadline(); ok {
 		return deadline.Sub(time.Now())
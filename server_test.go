@@ -0,0 +1,124 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DataDog/temporalite/internal/liteconfig"
+
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/common"
+	"go.temporal.io/server/common/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+func newTestServer(t *testing.T, opts ...ServerOption) *Server {
+	t.Helper()
+	opts = append([]ServerOption{WithPersistenceDisabled(), WithDynamicPorts(), WithLogger(log.NewNoopLogger())}, opts...)
+	s, err := NewServer(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	// Upstream is slow to stop while its services are still starting.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := s.AwaitNamespace(ctx, common.SystemLocalNamespace); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestStopTwice(t *testing.T) {
+	s := newTestServer(t)
+	s.Stop()
+	// Embedders defer Stop even when the parent watchdog may already have
+	// stopped the server.
+	s.Stop()
+}
+
+func TestDial(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var calls int
+	counter := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		calls++
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	conn, err := s.Dial(ctx, grpc.WithUnaryInterceptor(counter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var p peer.Peer
+	req := &workflowservice.DescribeNamespaceRequest{Namespace: common.SystemLocalNamespace}
+	if _, err := workflowservice.NewWorkflowServiceClient(conn).DescribeNamespace(ctx, req, grpc.Peer(&p)); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("interceptor called %d times, want 1", calls)
+	}
+	if network := p.Addr.Network(); network != "bufconn" {
+		t.Errorf("connected over %q, want the in-memory pipe", network)
+	}
+}
+
+func TestNewServerPortInUse(t *testing.T) {
+	tests := []struct {
+		name string
+		// offset is the offset from the frontend port of the port taken.
+		offset  int
+		retries int
+		wantErr bool
+	}{
+		{name: "frontend port", offset: 0, wantErr: true},
+		{name: "history membership port", offset: 101, wantErr: true},
+		{name: "pprof port", offset: 201, wantErr: true},
+		{name: "with retries", offset: 0, retries: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			port, err := liteconfig.FindAvailablePort("127.0.0.1", 20000+rand.Intn(20000), 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port+tc.offset))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			s, err := NewServer(WithPersistenceDisabled(), WithFrontendIP("127.0.0.1"), WithFrontendPort(port),
+				WithPortRetries(tc.retries), WithLogger(log.NewNoopLogger()))
+			if tc.wantErr {
+				if !errors.Is(err, ErrPortInUse) {
+					t.Errorf("NewServer() = %v, want ErrPortInUse", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Stop()
+			if want := fmt.Sprintf("127.0.0.1:%d", port+1); s.FrontendHostPort() != want {
+				t.Errorf("frontend address = %s, want %s", s.FrontendHostPort(), want)
+			}
+		})
+	}
+}
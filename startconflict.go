@@ -0,0 +1,178 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/api/workflowservice/v1"
+	"google.golang.org/grpc"
+)
+
+// maxStartConflicts bounds the number of rejected starts remembered per server.
+const maxStartConflicts = 1000
+
+// StartConflict explains why starting a workflow ID failed, or would fail,
+// with WorkflowExecutionAlreadyStarted.
+type StartConflict struct {
+	Namespace  string `json:"namespace"`
+	WorkflowID string `json:"workflow_id"`
+	// ExistingRunID and ExistingStatus describe the current run of the workflow ID.
+	ExistingRunID     string    `json:"existing_run_id"`
+	ExistingStatus    string    `json:"existing_status"`
+	ExistingStartTime time.Time `json:"existing_start_time"`
+	// WorkflowIDReusePolicy is the policy of the last rejected start, or the
+	// default policy if no start was rejected since the server started.
+	WorkflowIDReusePolicy string `json:"workflow_id_reuse_policy"`
+	// LastRejected is when a start was last rejected, if it was since the server started.
+	LastRejected *time.Time `json:"last_rejected,omitempty"`
+	// Allowed reports whether a start with the policy would now succeed.
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason"`
+}
+
+type rejectedStart struct {
+	policy enumspb.WorkflowIdReusePolicy
+	at     time.Time
+}
+
+// startConflictLog remembers the most recent start rejected for each workflow ID.
+type startConflictLog struct {
+	mu       sync.Mutex
+	rejected map[string]rejectedStart
+}
+
+func startConflictKey(namespace, workflowID string) string {
+	return namespace + "/" + workflowID
+}
+
+func (l *startConflictLog) Intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if _, ok := err.(*serviceerror.WorkflowExecutionAlreadyStarted); !ok {
+		return resp, err
+	}
+	var key string
+	var policy enumspb.WorkflowIdReusePolicy
+	switch req := req.(type) {
+	case *workflowservice.StartWorkflowExecutionRequest:
+		key, policy = startConflictKey(req.GetNamespace(), req.GetWorkflowId()), req.GetWorkflowIdReusePolicy()
+	case *workflowservice.SignalWithStartWorkflowExecutionRequest:
+		key, policy = startConflictKey(req.GetNamespace(), req.GetWorkflowId()), req.GetWorkflowIdReusePolicy()
+	default:
+		return resp, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rejected == nil {
+		l.rejected = make(map[string]rejectedStart)
+	}
+	if _, ok := l.rejected[key]; !ok && len(l.rejected) >= maxStartConflicts {
+		for k := range l.rejected {
+			delete(l.rejected, k)
+			break
+		}
+	}
+	l.rejected[key] = rejectedStart{policy: policy, at: time.Now()}
+	return resp, err
+}
+
+func (l *startConflictLog) lookup(namespace, workflowID string) (rejectedStart, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r, ok := l.rejected[startConflictKey(namespace, workflowID)]
+	return r, ok
+}
+
+// ExplainStartConflict describes the current run of workflowID and evaluates
+// the workflow ID reuse policy of the last rejected start against it, to explain
+// why starting the workflow fails with WorkflowExecutionAlreadyStarted.
+func (s *Server) ExplainStartConflict(ctx context.Context, namespace, workflowID string) (*StartConflict, error) {
+	svc, err := s.workflowService()
+	if err != nil {
+		return nil, err
+	}
+	conflict := &StartConflict{Namespace: namespace, WorkflowID: workflowID}
+	policy := enumspb.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE
+	if r, ok := s.startConflicts.lookup(namespace, workflowID); ok {
+		if r.policy != enumspb.WORKFLOW_ID_REUSE_POLICY_UNSPECIFIED {
+			policy = r.policy
+		}
+		conflict.LastRejected = &r.at
+	}
+	conflict.WorkflowIDReusePolicy = policy.String()
+
+	resp, err := svc.DescribeWorkflowExecution(ctx, &workflowservice.DescribeWorkflowExecutionRequest{
+		Namespace: namespace,
+		Execution: &commonpb.WorkflowExecution{WorkflowId: workflowID},
+	})
+	if _, ok := err.(*serviceerror.NotFound); ok {
+		conflict.Allowed = true
+		conflict.Reason = "no run with this workflow ID exists, or it was deleted after the namespace's retention period"
+		return conflict, nil
+	} else if err != nil {
+		return nil, err
+	}
+	info := resp.GetWorkflowExecutionInfo()
+	status := info.GetStatus()
+	conflict.ExistingRunID = info.GetExecution().GetRunId()
+	conflict.ExistingStatus = status.String()
+	if info.GetStartTime() != nil {
+		conflict.ExistingStartTime = *info.GetStartTime()
+	}
+
+	switch {
+	case status == enumspb.WORKFLOW_EXECUTION_STATUS_RUNNING:
+		conflict.Reason = fmt.Sprintf("run %s is still running; a workflow ID can only have one open run regardless of the reuse policy, "+
+			"and only a retried start with the same request ID returns the existing run", conflict.ExistingRunID)
+	case policy == enumspb.WORKFLOW_ID_REUSE_POLICY_REJECT_DUPLICATE:
+		conflict.Reason = fmt.Sprintf("run %s closed as %s, but the %s policy never reuses a workflow ID while a previous run is retained",
+			conflict.ExistingRunID, status, policy)
+	case policy == enumspb.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE_FAILED_ONLY && status == enumspb.WORKFLOW_EXECUTION_STATUS_COMPLETED:
+		conflict.Reason = fmt.Sprintf("run %s completed successfully, and the %s policy only reuses the workflow ID after a failed, canceled, terminated, or timed out run",
+			conflict.ExistingRunID, policy)
+	default:
+		conflict.Allowed = true
+		conflict.Reason = fmt.Sprintf("run %s closed as %s, which the %s policy allows starting over", conflict.ExistingRunID, status, policy)
+	}
+	return conflict, nil
+}
+
+// serveStartConflict handles /debug/startconflict?namespace=NS&workflow_id=ID on
+// the pprof port. As with /debug/taskqueue, the frontend parameter selects a
+// server when several run in one process.
+func serveStartConflict(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	workflowID := query.Get("workflow_id")
+	if workflowID == "" {
+		http.Error(w, "workflow_id parameter is required", http.StatusBadRequest)
+		return
+	}
+	namespace := query.Get("namespace")
+	if namespace == "" {
+		namespace = "default"
+	}
+	s, err := debugServer(query.Get("frontend"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conflict, err := s.ExplainStartConflict(r.Context(), namespace, workflowID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(conflict)
+}
@@ -0,0 +1,265 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/api/adminservice/v1"
+	"go.temporal.io/server/common"
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/log/tag"
+	"go.temporal.io/server/common/persistence"
+	"go.temporal.io/server/common/persistence/serialization"
+	persistencesql "go.temporal.io/server/common/persistence/sql"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin"
+	"go.temporal.io/server/common/resolver"
+	"go.temporal.io/server/schema/sqlite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/DataDog/temporalite/internal/liteconfig"
+)
+
+// precreateNamespaces registers each namespace in c.Namespaces that does not
+// already exist, logging those left unchanged.
+//
+// When a retention period is configured, existing namespaces with a different
+// retention are updated if c.ReconcileNamespaces is set and logged otherwise.
+func precreateNamespaces(ctx context.Context, sqlConfig *config.SQL, clusterName string, c *liteconfig.Config) error {
+	if len(c.Namespaces) == 0 {
+		return nil
+	}
+	db, err := persistencesql.NewSQLDB(sqlplugin.DbKindUnknown, sqlConfig, resolver.NewNoopResolver())
+	if err != nil {
+		return fmt.Errorf("unable to open database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var (
+		seen    = make(map[string]bool)
+		missing []*sqlite.NamespaceConfig
+	)
+	for _, name := range c.Namespaces {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		n := name
+		rows, err := db.SelectFromNamespace(ctx, sqlplugin.NamespaceFilter{Name: &n})
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("unable to look up namespace %q: %w", name, err)
+		}
+		if len(rows) > 0 {
+			if err := reconcileNamespace(ctx, db, rows[0], c); err != nil {
+				return fmt.Errorf("unable to reconcile namespace %q: %w", name, err)
+			}
+			continue
+		}
+		ns := sqlite.NewNamespaceConfig(clusterName, name, false)
+		if c.NamespaceRetention > 0 {
+			retention := c.NamespaceRetention
+			ns.Detail.Config.Retention = &retention
+		}
+		if c.HistoryArchivalURI != "" {
+			ns.Detail.Config.HistoryArchivalState = enumspb.ARCHIVAL_STATE_ENABLED
+			ns.Detail.Config.HistoryArchivalUri = c.HistoryArchivalURI
+		}
+		if c.VisibilityArchivalURI != "" {
+			ns.Detail.Config.VisibilityArchivalState = enumspb.ARCHIVAL_STATE_ENABLED
+			ns.Detail.Config.VisibilityArchivalUri = c.VisibilityArchivalURI
+		}
+		missing = append(missing, ns)
+		c.Logger.Info("Registering namespace", tag.WorkflowNamespace(name))
+	}
+	if err := sqlite.CreateNamespaces(sqlConfig, missing...); err != nil {
+		return err
+	}
+	for _, ns := range missing {
+		notifyLifecycle(c, LifecycleEvent{Type: LifecycleNamespaceCreated, Namespace: ns.Detail.Info.Name})
+	}
+	return nil
+}
+
+// reconcileNamespace compares an existing namespace against the configured
+// settings, updating it in place when reconciliation is enabled.
+func reconcileNamespace(ctx context.Context, db sqlplugin.DB, row sqlplugin.NamespaceRow, c *liteconfig.Config) error {
+	serializer := serialization.NewSerializer()
+	detail, err := serializer.NamespaceDetailFromBlob(persistence.NewDataBlob(row.Data, row.DataEncoding))
+	if err != nil {
+		return err
+	}
+	current := detail.GetConfig().GetRetention()
+	if c.NamespaceRetention == 0 || (current != nil && *current == c.NamespaceRetention) {
+		c.Logger.Info("Namespace already registered, skipping", tag.WorkflowNamespace(row.Name))
+		return nil
+	}
+	var currentRetention time.Duration
+	if current != nil {
+		currentRetention = *current
+	}
+	if !c.ReconcileNamespaces {
+		c.Logger.Warn("Namespace already registered with a different retention, keeping it; pass --reconcile-namespaces to update it",
+			tag.WorkflowNamespace(row.Name), tag.NewDurationTag("retention", currentRetention), tag.NewDurationTag("configured-retention", c.NamespaceRetention))
+		return nil
+	}
+
+	retention := c.NamespaceRetention
+	detail.Config.Retention = &retention
+	detail.ConfigVersion++
+	blob, err := serializer.NamespaceDetailToBlob(detail, enumspb.ENCODING_TYPE_PROTO3)
+	if err != nil {
+		return err
+	}
+
+	// Mirror upstream's metadata manager: namespace updates are guarded by, and
+	// bump, the namespace metadata notification version.
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	metadata, err := tx.LockNamespaceMetadata(ctx)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.UpdateNamespace(ctx, &sqlplugin.NamespaceRow{
+		ID:                  row.ID,
+		Name:                row.Name,
+		Data:                blob.GetData(),
+		DataEncoding:        blob.GetEncodingType().String(),
+		IsGlobal:            row.IsGlobal,
+		NotificationVersion: metadata.NotificationVersion,
+	}); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.UpdateNamespaceMetadata(ctx, metadata); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	c.Logger.Info("Updated namespace retention", tag.WorkflowNamespace(row.Name),
+		tag.NewDurationTag("previous-retention", currentRetention), tag.NewDurationTag("retention", c.NamespaceRetention))
+	return nil
+}
+
+// registerNamespaces registers namespaces through the frontend, skipping those
+// that already exist.
+func (s *Server) registerNamespaces(ctx context.Context, names []string) {
+	if err := s.AwaitNamespace(ctx, common.SystemLocalNamespace); err != nil {
+		reportErr(s.errCh, fmt.Errorf("unable to register namespaces: %w", err))
+		return
+	}
+	svc, err := s.workflowService()
+	if err != nil {
+		reportErr(s.errCh, fmt.Errorf("unable to register namespaces: %w", err))
+		return
+	}
+	retention := 24 * time.Hour
+	if s.config.NamespaceRetention > 0 {
+		retention = s.config.NamespaceRetention
+	}
+	// Namespaces are awaited by AwaitNamespace, rather than one refresh at a
+	// time by each registration.
+	registerCtx := metadata.AppendToOutgoingContext(ctx, skipNamespaceWaitHeader, "true")
+	for _, name := range names {
+		_, err := svc.RegisterNamespace(registerCtx, &workflowservice.RegisterNamespaceRequest{
+			Namespace:                        name,
+			WorkflowExecutionRetentionPeriod: &retention,
+		})
+		switch {
+		case status.Code(err) == codes.AlreadyExists:
+			s.config.Logger.Info("Namespace already registered, skipping", tag.WorkflowNamespace(name))
+		case err != nil:
+			reportErr(s.errCh, fmt.Errorf("unable to register namespace %q: %w", name, err))
+			return
+		default:
+			s.config.Logger.Info("Registered namespace", tag.WorkflowNamespace(name))
+		}
+	}
+}
+
+// registeredSearchAttributes returns the custom search attributes stored in the
+// cluster metadata, which is absent until the server first starts.
+func registeredSearchAttributes(ctx context.Context, sqlConfig *config.SQL, clusterName string) (map[string]enumspb.IndexedValueType, error) {
+	db, err := persistencesql.NewSQLDB(sqlplugin.DbKindUnknown, sqlConfig, resolver.NewNoopResolver())
+	if err != nil {
+		return nil, fmt.Errorf("unable to open database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	row, err := db.GetClusterMetadata(ctx, &sqlplugin.ClusterMetadataFilter{ClusterName: clusterName})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read cluster metadata: %w", err)
+	}
+	metadata, err := serialization.NewSerializer().DeserializeClusterMetadata(persistence.NewDataBlob(row.Data, row.DataEncoding))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode cluster metadata: %w", err)
+	}
+	// Standard visibility stores search attributes under the empty index name.
+	return metadata.GetIndexSearchAttributes()[""].GetCustomSearchAttributes(), nil
+}
+
+// missingSearchAttributes returns the desired search attributes that are not yet
+// registered, or an error if any is registered with a different type.
+func missingSearchAttributes(registered, desired map[string]enumspb.IndexedValueType) (map[string]enumspb.IndexedValueType, error) {
+	names := make([]string, 0, len(desired))
+	for name := range desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	missing := make(map[string]enumspb.IndexedValueType)
+	for _, name := range names {
+		existing, ok := registered[name]
+		if !ok {
+			missing[name] = desired[name]
+			continue
+		}
+		if existing != desired[name] {
+			return nil, fmt.Errorf("search attribute %q is already registered as %s, not %s; remove it with `temporalite search-attributes remove %s` before changing its type",
+				name, existing, desired[name], name)
+		}
+	}
+	return missing, nil
+}
+
+// registerSearchAttributes adds search attributes once the system worker,
+// which carries out the registration, is running.
+func (s *Server) registerSearchAttributes(ctx context.Context, attrs map[string]enumspb.IndexedValueType) {
+	if err := s.AwaitNamespace(ctx, common.SystemLocalNamespace); err != nil {
+		reportErr(s.errCh, fmt.Errorf("unable to register search attributes: %w", err))
+		return
+	}
+	admin, err := s.adminService()
+	if err != nil {
+		reportErr(s.errCh, fmt.Errorf("unable to register search attributes: %w", err))
+		return
+	}
+	if _, err := admin.AddSearchAttributes(ctx, &adminservice.AddSearchAttributesRequest{SearchAttributes: attrs}); err != nil {
+		reportErr(s.errCh, fmt.Errorf("unable to register search attributes: %w", err))
+		return
+	}
+	for name, typ := range attrs {
+		s.config.Logger.Info("Registered search attribute", tag.NewStringTag("name", name), tag.NewStringTag("type", typ.String()))
+	}
+}
@@ -0,0 +1,201 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/api/adminservice/v1"
+	"go.temporal.io/server/common"
+	"go.temporal.io/server/common/log/tag"
+)
+
+const stuckCheckInterval = 10 * time.Second
+
+// workflowTaskProgress is the state of the most recent workflow task of a run.
+type workflowTaskProgress struct {
+	// pending is true from when a workflow task is scheduled until one completes.
+	pending     bool
+	attempt     int32
+	since       time.Time
+	lastFailure string
+}
+
+// stuckReason explains why a workflow task is considered stuck, or returns an
+// empty string when it is making progress.
+func (p workflowTaskProgress) stuckReason(maxAttempts int32, timeout time.Duration, now time.Time) string {
+	if !p.pending {
+		return ""
+	}
+	if maxAttempts > 0 && p.attempt > maxAttempts {
+		return fmt.Sprintf("workflow task attempted %d times", p.attempt)
+	}
+	if timeout > 0 && now.Sub(p.since) > timeout {
+		return fmt.Sprintf("workflow task pending for %s", now.Sub(p.since).Round(time.Second))
+	}
+	return ""
+}
+
+// watchStuckWorkflows periodically inspects open workflows in every user namespace
+// and logs a warning once for each run whose workflow task is failing repeatedly
+// or has not completed within timeout.
+func (s *Server) watchStuckWorkflows(ctx context.Context, maxAttempts int32, timeout time.Duration) {
+	ticker := time.NewTicker(stuckCheckInterval)
+	defer ticker.Stop()
+
+	// warned holds runs already reported, so each stuck episode is logged once.
+	warned := make(map[string]bool)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		svc, err := s.workflowService()
+		if err != nil {
+			continue
+		}
+		admin, err := s.adminService()
+		if err != nil {
+			continue
+		}
+		namespaces, err := listNamespaces(ctx, svc)
+		if err != nil {
+			continue
+		}
+		open := make(map[string]bool)
+		for _, ns := range namespaces {
+			executions, err := listOpenExecutions(ctx, svc, ns)
+			if err != nil {
+				continue
+			}
+			for _, execution := range executions {
+				open[execution.GetRunId()] = true
+				progress, err := lastWorkflowTask(ctx, svc, ns, execution)
+				if err != nil {
+					continue
+				}
+				if progress.pending {
+					if progress.attempt, progress.pending, err = pendingWorkflowTaskAttempt(ctx, admin, ns, execution); err != nil {
+						continue
+					}
+				}
+				reason := progress.stuckReason(maxAttempts, timeout, time.Now())
+				if reason == "" {
+					delete(warned, execution.GetRunId())
+					continue
+				}
+				if warned[execution.GetRunId()] {
+					continue
+				}
+				warned[execution.GetRunId()] = true
+				s.logger.Warn("Workflow appears stuck: "+reason,
+					tag.WorkflowNamespace(ns),
+					tag.WorkflowID(execution.GetWorkflowId()),
+					tag.WorkflowRunID(execution.GetRunId()),
+					tag.NewStringTag("last-failure", progress.lastFailure),
+				)
+			}
+		}
+		for runID := range warned {
+			if !open[runID] {
+				delete(warned, runID)
+			}
+		}
+	}
+}
+
+func listOpenExecutions(ctx context.Context, svc workflowservice.WorkflowServiceClient, namespace string) ([]*commonpb.WorkflowExecution, error) {
+	var (
+		executions []*commonpb.WorkflowExecution
+		token      []byte
+	)
+	for {
+		resp, err := svc.ListOpenWorkflowExecutions(ctx, &workflowservice.ListOpenWorkflowExecutionsRequest{
+			Namespace:       namespace,
+			MaximumPageSize: 1000,
+			NextPageToken:   token,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range resp.GetExecutions() {
+			executions = append(executions, info.GetExecution())
+		}
+		if token = resp.GetNextPageToken(); len(token) == 0 {
+			return executions, nil
+		}
+	}
+}
+
+// lastWorkflowTask reads a run's history to find the state of its latest workflow task.
+//
+// Retried workflow tasks are transient: only the first attempt and failure are
+// written to history, so the attempt returned is that of the last task
+// recorded there. See pendingWorkflowTaskAttempt.
+func lastWorkflowTask(ctx context.Context, svc workflowservice.WorkflowServiceClient, namespace string, execution *commonpb.WorkflowExecution) (workflowTaskProgress, error) {
+	var (
+		progress workflowTaskProgress
+		token    []byte
+	)
+	for {
+		resp, err := svc.GetWorkflowExecutionHistory(ctx, &workflowservice.GetWorkflowExecutionHistoryRequest{
+			Namespace:     namespace,
+			Execution:     execution,
+			NextPageToken: token,
+		})
+		if err != nil {
+			return progress, err
+		}
+		for _, e := range resp.GetHistory().GetEvents() {
+			switch e.GetEventType() {
+			case enumspb.EVENT_TYPE_WORKFLOW_TASK_SCHEDULED:
+				// Keep the time of the first attempt so retries don't count as progress.
+				if !progress.pending {
+					progress.pending, progress.since = true, timeValue(e.GetEventTime())
+				}
+				progress.attempt = e.GetWorkflowTaskScheduledEventAttributes().GetAttempt()
+			case enumspb.EVENT_TYPE_WORKFLOW_TASK_COMPLETED:
+				progress = workflowTaskProgress{}
+			case enumspb.EVENT_TYPE_WORKFLOW_TASK_FAILED:
+				attrs := e.GetWorkflowTaskFailedEventAttributes()
+				progress.lastFailure = fmt.Sprintf("%s: %s", attrs.GetCause(), attrs.GetFailure().GetMessage())
+			case enumspb.EVENT_TYPE_WORKFLOW_TASK_TIMED_OUT:
+				progress.lastFailure = fmt.Sprintf("timed out (%s)", e.GetWorkflowTaskTimedOutEventAttributes().GetTimeoutType())
+			}
+		}
+		if token = resp.GetNextPageToken(); len(token) == 0 {
+			return progress, nil
+		}
+	}
+}
+
+// pendingWorkflowTaskAttempt reads a run's mutable state to find the attempt of
+// its pending workflow task, counting the transient retries that history omits.
+// pending is false if the run has no workflow task outstanding.
+func pendingWorkflowTaskAttempt(ctx context.Context, admin adminservice.AdminServiceClient, namespace string, execution *commonpb.WorkflowExecution) (attempt int32, pending bool, err error) {
+	resp, err := admin.DescribeMutableState(ctx, &adminservice.DescribeMutableStateRequest{
+		Namespace: namespace,
+		Execution: execution,
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	state := resp.GetCacheMutableState()
+	if state == nil {
+		state = resp.GetDatabaseMutableState()
+	}
+	info := state.GetExecutionInfo()
+	if info.GetWorkflowTaskScheduleId() == common.EmptyEventID {
+		return 0, false, nil
+	}
+	return info.GetWorkflowTaskAttempt(), true, nil
+}
@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+	"go.temporal.io/server/common/log"
+)
+
+func panickingWorkflow(ctx workflow.Context) error {
+	panic("always fails")
+}
+
+func TestPendingWorkflowTaskAttempt(t *testing.T) {
+	s := newTestServer(t, WithNamespaces("default"))
+	defer s.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := s.AwaitNamespace(ctx, "default"); err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.NewClientWithOptions(ctx, client.Options{Namespace: "default", Logger: log.NewSdkLogger(log.NewNoopLogger())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	w := worker.New(c, "stuck", worker.Options{})
+	w.RegisterWorkflow(panickingWorkflow)
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	run, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{TaskQueue: "stuck"}, panickingWorkflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, err := s.workflowService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := s.adminService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	execution := &commonpb.WorkflowExecution{WorkflowId: run.GetID(), RunId: run.GetRunID()}
+
+	// Retries of the failing task are transient, so history alone never
+	// shows more than the first attempt.
+	for {
+		attempt, pending, err := pendingWorkflowTaskAttempt(ctx, admin, "default", execution)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pending && attempt >= 3 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("attempt %d after %s, want at least 3", attempt, time.Minute)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	progress, err := lastWorkflowTask(ctx, svc, "default", execution)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !progress.pending || progress.lastFailure == "" {
+		t.Errorf("history shows %+v, want a pending task with a failure", progress)
+	}
+}
@@ -0,0 +1,102 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/common"
+
+	"github.com/DataDog/temporalite/internal/sqlitedb"
+)
+
+// WorkflowSummary counts the workflow runs of one namespace.
+type WorkflowSummary struct {
+	Namespace string `json:"namespace"`
+	Open      int64  `json:"open"`
+	Closed    int64  `json:"closed"`
+	// ClosedByStatus counts closed runs by status, eg. "Completed" or "Failed".
+	ClosedByStatus map[string]int64 `json:"closed_by_status"`
+}
+
+// WorkflowSummary counts the open and closed workflow runs of every namespace,
+// sorted by name, without listing them. Runs are counted from visibility
+// records, which are written shortly after runs start and close, and closed
+// runs are only counted until their namespace's retention period passes.
+//
+// Only servers storing their state in a database file support this.
+func (s *Server) WorkflowSummary(ctx context.Context) ([]WorkflowSummary, error) {
+	if s.config.Ephemeral || s.config.DataStoreFactory != nil {
+		return nil, fmt.Errorf("summarizing workflows requires a database file")
+	}
+	svc, err := s.workflowService()
+	if err != nil {
+		return nil, err
+	}
+
+	// Visibility records namespaces by ID, so list them to find their names.
+	summaries := make(map[string]*WorkflowSummary)
+	var token []byte
+	for {
+		resp, err := svc.ListNamespaces(ctx, &workflowservice.ListNamespacesRequest{PageSize: 100, NextPageToken: token})
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range resp.GetNamespaces() {
+			if info := ns.GetNamespaceInfo(); info.GetName() != common.SystemLocalNamespace {
+				summaries[info.GetId()] = &WorkflowSummary{Namespace: info.GetName(), ClosedByStatus: make(map[string]int64)}
+			}
+		}
+		if token = resp.GetNextPageToken(); len(token) == 0 {
+			break
+		}
+	}
+
+	counts, err := sqlitedb.CountRuns(ctx, s.config.DatabaseFilePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, count := range counts {
+		summary, ok := summaries[count.NamespaceID]
+		if !ok {
+			continue
+		}
+		status := enumspb.WorkflowExecutionStatus(count.Status)
+		if status == enumspb.WORKFLOW_EXECUTION_STATUS_RUNNING {
+			summary.Open += count.Count
+			continue
+		}
+		summary.Closed += count.Count
+		summary.ClosedByStatus[status.String()] += count.Count
+	}
+
+	result := make([]WorkflowSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		result = append(result, *summary)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Namespace < result[j].Namespace })
+	return result, nil
+}
+
+func serveSummary(w http.ResponseWriter, r *http.Request) {
+	s, err := debugServer(r.URL.Query().Get("frontend"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	summary, err := s.WorkflowSummary(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(summary)
+}
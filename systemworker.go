@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"time"
+
+	"go.temporal.io/server/common/rpc/encryption"
+)
+
+// systemWorkerServerName is the name in the certificate securing the system
+// worker's connections to the frontend.
+const systemWorkerServerName = "temporalite-system-worker"
+
+// newSystemWorkerTLS returns the TLS configurations of both ends of the
+// connection upstream's system worker makes to the frontend when API keys are
+// required.
+//
+// Upstream's system worker creates its SDK clients without credentials, so it
+// cannot present an API key. Instead, it reaches the frontend through a
+// listener that only accepts clients holding a certificate generated for this
+// server, whose key never leaves the process.
+func newSystemWorkerTLS() (server, client *tls.Config, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: systemWorkerServerName},
+		DNSNames:              []string{systemWorkerServerName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+
+	server = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		NextProtos:   []string{"h2"},
+		MinVersion:   tls.VersionTLS12,
+	}
+	client = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ServerName:   systemWorkerServerName,
+		MinVersion:   tls.VersionTLS12,
+	}
+	return server, client, nil
+}
+
+// systemWorkerTLSProvider hands upstream's system worker the client side of
+// newSystemWorkerTLS, leaving every other TLS configuration to next.
+type systemWorkerTLSProvider struct {
+	encryption.TLSConfigProvider
+	client *tls.Config
+}
+
+func (p systemWorkerTLSProvider) GetFrontendClientConfig() (*tls.Config, error) {
+	return p.client, nil
+}
+
+// systemWorkerListener marks the remote address of each accepted connection
+// as the system worker's, so that requests forwarded from it can be told apart
+// by their peer address.
+type systemWorkerListener struct {
+	net.Listener
+}
+
+func (l systemWorkerListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return systemWorkerConn{conn}, nil
+}
+
+type systemWorkerConn struct {
+	net.Conn
+}
+
+func (c systemWorkerConn) RemoteAddr() net.Addr {
+	return systemWorkerAddr{c.Conn.RemoteAddr()}
+}
+
+// systemWorkerAddr is the address of a connection accepted from the system
+// worker.
+type systemWorkerAddr struct {
+	net.Addr
+}
@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	taskqueuepb "go.temporal.io/api/taskqueue/v1"
+	"go.temporal.io/api/workflowservice/v1"
+)
+
+// TaskQueueBacklog is the matching service's view of a task queue.
+type TaskQueueBacklog struct {
+	Namespace       string   `json:"namespace"`
+	Name            string   `json:"name"`
+	WorkflowBacklog int64    `json:"workflow_backlog"`
+	ActivityBacklog int64    `json:"activity_backlog"`
+	WorkflowPollers []Poller `json:"workflow_pollers"`
+	ActivityPollers []Poller `json:"activity_pollers"`
+}
+
+// Poller is a worker that recently polled a task queue.
+type Poller struct {
+	Identity       string    `json:"identity"`
+	LastAccessTime time.Time `json:"last_access_time"`
+}
+
+// Stalled reports whether tasks are waiting in the queue with nobody polling for them.
+func (b *TaskQueueBacklog) Stalled() bool {
+	return (b.WorkflowBacklog > 0 && len(b.WorkflowPollers) == 0) || (b.ActivityBacklog > 0 && len(b.ActivityPollers) == 0)
+}
+
+// DescribeTaskQueue returns the backlog depth and recent pollers of both the
+// workflow and activity halves of a task queue.
+//
+// Backlog counts are hints from the matching service and may lag slightly
+// behind tasks that were just added or dispatched.
+func (s *Server) DescribeTaskQueue(ctx context.Context, namespace, name string) (*TaskQueueBacklog, error) {
+	svc, err := s.workflowService()
+	if err != nil {
+		return nil, err
+	}
+	return DescribeTaskQueueBacklog(ctx, svc, namespace, name)
+}
+
+// DescribeTaskQueueBacklog is like Server.DescribeTaskQueue, but describes the
+// task queue through svc, such as a client of a server in another process.
+func DescribeTaskQueueBacklog(ctx context.Context, svc workflowservice.WorkflowServiceClient, namespace, name string) (*TaskQueueBacklog, error) {
+	backlog := &TaskQueueBacklog{Namespace: namespace, Name: name}
+	for _, typ := range []enumspb.TaskQueueType{enumspb.TASK_QUEUE_TYPE_WORKFLOW, enumspb.TASK_QUEUE_TYPE_ACTIVITY} {
+		resp, err := svc.DescribeTaskQueue(ctx, &workflowservice.DescribeTaskQueueRequest{
+			Namespace:              namespace,
+			TaskQueue:              &taskqueuepb.TaskQueue{Name: name, Kind: enumspb.TASK_QUEUE_KIND_NORMAL},
+			TaskQueueType:          typ,
+			IncludeTaskQueueStatus: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		pollers := make([]Poller, 0, len(resp.GetPollers()))
+		for _, p := range resp.GetPollers() {
+			poller := Poller{Identity: p.GetIdentity()}
+			if p.GetLastAccessTime() != nil {
+				poller.LastAccessTime = *p.GetLastAccessTime()
+			}
+			pollers = append(pollers, poller)
+		}
+		if typ == enumspb.TASK_QUEUE_TYPE_WORKFLOW {
+			backlog.WorkflowBacklog, backlog.WorkflowPollers = resp.GetTaskQueueStatus().GetBacklogCountHint(), pollers
+		} else {
+			backlog.ActivityBacklog, backlog.ActivityPollers = resp.GetTaskQueueStatus().GetBacklogCountHint(), pollers
+		}
+	}
+	return backlog, nil
+}
+
+// serveTaskQueue handles /debug/taskqueue?namespace=NS&name=NAME on the pprof port.
+//
+// When several servers run in one process, the frontend parameter selects one
+// by its host:port.
+func serveTaskQueue(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	name := query.Get("name")
+	if name == "" {
+		http.Error(w, "name parameter is required", http.StatusBadRequest)
+		return
+	}
+	namespace := query.Get("namespace")
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	s, err := debugServer(query.Get("frontend"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	backlog, err := s.DescribeTaskQueue(r.Context(), namespace, name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(backlog)
+}
@@ -0,0 +1,66 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalitecmd
+
+import (
+	"io"
+	"time"
+)
+
+type Option interface {
+	apply(*Server)
+}
+
+// WithBinary runs the temporalite binary at path rather than the one found on PATH.
+func WithBinary(path string) Option {
+	return newApplyFuncContainer(func(s *Server) {
+		s.binary = path
+	})
+}
+
+// WithNamespaces registers namespaces at startup.
+func WithNamespaces(namespaces ...string) Option {
+	return newApplyFuncContainer(func(s *Server) {
+		s.namespaces = append(s.namespaces, namespaces...)
+	})
+}
+
+// WithArgs passes additional flags to `temporalite start`, such as
+// "--filename", "my.db" to persist state instead of running in memory.
+func WithArgs(args ...string) Option {
+	return newApplyFuncContainer(func(s *Server) {
+		s.args = append(s.args, args...)
+	})
+}
+
+// WithOutput copies the subprocess's standard output and error to stdout and stderr.
+// Output is discarded by default.
+func WithOutput(stdout, stderr io.Writer) Option {
+	return newApplyFuncContainer(func(s *Server) {
+		s.stdout, s.stderr = stdout, stderr
+	})
+}
+
+// WithStartTimeout bounds how long Start waits for the server to accept
+// connections. The default is one minute.
+func WithStartTimeout(timeout time.Duration) Option {
+	return newApplyFuncContainer(func(s *Server) {
+		s.startTimeout = timeout
+	})
+}
+
+type applyFuncContainer struct {
+	applyInternal func(*Server)
+}
+
+func (fso *applyFuncContainer) apply(s *Server) {
+	fso.applyInternal(s)
+}
+
+func newApplyFuncContainer(apply func(*Server)) *applyFuncContainer {
+	return &applyFuncContainer{
+		applyInternal: apply,
+	}
+}
@@ -0,0 +1,234 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+// Package temporalitecmd runs temporalite as a subprocess, for programs and test
+// suites that can't link the CGO SQLite driver or want the server isolated in
+// its own process.
+package temporalitecmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+)
+
+// stopTimeout is how long Stop waits for a graceful shutdown before killing the process.
+const stopTimeout = 10 * time.Second
+
+// ports mirrors the JSON written by `temporalite start --ports-file`.
+type ports struct {
+	PID      int    `json:"pid"`
+	Frontend string `json:"frontend"`
+	Metrics  string `json:"metrics,omitempty"`
+	PProf    string `json:"pprof"`
+}
+
+// Server is a temporalite server running in a subprocess.
+type Server struct {
+	binary       string
+	args         []string
+	namespaces   []string
+	stdout       io.Writer
+	stderr       io.Writer
+	startTimeout time.Duration
+
+	cmd     *exec.Cmd
+	dir     string
+	ports   ports
+	exited  chan struct{}
+	exitErr error
+}
+
+// Start runs `temporalite start` and returns once its frontend accepts connections.
+//
+// The server listens on system-chosen ports with the web UI disabled and, unless
+// a --filename is passed with WithArgs, keeps all state in memory. It exits on its
+// own if the calling process dies without calling Stop.
+func Start(ctx context.Context, opts ...Option) (*Server, error) {
+	s := &Server{
+		binary:       "temporalite",
+		startTimeout: time.Minute,
+		exited:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt.apply(s)
+	}
+
+	dir, err := os.MkdirTemp("", "temporalitecmd-")
+	if err != nil {
+		return nil, err
+	}
+	s.dir = dir
+	portsFile := filepath.Join(dir, "ports.json")
+
+	args := []string{"start", "--dynamic-ports", "--ports-file", portsFile, "--exit-with-parent", "--headless"}
+	if !hasFlag(s.args, "filename", "f") {
+		args = append(args, "--ephemeral")
+	}
+	for _, ns := range s.namespaces {
+		args = append(args, "--namespace", ns)
+	}
+	args = append(args, s.args...)
+
+	s.cmd = exec.Command(s.binary, args...)
+	if s.stdout != nil {
+		s.cmd.Stdout = s.stdout
+	}
+	if s.stderr != nil {
+		s.cmd.Stderr = s.stderr
+	}
+	if err := s.cmd.Start(); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("unable to start %s: %w", s.binary, err)
+	}
+	go func() {
+		s.exitErr = s.cmd.Wait()
+		close(s.exited)
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, s.startTimeout)
+	defer cancel()
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if data, err := os.ReadFile(portsFile); err == nil {
+			if err := json.Unmarshal(data, &s.ports); err != nil {
+				s.Stop()
+				return nil, fmt.Errorf("invalid ports file: %w", err)
+			}
+			return s, nil
+		}
+		select {
+		case <-s.exited:
+			_ = os.RemoveAll(dir)
+			return nil, fmt.Errorf("temporalite exited during startup: %v", s.exitErr)
+		case <-ctx.Done():
+			s.Stop()
+			return nil, fmt.Errorf("temporalite did not start: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// hasFlag reports whether args set the flag with the given long or short name.
+func hasFlag(args []string, long, short string) bool {
+	for _, arg := range args {
+		name := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)[0]
+		if strings.HasPrefix(arg, "-") && (name == long || name == short) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stop interrupts the server and waits for it to exit, killing it if it doesn't
+// shut down in time.
+func (s *Server) Stop() {
+	defer os.RemoveAll(s.dir)
+	select {
+	case <-s.exited:
+		return
+	default:
+	}
+
+	// Interrupts aren't supported on Windows, so fall back to killing the process.
+	if err := s.cmd.Process.Signal(os.Interrupt); err != nil {
+		_ = s.cmd.Process.Kill()
+	}
+	select {
+	case <-s.exited:
+	case <-time.After(stopTimeout):
+		_ = s.cmd.Process.Kill()
+		<-s.exited
+	}
+}
+
+// Exited returns a channel that is closed when the subprocess exits.
+func (s *Server) Exited() <-chan struct{} {
+	return s.exited
+}
+
+// PID returns the process ID of the server.
+func (s *Server) PID() int {
+	return s.cmd.Process.Pid
+}
+
+// FrontendHostPort returns the host:port of the temporal-frontend GRPC service.
+func (s *Server) FrontendHostPort() string {
+	return s.ports.Frontend
+}
+
+// MetricsHostPort returns the host:port serving Prometheus metrics.
+func (s *Server) MetricsHostPort() string {
+	return s.ports.Metrics
+}
+
+// NewClient initializes a client ready to communicate with the server in the
+// target namespace.
+func (s *Server) NewClient(ctx context.Context, namespace string) (client.Client, error) {
+	return s.NewClientWithOptions(ctx, client.Options{Namespace: namespace})
+}
+
+// NewClientWithOptions is the same as NewClient but allows further customization.
+//
+// Note that the HostPort field of client.Options will always be overridden.
+func (s *Server) NewClientWithOptions(ctx context.Context, options client.Options) (client.Client, error) {
+	options.HostPort = s.ports.Frontend
+	if deadline, ok := ctx.Deadline(); ok {
+		options.ConnectionOptions.HealthCheckTimeout = time.Until(deadline)
+	}
+	return client.NewClient(options)
+}
+
+// AwaitNamespace blocks until the namespace accepts workflow requests or ctx is done.
+func (s *Server) AwaitNamespace(ctx context.Context, namespace string) error {
+	c, err := s.NewClient(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if namespaceReady(ctx, c, namespace) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("namespace %q not ready: %w", namespace, ctx.Err())
+		case <-s.exited:
+			return fmt.Errorf("temporalite exited: %v", s.exitErr)
+		case <-ticker.C:
+		}
+	}
+}
+
+// namespaceReady reports whether the frontend, history, and matching services
+// are serving requests for namespace.
+func namespaceReady(ctx context.Context, c client.Client, namespace string) bool {
+	// CountWorkflowExecutions requires advanced visibility, so list instead.
+	if _, err := c.ListOpenWorkflow(ctx, &workflowservice.ListOpenWorkflowExecutionsRequest{Namespace: namespace, MaximumPageSize: 1}); err != nil {
+		return false
+	}
+	_, err := c.DescribeWorkflowExecution(ctx, "temporalite-readiness-probe", "")
+	var notFound *serviceerror.NotFound
+	if err != nil && !errors.As(err, &notFound) {
+		return false
+	}
+	_, err = c.DescribeTaskQueue(ctx, "temporalite-readiness-probe", enumspb.TASK_QUEUE_TYPE_WORKFLOW)
+	return err == nil
+}
@@ -0,0 +1,180 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporaltest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/jsonpb"
+	enumspb "go.temporal.io/api/enums/v1"
+	historypb "go.temporal.io/api/history/v1"
+	"go.temporal.io/sdk/client"
+)
+
+// UpdateGoldenEnv is the environment variable that, when set to a non-empty
+// value, makes AssertHistoryMatches write golden files instead of comparing them.
+const UpdateGoldenEnv = "TEMPORALTEST_UPDATE_GOLDEN"
+
+// volatileIDFields are history fields holding IDs or host details that differ
+// between runs. Each distinct value is replaced by a placeholder numbered in
+// order of appearance, so references between events are still checked.
+var volatileIDFields = map[string]bool{
+	"runId":                   true,
+	"originalExecutionRunId":  true,
+	"firstExecutionRunId":     true,
+	"newExecutionRunId":       true,
+	"continuedExecutionRunId": true,
+	"requestId":               true,
+	"identity":                true,
+	"binaryChecksum":          true,
+	"namespaceId":             true,
+}
+
+// AssertHistoryMatches compares the history of a workflow run in the test
+// namespace with a golden file, failing t with the first difference.
+//
+// Timestamps, run IDs, request IDs, worker identities, sticky task queue
+// names, and task IDs are normalized first, so the comparison covers the structure of the workflow:
+// its events, their order, and their attributes. Call it once the run has
+// completed, eg. after run.Get. Run the test with TEMPORALTEST_UPDATE_GOLDEN=1
+// to write the golden file from the current history.
+func (ts *TestServer) AssertHistoryMatches(t testing.TB, run client.WorkflowRun, goldenFile string) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	history := &historypb.History{}
+	iter := ts.Client().GetWorkflowHistory(ctx, run.GetID(), run.GetRunID(), false, enumspb.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT)
+	for iter.HasNext() {
+		event, err := iter.Next()
+		if err != nil {
+			t.Fatalf("unable to read history of workflow %s: %v", run.GetID(), err)
+		}
+		history.Events = append(history.Events, event)
+	}
+
+	got, err := normalizeHistory(history)
+	if err != nil {
+		t.Fatalf("unable to normalize history: %v", err)
+	}
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(goldenFile), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(goldenFile, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(goldenFile)
+	if os.IsNotExist(err) {
+		t.Fatalf("golden file %s does not exist; run with %s=1 to create it", goldenFile, UpdateGoldenEnv)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	if diff := firstDifference(string(want), string(got)); diff != "" {
+		t.Errorf("history of workflow %s does not match %s (rerun with %s=1 to update):\n%s", run.GetID(), goldenFile, UpdateGoldenEnv, diff)
+	}
+}
+
+// normalizeHistory renders history as indented JSON with volatile fields replaced.
+func normalizeHistory(history *historypb.History) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := (&jsonpb.Marshaler{}).Marshal(&buf, history); err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		return nil, err
+	}
+	placeholders := make(map[string]string)
+	doc = normalizeValue("", doc, placeholders)
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+func normalizeValue(key string, v interface{}, placeholders map[string]string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		// Visit keys in order so placeholders are numbered the same way each run.
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		// Sticky task queues are named after the worker's host and a random ID.
+		if name, ok := v["name"].(string); ok && v["kind"] == "Sticky" {
+			v["name"] = placeholder("stickyTaskQueue", name, placeholders)
+		}
+		for _, k := range keys {
+			if k == "taskId" {
+				delete(v, k)
+				continue
+			}
+			v[k] = normalizeValue(k, v[k], placeholders)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = normalizeValue(key, child, placeholders)
+		}
+		return v
+	case string:
+		switch {
+		case strings.HasSuffix(key, "Time") || strings.HasSuffix(key, "Timestamp"):
+			return "<time>"
+		case volatileIDFields[key] && v != "":
+			return placeholder(key, v, placeholders)
+		}
+	}
+	return v
+}
+
+// placeholder returns the placeholder replacing value, numbering a new one in
+// order of appearance.
+func placeholder(key, value string, placeholders map[string]string) string {
+	p, ok := placeholders[value]
+	if !ok {
+		p = fmt.Sprintf("<%s-%d>", key, len(placeholders)+1)
+		placeholders[value] = p
+	}
+	return p
+}
+
+// firstDifference describes the first line at which want and got differ, or
+// returns an empty string if they are equal.
+func firstDifference(want, got string) string {
+	if want == got {
+		return ""
+	}
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return fmt.Sprintf("line %d:\n  want: %s\n  got:  %s", i+1, strings.TrimSpace(w), strings.TrimSpace(g))
+		}
+	}
+	return ""
+}
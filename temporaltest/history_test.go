@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporaltest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	historypb "go.temporal.io/api/history/v1"
+	taskqueuepb "go.temporal.io/api/taskqueue/v1"
+)
+
+func workflowTaskScheduled(taskQueue *taskqueuepb.TaskQueue) *historypb.HistoryEvent {
+	now := time.Now()
+	return &historypb.HistoryEvent{
+		EventId:   2,
+		EventTime: &now,
+		EventType: enumspb.EVENT_TYPE_WORKFLOW_TASK_SCHEDULED,
+		TaskId:    1048576,
+		Attributes: &historypb.HistoryEvent_WorkflowTaskScheduledEventAttributes{
+			WorkflowTaskScheduledEventAttributes: &historypb.WorkflowTaskScheduledEventAttributes{TaskQueue: taskQueue},
+		},
+	}
+}
+
+func TestNormalizeHistory(t *testing.T) {
+	tests := []struct {
+		name        string
+		history     *historypb.History
+		contains    []string
+		notContains []string
+	}{
+		{
+			name: "sticky task queue",
+			history: &historypb.History{Events: []*historypb.HistoryEvent{
+				workflowTaskScheduled(&taskqueuepb.TaskQueue{Name: "host-1:0b6c1d2e", Kind: enumspb.TASK_QUEUE_KIND_STICKY}),
+				workflowTaskScheduled(&taskqueuepb.TaskQueue{Name: "host-1:0b6c1d2e", Kind: enumspb.TASK_QUEUE_KIND_STICKY}),
+			}},
+			contains:    []string{"stickyTaskQueue-1"},
+			notContains: []string{"host-1", "stickyTaskQueue-2"},
+		},
+		{
+			name: "normal task queue",
+			history: &historypb.History{Events: []*historypb.HistoryEvent{
+				workflowTaskScheduled(&taskqueuepb.TaskQueue{Name: "orders", Kind: enumspb.TASK_QUEUE_KIND_NORMAL}),
+			}},
+			contains: []string{`"name": "orders"`},
+		},
+		{
+			name: "times and task IDs",
+			history: &historypb.History{Events: []*historypb.HistoryEvent{
+				workflowTaskScheduled(&taskqueuepb.TaskQueue{Name: "orders"}),
+			}},
+			contains:    []string{`"eventTime": "\u003ctime\u003e"`},
+			notContains: []string{"taskId", "1048576"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := normalizeHistory(tc.history)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tc.contains {
+				if !strings.Contains(string(got), s) {
+					t.Errorf("normalized history does not contain %s:\n%s", s, got)
+				}
+			}
+			for _, s := range tc.notContains {
+				if strings.Contains(string(got), s) {
+					t.Errorf("normalized history contains %s:\n%s", s, got)
+				}
+			}
+		})
+	}
+}
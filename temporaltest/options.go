@@ -20,6 +20,42 @@ func WithT(t *testing.T) TestServerOption {
 	})
 }
 
+// WithDeterministicTaskOrdering processes server tasks one at a time in the
+// order they were created, and limits workers started with TestServer.Worker
+// to a single poller and task at a time, so that workflow histories are
+// reproducible from run to run. See temporalite.WithDeterministicTaskOrdering.
+func WithDeterministicTaskOrdering() TestServerOption {
+	return newApplyFuncContainer(func(server *TestServer) {
+		server.deterministic = true
+	})
+}
+
+// WithTimeScale makes workflow timers fire and cron workflows run factor times
+// sooner, so tests of workflows that sleep for hours or run daily finish in
+// seconds. See temporalite.WithTimeScale.
+func WithTimeScale(factor float64) TestServerOption {
+	return newApplyFuncContainer(func(server *TestServer) {
+		server.timeScale = factor
+	})
+}
+
+// WithTightHeartbeatTimeouts times out activities that miss a heartbeat for a
+// second, so tests of heartbeat timeouts and activity cancellation run quickly.
+// See temporalite.WithTightHeartbeatTimeouts.
+func WithTightHeartbeatTimeouts() TestServerOption {
+	return newApplyFuncContainer(func(server *TestServer) {
+		server.tightHeartbeats = true
+	})
+}
+
+// WithFaultInjection lets tests force workflow and activity tasks to fail or
+// time out with TestServer.InjectFault. See temporalite.WithFaultInjection.
+func WithFaultInjection() TestServerOption {
+	return newApplyFuncContainer(func(server *TestServer) {
+		server.faultInjection = true
+	})
+}
+
 type applyFuncContainer struct {
 	applyInternal func(*TestServer)
 }
@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"os"
 	"testing"
 	"time"
 
@@ -18,6 +19,15 @@ import (
 	"github.com/DataDog/temporalite"
 )
 
+// CoverageReportEnv is the environment variable naming a file in which test
+// servers record the workflow and activity types they execute. See
+// temporalite.WithCoverageReport.
+const CoverageReportEnv = "TEMPORALTEST_COVERAGE"
+
+// namespaceReadyTimeout bounds how long clients wait for their namespace to
+// accept requests.
+const namespaceReadyTimeout = 30 * time.Second
+
 // A TestServer is a Temporal server listening on a system-chosen port on the
 // local loopback interface, for use in end-to-end tests.
 type TestServer struct {
@@ -27,6 +37,10 @@ type TestServer struct {
 	clients              []client.Client
 	workers              []worker.Worker
 	t                    *testing.T
+	deterministic        bool
+	timeScale            float64
+	tightHeartbeats      bool
+	faultInjection       bool
 }
 
 func (ts *TestServer) fatal(err error) {
@@ -38,9 +52,17 @@ func (ts *TestServer) fatal(err error) {
 
 // Worker registers and starts a Temporal worker on the specified task queue.
 func (ts *TestServer) Worker(taskQueue string, registerFunc func(registry worker.Registry)) worker.Worker {
-	w := worker.New(ts.Client(), taskQueue, worker.Options{
+	opts := worker.Options{
 		WorkflowPanicPolicy: worker.FailWorkflow,
-	})
+	}
+	if ts.deterministic {
+		opts.MaxConcurrentWorkflowTaskPollers = 1
+		opts.MaxConcurrentActivityTaskPollers = 1
+		opts.MaxConcurrentWorkflowTaskExecutionSize = 1
+		opts.MaxConcurrentActivityExecutionSize = 1
+		opts.MaxConcurrentLocalActivityExecutionSize = 1
+	}
+	w := worker.New(ts.Client(), taskQueue, opts)
 	registerFunc(w)
 	ts.workers = append(ts.workers, w)
 
@@ -80,11 +102,58 @@ func (ts *TestServer) NewClientWithOptions(opts client.Options) client.Client {
 		ts.fatal(fmt.Errorf("error creating client: %w", err))
 	}
 
+	// The namespace only becomes usable once upstream's namespace caches are
+	// refreshed, which can take longer than dialing.
+	awaitCtx, awaitCancel := context.WithTimeout(context.Background(), namespaceReadyTimeout)
+	defer awaitCancel()
+	if err := ts.server.AwaitNamespace(awaitCtx, opts.Namespace); err != nil {
+		ts.fatal(err)
+	}
+
 	ts.clients = append(ts.clients, c)
 
 	return c
 }
 
+// InjectFault forces the next workflow task or activity of workflowID to fail
+// or time out. The server must have been created with WithFaultInjection. See
+// temporalite.Server.InjectFault.
+func (ts *TestServer) InjectFault(workflowID string, fault temporalite.Fault) {
+	if err := ts.server.InjectFault(workflowID, fault); err != nil {
+		ts.fatal(err)
+	}
+}
+
+// PauseTaskProcessing withholds workflow and activity tasks from workers until
+// ResumeTaskProcessing is called. See temporalite.Server.PauseTaskProcessing.
+func (ts *TestServer) PauseTaskProcessing() {
+	ts.server.PauseTaskProcessing()
+}
+
+// ResumeTaskProcessing hands tasks to workers again.
+func (ts *TestServer) ResumeTaskProcessing() {
+	ts.server.ResumeTaskProcessing()
+}
+
+// Barrier holds the next call of the WorkflowService method, such as
+// "SignalWithStartWorkflowExecution", for workflowID until it is released, to
+// reproduce races between calls deterministically:
+//
+//	b := ts.Barrier("SignalWithStartWorkflowExecution", "order-1")
+//	go c.SignalWithStartWorkflow(ctx, "order-1", "cancel", nil, opts, OrderWorkflow)
+//	_ = b.Wait(ctx)
+//	_ = c.TerminateWorkflow(ctx, "order-1", "", "race")
+//	b.Release()
+//
+// See temporalite.Server.AddBarrier. Held calls are released on Stop.
+func (ts *TestServer) Barrier(method, workflowID string) *temporalite.Barrier {
+	b, err := ts.server.AddBarrier(method, workflowID)
+	if err != nil {
+		ts.fatal(err)
+	}
+	return b
+}
+
 // Stop closes test clients and shuts down the server.
 func (ts *TestServer) Stop() {
 	for _, w := range ts.workers {
@@ -119,12 +188,28 @@ func NewServer(opts ...TestServerOption) *TestServer {
 		})
 	}
 
-	s, err := temporalite.NewServer(
+	serverOpts := []temporalite.ServerOption{
 		temporalite.WithNamespaces(ts.defaultTestNamespace),
 		temporalite.WithPersistenceDisabled(),
 		temporalite.WithDynamicPorts(),
 		temporalite.WithLogger(log.NewNoopLogger()),
-	)
+	}
+	if ts.deterministic {
+		serverOpts = append(serverOpts, temporalite.WithDeterministicTaskOrdering())
+	}
+	if ts.timeScale != 0 {
+		serverOpts = append(serverOpts, temporalite.WithTimeScale(ts.timeScale))
+	}
+	if ts.tightHeartbeats {
+		serverOpts = append(serverOpts, temporalite.WithTightHeartbeatTimeouts())
+	}
+	if ts.faultInjection {
+		serverOpts = append(serverOpts, temporalite.WithFaultInjection())
+	}
+	if path := os.Getenv(CoverageReportEnv); path != "" {
+		serverOpts = append(serverOpts, temporalite.WithCoverageReport(path))
+	}
+	s, err := temporalite.NewServer(serverOpts...)
 	if err != nil {
 		ts.fatal(fmt.Errorf("error creating server: %w", err))
 	}
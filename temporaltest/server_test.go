@@ -12,6 +12,7 @@ import (
 
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
 
 	"github.com/DataDog/temporalite/internal/examples/helloworld"
 	"github.com/DataDog/temporalite/temporaltest"
@@ -82,6 +83,29 @@ func TestNewServer(t *testing.T) {
 	}
 }
 
+func TestWithTimeScale(t *testing.T) {
+	// An hour-long sleep takes 100ms at this factor.
+	ts := temporaltest.NewServer(temporaltest.WithT(t), temporaltest.WithTimeScale(36000))
+
+	sleep := func(ctx workflow.Context) error {
+		return workflow.Sleep(ctx, time.Hour)
+	}
+	ts.Worker("sleep", func(registry worker.Registry) {
+		registry.RegisterWorkflowWithOptions(sleep, workflow.RegisterOptions{Name: "sleep"})
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	wfr, err := ts.Client().ExecuteWorkflow(ctx, client.StartWorkflowOptions{TaskQueue: "sleep"}, "sleep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wfr.Get(ctx, nil); err != nil {
+		t.Fatalf("scaled sleep did not return early: %v", err)
+	}
+}
+
 func BenchmarkRunWorkflow(b *testing.B) {
 	ts := temporaltest.NewServer()
 	defer ts.Stop()
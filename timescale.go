@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/common/backoff"
+	"google.golang.org/grpc"
+)
+
+const (
+	// minScaledTimer is the shortest duration a scaled timer is given, as
+	// upstream rejects timers that fire immediately.
+	minScaledTimer = time.Millisecond
+	// minScaledCron is the shortest interval of a scaled cron schedule, as
+	// cron intervals are rounded to whole seconds.
+	minScaledCron = time.Second
+)
+
+// timeScaler shortens the durable timers and cron schedules of workflows by
+// factor, so that a 24 hour timer fires after 24h/factor and an @daily
+// workflow runs every 24h/factor.
+type timeScaler struct {
+	factor float64
+}
+
+func (s *timeScaler) scale(d time.Duration) time.Duration {
+	scaled := time.Duration(float64(d) / s.factor)
+	if scaled < minScaledTimer {
+		return minScaledTimer
+	}
+	return scaled
+}
+
+// scaleCron replaces a cron schedule with an @every schedule running factor
+// times as often, based on the interval between its next two activations.
+// Invalid schedules are returned unchanged, for upstream to reject.
+func (s *timeScaler) scaleCron(schedule string) string {
+	if schedule == "" || backoff.ValidateSchedule(schedule) != nil {
+		return schedule
+	}
+	now := time.Now()
+	next := now.Add(backoff.GetBackoffForNextSchedule(schedule, now, now))
+	interval := time.Duration(float64(backoff.GetBackoffForNextSchedule(schedule, next, next)) / s.factor).Round(time.Second)
+	if interval < minScaledCron {
+		interval = minScaledCron
+	}
+	return fmt.Sprintf("@every %s", interval)
+}
+
+func (s *timeScaler) Intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	switch r := req.(type) {
+	case *workflowservice.StartWorkflowExecutionRequest:
+		r.CronSchedule = s.scaleCron(r.GetCronSchedule())
+	case *workflowservice.SignalWithStartWorkflowExecutionRequest:
+		r.CronSchedule = s.scaleCron(r.GetCronSchedule())
+	case *workflowservice.RespondWorkflowTaskCompletedRequest:
+		for _, cmd := range r.GetCommands() {
+			if attrs := cmd.GetStartChildWorkflowExecutionCommandAttributes(); attrs != nil {
+				attrs.CronSchedule = s.scaleCron(attrs.GetCronSchedule())
+			}
+			attrs := cmd.GetStartTimerCommandAttributes()
+			if attrs == nil || attrs.StartToFireTimeout == nil || *attrs.StartToFireTimeout <= 0 {
+				continue
+			}
+			scaled := s.scale(*attrs.StartToFireTimeout)
+			attrs.StartToFireTimeout = &scaled
+		}
+	}
+	return handler(ctx, req)
+}
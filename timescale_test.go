@@ -0,0 +1,35 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTimeScaleValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		factor  float64
+		wantErr bool
+	}{
+		{name: "unset", factor: 0},
+		{name: "normal speed", factor: 1},
+		{name: "faster", factor: 3600},
+		{name: "slower", factor: 0.5, wantErr: true},
+		{name: "negative", factor: -2, wantErr: true},
+		{name: "NaN", factor: math.NaN(), wantErr: true},
+		{name: "infinite", factor: math.Inf(1), wantErr: true},
+		{name: "negative infinite", factor: math.Inf(-1), wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewServer(WithPersistenceDisabled(), WithDynamicPorts(), WithTimeScale(tc.factor))
+			if (err != nil) != tc.wantErr {
+				t.Errorf("NewServer() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
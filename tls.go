@@ -0,0 +1,170 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.temporal.io/server/common/authorization"
+)
+
+// certificateRoles maps role names in a claim mapping file to upstream roles.
+// Upstream's default authorizer ignores RoleWorker and only lets writers poll
+// for and complete tasks, so worker is an alias of writer.
+var certificateRoles = map[string]authorization.Role{
+	"reader": authorization.RoleReader,
+	"writer": authorization.RoleWriter,
+	"worker": authorization.RoleWriter,
+	"admin":  authorization.RoleAdmin,
+}
+
+// newClientTLSConfig returns the TLS configuration temporalite's own clients use
+// to reach a frontend serving certFile, presenting the same certificate.
+//
+// The connection is always made to this process over loopback, so the server
+// certificate is verified against caFile without checking its host name.
+func newClientTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("%s: no certificates found", caFile)
+	}
+
+	return &tls.Config{
+		Certificates:       []tls.Certificate{cert},
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return errors.New("no server certificate presented")
+			}
+			leaf, err := x509.ParseCertificate(rawCerts[0])
+			if err != nil {
+				return err
+			}
+			intermediates := x509.NewCertPool()
+			for _, raw := range rawCerts[1:] {
+				if c, err := x509.ParseCertificate(raw); err == nil {
+					intermediates.AddCert(c)
+				}
+			}
+			_, err = leaf.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates})
+			return err
+		},
+	}, nil
+}
+
+// certificateRule grants roles to client certificates carrying a subject alternative name.
+type certificateRule struct {
+	name       string
+	system     authorization.Role
+	namespaces map[string]authorization.Role
+}
+
+// loadCertificateClaims reads a certificate claim mapping file.
+//
+// Each non-empty line not starting with # holds a subject alternative name (a DNS
+// name, URI, or email address) or common name, followed by one or more
+// NAMESPACE=ROLE grants. The namespace * grants a role across the whole server.
+// Roles are reader, writer, worker (the same as writer), or admin. For example:
+//
+//	payments.dev.example.com payments=admin billing=reader
+//	ops.dev.example.com *=admin
+func loadCertificateClaims(path string) ([]certificateRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []certificateRule
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: expected a name followed by NAMESPACE=ROLE grants", path, line)
+		}
+		rule := certificateRule{name: fields[0], namespaces: make(map[string]authorization.Role)}
+		for _, grant := range fields[1:] {
+			vals := strings.SplitN(grant, "=", 2)
+			if len(vals) != 2 {
+				return nil, fmt.Errorf("%s:%d: grants must be in NAMESPACE=ROLE format, got %q", path, line, grant)
+			}
+			role, ok := certificateRoles[strings.ToLower(vals[1])]
+			if !ok {
+				return nil, fmt.Errorf("%s:%d: unknown role %q", path, line, vals[1])
+			}
+			if vals[0] == "*" {
+				rule.system |= role
+			} else {
+				rule.namespaces[strings.ToLower(vals[0])] |= role
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// certificateClaimMapper derives claims from the caller's verified client
+// certificate. The certificate temporalite itself presents is granted admin.
+type certificateClaimMapper struct {
+	rules    []certificateRule
+	selfCert []byte
+}
+
+// certificateNames returns the subject alternative names and common name of cert.
+func certificateNames(cert *x509.Certificate) []string {
+	names := append([]string{}, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	if cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+	return names
+}
+
+func (m *certificateClaimMapper) GetClaims(authInfo *authorization.AuthInfo) (*authorization.Claims, error) {
+	cert := authorization.PeerCert(authInfo.TLSConnection)
+	if cert == nil {
+		return nil, nil
+	}
+	if bytes.Equal(cert.Raw, m.selfCert) {
+		return &authorization.Claims{Subject: cert.Subject.CommonName, System: authorization.RoleAdmin}, nil
+	}
+
+	names := certificateNames(cert)
+	claims := &authorization.Claims{Subject: cert.Subject.CommonName, Namespaces: make(map[string]authorization.Role)}
+	for _, rule := range m.rules {
+		for _, name := range names {
+			if !strings.EqualFold(name, rule.name) {
+				continue
+			}
+			claims.Subject = name
+			claims.System |= rule.system
+			for ns, role := range rule.namespaces {
+				claims.Namespaces[ns] |= role
+			}
+		}
+	}
+	return claims, nil
+}
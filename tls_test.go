@@ -0,0 +1,89 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.temporal.io/server/common/authorization"
+)
+
+func TestLoadCertificateClaims(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     []certificateRule
+		wantErr  bool
+	}{
+		{
+			name:     "grants",
+			contents: "# teams\npayments.example.com Payments=admin billing=reader\nops.example.com *=admin *=reader\n",
+			want: []certificateRule{
+				{name: "payments.example.com", namespaces: map[string]authorization.Role{
+					"payments": authorization.RoleAdmin,
+					"billing":  authorization.RoleReader,
+				}},
+				{name: "ops.example.com", system: authorization.RoleAdmin | authorization.RoleReader, namespaces: map[string]authorization.Role{}},
+			},
+		},
+		{
+			name:     "worker is writer",
+			contents: "worker.example.com orders=worker\n",
+			want: []certificateRule{
+				{name: "worker.example.com", namespaces: map[string]authorization.Role{"orders": authorization.RoleWriter}},
+			},
+		},
+		{
+			name:     "missing grants",
+			contents: "payments.example.com\n",
+			wantErr:  true,
+		},
+		{
+			name:     "malformed grant",
+			contents: "payments.example.com payments\n",
+			wantErr:  true,
+		},
+		{
+			name:     "unknown role",
+			contents: "payments.example.com payments=owner\n",
+			wantErr:  true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "claims.txt")
+			if err := os.WriteFile(path, []byte(tc.contents), 0600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadCertificateClaims(path)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("loadCertificateClaims() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("loadCertificateClaims() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWorkerRoleAuthorized(t *testing.T) {
+	claims := &authorization.Claims{Namespaces: map[string]authorization.Role{"orders": certificateRoles["worker"]}}
+	for _, api := range []string{"PollWorkflowTaskQueue", "RespondWorkflowTaskCompleted", "RecordActivityTaskHeartbeat"} {
+		result, err := authorization.NewDefaultAuthorizer().Authorize(context.Background(), claims, &authorization.CallTarget{
+			Namespace: "orders",
+			APIName:   "/temporal.api.workflowservice.v1.WorkflowService/" + api,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Decision != authorization.DecisionAllow {
+			t.Errorf("worker denied %s", api)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+//go:build !sqlite_unlock_notify
+// +build !sqlite_unlock_notify
+
+package temporalite
+
+const sqliteUnlockNotify = false
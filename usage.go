@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// APIUsage is the number of calls a server received for one frontend GRPC method.
+type APIUsage struct {
+	// Method is the full GRPC method name, eg.
+	// /temporal.api.workflowservice.v1.WorkflowService/StartWorkflowExecution.
+	Method string
+	Calls  int64
+	Errors int64
+}
+
+// usageCounter counts frontend calls per method for a single server. The
+// temporalite.frontend.requests and temporalite.frontend.errors debug vars sum
+// the counts of all running servers.
+type usageCounter struct {
+	mu     sync.Mutex
+	counts map[string]*APIUsage
+}
+
+func (u *usageCounter) Intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.counts == nil {
+		u.counts = make(map[string]*APIUsage)
+	}
+	usage, ok := u.counts[info.FullMethod]
+	if !ok {
+		usage = &APIUsage{Method: info.FullMethod}
+		u.counts[info.FullMethod] = usage
+	}
+	usage.Calls++
+	if err != nil {
+		usage.Errors++
+	}
+	return resp, err
+}
+
+// APIUsage returns the frontend methods called since the server was created,
+// most called first, to help SDK users understand the traffic their
+// application generates.
+func (s *Server) APIUsage() []APIUsage {
+	s.usage.mu.Lock()
+	defer s.usage.mu.Unlock()
+	usage := make([]APIUsage, 0, len(s.usage.counts))
+	for _, u := range s.usage.counts {
+		usage = append(usage, *u)
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Calls != usage[j].Calls {
+			return usage[i].Calls > usage[j].Calls
+		}
+		return usage[i].Method < usage[j].Method
+	})
+	return usage
+}
@@ -0,0 +1,45 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+func TestFrontendCounts(t *testing.T) {
+	call := func(s *Server, method string, err error) {
+		info := &grpc.UnaryServerInfo{FullMethod: method}
+		_, _ = s.usage.Intercept(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, err
+		})
+	}
+	a, b := &Server{}, &Server{}
+	call(a, "/Start", nil)
+	call(a, "/Start", errors.New("failed"))
+	call(b, "/Start", nil)
+	call(b, "/Signal", nil)
+	trackRunningServer(a, true)
+	trackRunningServer(b, true)
+	defer trackRunningServer(a, false)
+	defer trackRunningServer(b, false)
+
+	wantUsage := []APIUsage{{Method: "/Start", Calls: 2, Errors: 1}}
+	if got := a.APIUsage(); !reflect.DeepEqual(got, wantUsage) {
+		t.Errorf("APIUsage() = %v, want %v", got, wantUsage)
+	}
+	wantCalls := map[string]int64{"/Start": 3, "/Signal": 1}
+	if got := frontendCounts(func(u APIUsage) int64 { return u.Calls }); !reflect.DeepEqual(got, wantCalls) {
+		t.Errorf("calls = %v, want %v", got, wantCalls)
+	}
+	wantErrors := map[string]int64{"/Start": 1}
+	if got := frontendCounts(func(u APIUsage) int64 { return u.Errors }); !reflect.DeepEqual(got, wantErrors) {
+		t.Errorf("errors = %v, want %v", got, wantErrors)
+	}
+}
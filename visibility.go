@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"errors"
+
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/common/persistence/visibility/store"
+	"google.golang.org/grpc"
+)
+
+// visibilityCountPageSize is the page size used to count workflows.
+const visibilityCountPageSize = 1000
+
+// visibilityFallback serves CountWorkflowExecutions and ScanWorkflowExecutions,
+// which upstream's SQL visibility store does not support, by listing workflows
+// matching the same query instead. Upstream's system batcher worker, and so
+// `tctl batch`, relies on both.
+//
+// Queries are limited to those ListWorkflowExecutions accepts without
+// advanced visibility.
+type visibilityFallback struct {
+	workflowService func() (workflowservice.WorkflowServiceClient, error)
+}
+
+func (f *visibilityFallback) Intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if !isVisibilityNotSupported(err) {
+		return resp, err
+	}
+	switch r := req.(type) {
+	case *workflowservice.ScanWorkflowExecutionsRequest:
+		return f.scan(ctx, r)
+	case *workflowservice.CountWorkflowExecutionsRequest:
+		return f.count(ctx, r)
+	}
+	return resp, err
+}
+
+func (f *visibilityFallback) scan(ctx context.Context, req *workflowservice.ScanWorkflowExecutionsRequest) (*workflowservice.ScanWorkflowExecutionsResponse, error) {
+	svc, err := f.workflowService()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := svc.ListWorkflowExecutions(ctx, &workflowservice.ListWorkflowExecutionsRequest{
+		Namespace:     req.GetNamespace(),
+		PageSize:      req.GetPageSize(),
+		NextPageToken: req.GetNextPageToken(),
+		Query:         req.GetQuery(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &workflowservice.ScanWorkflowExecutionsResponse{
+		Executions:    resp.GetExecutions(),
+		NextPageToken: resp.GetNextPageToken(),
+	}, nil
+}
+
+func (f *visibilityFallback) count(ctx context.Context, req *workflowservice.CountWorkflowExecutionsRequest) (*workflowservice.CountWorkflowExecutionsResponse, error) {
+	svc, err := f.workflowService()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		count int64
+		token []byte
+	)
+	for {
+		resp, err := svc.ListWorkflowExecutions(ctx, &workflowservice.ListWorkflowExecutionsRequest{
+			Namespace:     req.GetNamespace(),
+			PageSize:      visibilityCountPageSize,
+			NextPageToken: token,
+			Query:         req.GetQuery(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		count += int64(len(resp.GetExecutions()))
+		if token = resp.GetNextPageToken(); len(token) == 0 {
+			return &workflowservice.CountWorkflowExecutionsResponse{Count: count}, nil
+		}
+	}
+}
+
+// isVisibilityNotSupported reports whether err is upstream's error for a
+// visibility operation that requires Elasticsearch.
+func isVisibilityNotSupported(err error) bool {
+	var invalid *serviceerror.InvalidArgument
+	return errors.As(err, &invalid) && invalid.Message == store.OperationNotSupportedErr.Error()
+}
@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed under the MIT License.
+//
+// This product includes software developed at Datadog (https://www.datadoghq.com/). Copyright 2021 Datadog, Inc.
+
+package temporalite
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"go.temporal.io/api/serviceerror"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/common/persistence/visibility/store"
+	"google.golang.org/grpc"
+)
+
+// fakeWorkflowLister lists total workflows in pages of the requested size.
+type fakeWorkflowLister struct {
+	workflowservice.WorkflowServiceClient
+	total   int
+	queries []string
+}
+
+func (f *fakeWorkflowLister) ListWorkflowExecutions(_ context.Context, req *workflowservice.ListWorkflowExecutionsRequest, _ ...grpc.CallOption) (*workflowservice.ListWorkflowExecutionsResponse, error) {
+	f.queries = append(f.queries, req.GetQuery())
+	start := 0
+	if len(req.GetNextPageToken()) > 0 {
+		start, _ = strconv.Atoi(string(req.GetNextPageToken()))
+	}
+	end := start + int(req.GetPageSize())
+	resp := &workflowservice.ListWorkflowExecutionsResponse{}
+	if end < f.total {
+		resp.NextPageToken = []byte(strconv.Itoa(end))
+	} else {
+		end = f.total
+	}
+	for i := start; i < end; i++ {
+		resp.Executions = append(resp.Executions, &workflowpb.WorkflowExecutionInfo{})
+	}
+	return resp, nil
+}
+
+func TestVisibilityFallbackCount(t *testing.T) {
+	tests := []struct {
+		name       string
+		handlerErr error
+		want       int64
+		wantErr    bool
+	}{
+		{name: "not supported", handlerErr: store.OperationNotSupportedErr, want: 2500},
+		{name: "supported", want: 7},
+		{name: "other error", handlerErr: serviceerror.NewInvalidArgument("invalid query"), wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := &fakeWorkflowLister{total: 2500}
+			f := &visibilityFallback{workflowService: func() (workflowservice.WorkflowServiceClient, error) { return svc, nil }}
+			handler := func(context.Context, interface{}) (interface{}, error) {
+				if tc.handlerErr != nil {
+					return nil, tc.handlerErr
+				}
+				return &workflowservice.CountWorkflowExecutionsResponse{Count: 7}, nil
+			}
+			req := &workflowservice.CountWorkflowExecutionsRequest{Namespace: "default", Query: "WorkflowType='order'"}
+			resp, err := f.Intercept(context.Background(), req, &grpc.UnaryServerInfo{}, handler)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Intercept() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if got := resp.(*workflowservice.CountWorkflowExecutionsResponse).GetCount(); got != tc.want {
+				t.Errorf("count = %d, want %d", got, tc.want)
+			}
+			for _, q := range svc.queries {
+				if q != req.Query {
+					t.Errorf("listed with query %q, want %q", q, req.Query)
+				}
+			}
+		})
+	}
+}
+
+func TestVisibilityFallbackScan(t *testing.T) {
+	svc := &fakeWorkflowLister{total: 3}
+	f := &visibilityFallback{workflowService: func() (workflowservice.WorkflowServiceClient, error) { return svc, nil }}
+	handler := func(context.Context, interface{}) (interface{}, error) { return nil, store.OperationNotSupportedErr }
+
+	var scanned int
+	var token []byte
+	for {
+		req := &workflowservice.ScanWorkflowExecutionsRequest{Namespace: "default", PageSize: 2, NextPageToken: token}
+		resp, err := f.Intercept(context.Background(), req, &grpc.UnaryServerInfo{}, handler)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page := resp.(*workflowservice.ScanWorkflowExecutionsResponse)
+		scanned += len(page.GetExecutions())
+		if token = page.GetNextPageToken(); len(token) == 0 {
+			break
+		}
+	}
+	if scanned != 3 {
+		t.Errorf("scanned %d workflows, want 3", scanned)
+	}
+}